@@ -0,0 +1,251 @@
+package deck_activity
+
+import (
+	"fmt"
+	"github.com/rivo/tview"
+	"regexp"
+	"strconv"
+	"time"
+	"tui-deck/deck_board"
+	"tui-deck/deck_card"
+	"tui-deck/deck_http"
+	"tui-deck/deck_keys"
+	"tui-deck/deck_stack"
+	"tui-deck/deck_structs"
+	"tui-deck/deck_theme"
+	"tui-deck/deck_ui"
+	"tui-deck/utils"
+)
+
+const activityPageSize = 50
+
+var ActivityFlex *tview.Flex
+var ActivityList *tview.List
+
+var Activities []deck_structs.Activity
+
+var card deck_structs.Card
+var lastGiven = 0
+var loading = false
+var back func()
+
+var app *tview.Application
+var configuration utils.Configuration
+
+func Init(application *tview.Application, conf utils.Configuration) {
+	app = application
+	configuration = conf
+
+	ActivityFlex = tview.NewFlex()
+	ActivityList = tview.NewList()
+
+	ActivityList.SetBorder(true)
+	ActivityList.SetTitle(" Activity ")
+
+	ActivityFlex.AddItem(ActivityList, 0, 1, true)
+
+	deck_keys.SetHandler("main.activity", func() {
+		BuildActivity(deck_structs.Card{}, func() {
+			deck_ui.BuildFullFlex(deck_ui.MainFlex, nil)
+		})
+	})
+	deck_keys.SetHandler("card.activity", func() {
+		BuildActivity(deck_card.EditableCard, func() {
+			deck_ui.BuildFullFlex(deck_card.DetailFlex, nil)
+		})
+	})
+
+	applyTheme()
+	deck_theme.OnChange(func() {
+		applyTheme()
+		buildActivityList()
+	})
+}
+
+func applyTheme() {
+	deck_theme.StyleBox(ActivityFlex.Box)
+	deck_theme.StyleBox(ActivityList.Box)
+	deck_theme.StyleList(ActivityList)
+}
+
+func BuildActivity(c deck_structs.Card, backFunc func()) {
+	if loading {
+		deck_ui.FooterBar.SetText("Wait for the activity to load")
+		return
+	}
+	card = c
+	back = backFunc
+	Activities = make([]deck_structs.Activity, 0)
+	lastGiven = 0
+	loadActivities(func() {
+		buildActivityList()
+
+		ActivityList.SetInputCapture(deck_keys.Capture(deck_keys.Activity))
+		deck_keys.SetHandler("activity.back", func() {
+			back()
+		})
+		deck_keys.SetHandler("activity.open", func() {
+			if len(Activities) == 0 {
+				return
+			}
+			openCard(Activities[ActivityList.GetCurrentItem()])
+		})
+		deck_keys.SetHandler("activity.more", func() {
+			if loading {
+				return
+			}
+			current := ActivityList.GetCurrentItem()
+			count := len(Activities)
+			loadActivities(func() {
+				buildActivityList()
+				ActivityList.SetCurrentItem(current)
+				if len(Activities) == count {
+					deck_ui.FooterBar.SetText("No more activity")
+				}
+			})
+		})
+		deck_keys.SetHandler("activity.reload", func() {
+			BuildActivity(card, back)
+		})
+		deck_keys.SetHandler("activity.help", func() {
+			deck_ui.BuildHelp(ActivityFlex, deck_keys.Activity)
+		})
+
+		ActivityList.SetSelectedFunc(func(index int, name string, secondName string, shortcut rune) {
+			openCard(Activities[index])
+		})
+
+		deck_ui.BuildFullFlex(ActivityFlex, nil)
+	})
+}
+
+func loadActivities(done func()) {
+	loading = true
+	c := card
+	since := lastGiven
+	boardId := deck_board.CurrentBoard.Id
+	cardIds := make(map[int]bool)
+	for _, s := range deck_stack.Stacks {
+		for _, stackCard := range s.Cards {
+			cardIds[stackCard.Id] = true
+		}
+	}
+	go func() {
+		activities, last, err := getActivities(c, since, boardId, cardIds)
+		app.QueueUpdateDraw(func() {
+			loading = false
+			Activities = append(Activities, activities...)
+			lastGiven = last
+			if err != nil {
+				deck_ui.FooterBar.SetText(fmt.Sprintf("Error getting activity: %s", err.Error()))
+				if len(activities) == 0 {
+					return
+				}
+			}
+			done()
+		})
+	}()
+}
+
+func getActivities(c deck_structs.Card, since int, boardId int, cardIds map[int]bool) ([]deck_structs.Activity, int, error) {
+	activities := make([]deck_structs.Activity, 0)
+	for {
+		var page []deck_structs.Activity
+		var err error
+		if c.Id != 0 {
+			page, err = deck_http.GetCardActivities(c.Id, since, activityPageSize, configuration)
+		} else {
+			page, err = deck_http.GetDeckActivities(since, activityPageSize, configuration)
+		}
+		if err != nil {
+			return activities, since, err
+		}
+		last := since
+		for _, a := range page {
+			since = a.ActivityId
+			if c.Id != 0 || isCurrentBoard(a, boardId, cardIds) {
+				activities = append(activities, a)
+			}
+		}
+		if c.Id != 0 || len(page) == 0 || since == last || len(activities) >= activityPageSize {
+			return activities, since, nil
+		}
+	}
+}
+
+func isCurrentBoard(activity deck_structs.Activity, boardId int, cardIds map[int]bool) bool {
+	if activity.ObjectType == "deck_board" {
+		return activity.ObjectId == boardId
+	}
+	linkBoardId := getBoardId(activity.Link)
+	if linkBoardId != 0 {
+		return linkBoardId == boardId
+	}
+	if activity.ObjectType != "deck_card" {
+		return false
+	}
+	return cardIds[activity.ObjectId]
+}
+
+func buildActivityList() {
+	ActivityList.Clear()
+	title := fmt.Sprintf(" Activity - %s (%d) ", deck_board.CurrentBoard.Title, len(Activities))
+	if card.Id != 0 {
+		title = fmt.Sprintf(" Activity - #%d %s (%d) ", card.Id, card.Title, len(Activities))
+	}
+	ActivityList.SetTitle(title)
+	for _, a := range Activities {
+		ActivityList.AddItem(fmt.Sprintf("[%s]%s[-] - %s", deck_theme.Current.Accent, getDate(a), tview.Escape(a.Subject)),
+			fmt.Sprintf("[-:-:i]%s[-:-:-] %s", tview.Escape(getUserName(a.User)), tview.Escape(a.Message)), rune(0), nil)
+	}
+}
+
+func openCard(activity deck_structs.Activity) {
+	if activity.ObjectType != "deck_card" {
+		deck_ui.FooterBar.SetText(fmt.Sprintf("Activity #%d does not reference a card", activity.ActivityId))
+		return
+	}
+
+	boardId := getBoardId(activity.Link)
+	if boardId != 0 && boardId != deck_board.CurrentBoard.Id {
+		err := deck_board.SwitchBoard(boardId)
+		if err != nil {
+			deck_ui.FooterBar.SetText(fmt.Sprintf("Error switching board: %s", err.Error()))
+			return
+		}
+	}
+
+	err := deck_card.OpenCardFrom(activity.ObjectId, func() {
+		deck_ui.BuildFullFlex(ActivityFlex, nil)
+	})
+	if err != nil {
+		deck_ui.FooterBar.SetText(err.Error())
+	}
+}
+
+func getUserName(uid string) string {
+	for _, user := range deck_board.CurrentBoard.Users {
+		if user.Uid == uid {
+			return user.DisplayName
+		}
+	}
+	return uid
+}
+
+func getBoardId(link string) int {
+	re := regexp.MustCompile(`/board/(\d+)`)
+	match := re.FindStringSubmatch(link)
+	if len(match) < 2 {
+		return 0
+	}
+	boardId, _ := strconv.Atoi(match[1])
+	return boardId
+}
+
+func getDate(activity deck_structs.Activity) string {
+	parse, err := time.Parse(time.RFC3339, activity.Datetime)
+	if err != nil {
+		return activity.Datetime
+	}
+	return parse.Local().Format("02/01/2006 15:04")
+}
@@ -6,10 +6,11 @@ import (
 	"github.com/rivo/tview"
 	"tui-deck/deck_card"
 	"tui-deck/deck_db"
-	"tui-deck/deck_help"
 	"tui-deck/deck_http"
+	"tui-deck/deck_keys"
 	"tui-deck/deck_stack"
 	"tui-deck/deck_structs"
+	"tui-deck/deck_theme"
 	"tui-deck/deck_ui"
 	"tui-deck/utils"
 )
@@ -35,226 +36,245 @@ func Init(application *tview.Application, conf utils.Configuration) {
 
 	BoardFlex.Clear()
 	BoardFlex.AddItem(BoardList, 0, 1, true)
+
+	applyTheme()
+	deck_theme.OnChange(applyTheme)
+}
+
+func applyTheme() {
+	deck_theme.StyleBox(BoardFlex.Box)
+	deck_theme.StyleBox(BoardList.Box)
+	deck_theme.StyleList(BoardList)
+	deck_theme.StyleBox(EditTagsFlex.Box)
 }
 
 func BuildSwitchBoard(configuration utils.Configuration) {
 	BoardList.SetBorder(true)
-	BoardList.SetBorderColor(utils.GetColor(configuration.Color))
 	BoardList.SetTitle("Select Boards")
 	for _, b := range Boards {
-		BoardList.AddItem(fmt.Sprintf("[#%s]#%d - %s", b.Color, b.Id, b.Title), "", rune(0), nil)
+		BoardList.AddItem(fmt.Sprintf("[%s]#%d - %s", deck_theme.LabelColor(b.Color), b.Id, b.Title), "", rune(0), nil)
 	}
-	BoardList.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
-		if event.Key() == tcell.KeyEsc {
-			deck_ui.BuildFullFlex(deck_ui.MainFlex, nil)
-		} else if event.Rune() == 97 {
-			// a -> add board
-			addForm, board := buildAddBoardForm(deck_structs.Board{})
-			addForm.AddButton("Save", func() {
-				addBoard(*board)
-			})
+	BoardList.SetInputCapture(deck_keys.Capture(deck_keys.Boards))
+	deck_keys.SetHandler("boards.back", func() {
+		deck_ui.BuildFullFlex(deck_ui.MainFlex, nil)
+	})
+	deck_keys.SetHandler("boards.select", func() {
+		if BoardList.GetItemCount() == 0 {
+			return
+		}
+		err := selectBoard(BoardList.GetCurrentItem())
+		deck_ui.BuildFullFlex(deck_ui.MainFlex, err)
+	})
+	deck_keys.SetHandler("boards.add", func() {
+		addForm, board := buildAddBoardForm(deck_structs.Board{})
+		addForm.AddButton("Save", func() {
+			addBoard(*board)
+		})
 
-			deck_ui.BuildFullFlex(addForm, nil)
-		} else if event.Rune() == 101 {
-			// e -> edit board
-			selectedBoardIndex := BoardList.GetCurrentItem()
-			text, _ := BoardList.GetItemText(selectedBoardIndex)
-
-			boardId := utils.GetId(text)
-			board := deck_structs.Board{}
-			for _, b := range Boards {
-				if b.Id == boardId {
-					board = b
+		deck_ui.BuildFullFlex(addForm, nil)
+	})
+	deck_keys.SetHandler("boards.edit", func() {
+		selectedBoardIndex := BoardList.GetCurrentItem()
+		text, _ := BoardList.GetItemText(selectedBoardIndex)
+
+		boardId := utils.GetId(text)
+		board := deck_structs.Board{}
+		for _, b := range Boards {
+			if b.Id == boardId {
+				board = b
+				break
+			}
+		}
+
+		editForm, editedBoard := buildAddBoardForm(board)
+		editForm.AddButton("Save", func() {
+			var err error
+			go func() {
+				err = editBoard(*editedBoard)
+			}()
+			BoardList.SetItemText(selectedBoardIndex, fmt.Sprintf("[%s]#%d - %s", deck_theme.LabelColor(editedBoard.Color), editedBoard.Id, editedBoard.Title), "")
+			for i, b := range Boards {
+				if b.Id == editedBoard.Id {
+					Boards[i] = *editedBoard
 					break
 				}
 			}
+			deck_ui.BuildFullFlex(BoardFlex, err)
+		})
+		deck_ui.BuildFullFlex(editForm, nil)
+	})
+	deck_keys.SetHandler("boards.delete", func() {
+		selectedBoardIndex := BoardList.GetCurrentItem()
+		text, _ := BoardList.GetItemText(selectedBoardIndex)
+		boardId := utils.GetId(text)
+		modal = tview.NewModal()
+		modal.ClearButtons()
+		modal.SetText(fmt.Sprintf("Are you sure to delete bord #%d?", boardId))
+		deck_theme.StyleModal(modal)
+		modal.AddButtons([]string{"Yes", "No"})
+
+		modal.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+			if event.Key() == tcell.KeyEsc {
+				BoardFlex.RemoveItem(modal)
+				app.SetFocus(BoardList)
+			}
+			if event.Key() == tcell.KeyRight || event.Key() == tcell.KeyLeft || event.Key() == tcell.KeyEnter {
+				return event
+			}
+			return nil
+		})
 
-			editForm, editedBoard := buildAddBoardForm(board)
-			editForm.AddButton("Save", func() {
-				var err error
+		modal.SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+			if buttonLabel == "Yes" {
 				go func() {
-					err = editBoard(*editedBoard)
-				}()
-				BoardList.SetItemText(selectedBoardIndex, fmt.Sprintf("[#%s]#%d - %s", editedBoard.Color, editedBoard.Id, editedBoard.Title), "")
-				for i, b := range Boards {
-					if b.Id == editedBoard.Id {
-						Boards[i] = *editedBoard
-						break
+					_, err := deck_http.DeleteBoard(boardId, configuration)
+					if err != nil {
+						deck_ui.FooterBar.SetText(fmt.Sprintf("Error deleteing board: %s", err.Error()))
 					}
-				}
-				deck_ui.BuildFullFlex(BoardFlex, err)
-			})
-			deck_ui.BuildFullFlex(editForm, nil)
-		} else if event.Rune() == 100 {
-			// d -> delete board
-			selectedBoardIndex := BoardList.GetCurrentItem()
-			text, _ := BoardList.GetItemText(selectedBoardIndex)
-			boardId := utils.GetId(text)
-			modal = tview.NewModal()
-			modal.ClearButtons()
-			modal.SetText(fmt.Sprintf("Are you sure to delete bord #%d?", boardId))
-			modal.SetBackgroundColor(utils.GetColor(configuration.Color))
-			modal.AddButtons([]string{"Yes", "No"})
-
-			modal.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
-				if event.Key() == tcell.KeyEsc {
-					BoardFlex.RemoveItem(modal)
-					app.SetFocus(BoardList)
-				}
-				if event.Key() == tcell.KeyRight || event.Key() == tcell.KeyLeft || event.Key() == tcell.KeyEnter {
-					return event
-				}
-				return nil
-			})
+				}()
+				BoardList.RemoveItem(selectedBoardIndex)
+				BoardFlex.RemoveItem(modal)
+				app.SetFocus(BoardList)
+			} else if buttonLabel == "No" {
+				BoardFlex.RemoveItem(modal)
+				app.SetFocus(BoardList)
+			}
+		})
 
-			modal.SetDoneFunc(func(buttonIndex int, buttonLabel string) {
-				if buttonLabel == "Yes" {
-					go func() {
-						_, err := deck_http.DeleteBoard(boardId, configuration)
-						if err != nil {
-							deck_ui.FooterBar.SetText(fmt.Sprintf("Error deleteing board: %s", err.Error()))
-						}
-					}()
-					BoardList.RemoveItem(selectedBoardIndex)
-					BoardFlex.RemoveItem(modal)
-					app.SetFocus(BoardList)
-				} else if buttonLabel == "No" {
-					BoardFlex.RemoveItem(modal)
-					app.SetFocus(BoardList)
-				}
-			})
+		BoardFlex.AddItem(modal, 0, 0, false)
+		app.SetFocus(modal)
+	})
+	deck_keys.SetHandler("boards.labels", func() {
+		currentIndex := BoardList.GetCurrentItem()
+		text, _ := BoardList.GetItemText(currentIndex)
 
-			BoardFlex.AddItem(modal, 0, 0, false)
-			app.SetFocus(modal)
+		boardId := utils.GetId(text)
 
-		} else if event.Rune() == 116 {
-			// t -> tags
-			currentIndex := BoardList.GetCurrentItem()
-			text, _ := BoardList.GetItemText(currentIndex)
+		board, _ := deck_db.GetBoardDetails(boardId, Boards[currentIndex].Updated, configuration)
 
-			boardId := utils.GetId(text)
+		EditTagsFlex.Clear()
+		actualLabelList := tview.NewList()
+		deck_theme.StyleList(actualLabelList)
+		actualLabelList.SetBorder(true)
+		actualLabelList.SetTitle(" delete labels ")
+		actualLabelList.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+			if event.Key() == tcell.KeyTab {
+				return nil
+			}
+			return event
+		})
+		for _, label := range board.Labels {
+			actualLabelList.AddItem(fmt.Sprintf("[%s]#%d - %s", deck_theme.LabelColor(label.Color), label.Id, label.Title), "",
+				rune(0), nil)
+		}
+		actualLabelList.SetSelectedFunc(func(index int, name string, secondName string, rune rune) {
 
-			board, _ := deck_db.GetBoardDetails(boardId, Boards[currentIndex].Updated, configuration)
+			labelId := utils.GetId(name)
 
-			EditTagsFlex.Clear()
-			actualLabelList := tview.NewList()
-			actualLabelList.SetBorder(true)
-			actualLabelList.SetTitle(" delete labels ")
-			actualLabelList.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
-				if event.Key() == tcell.KeyTab {
-					return nil
+			go DeleteLabel(boardId, labelId)
+			board.Updated = true
+			board.Labels = append(board.Labels[:index], board.Labels[index+1:]...)
+			for i, b := range Boards {
+				if b.Id == boardId {
+					Boards[i] = board
+					break
 				}
-				return event
-			})
-			for _, label := range board.Labels {
-				actualLabelList.AddItem(fmt.Sprintf("[#%s]#%d - %s", label.Color, label.Id, label.Title), "",
-					rune(0), nil)
 			}
-			actualLabelList.SetSelectedFunc(func(index int, name string, secondName string, rune rune) {
+			actualLabelList.RemoveItem(index)
 
-				labelId := utils.GetId(name)
+			app.SetFocus(actualLabelList)
+		})
 
-				go DeleteLabel(boardId, labelId)
+		deck_keys.SetHandler("board-labels.add", func() {
+			addForm, label := buildAddLabelForm(deck_structs.Label{})
+			addForm.AddButton("Save", func() {
 				board.Updated = true
-				board.Labels = append(board.Labels[:index], board.Labels[index+1:]...)
-				for i, b := range Boards {
-					if b.Id == boardId {
-						Boards[i] = board
-						break
-					}
-				}
-				actualLabelList.RemoveItem(index)
-
-				app.SetFocus(actualLabelList)
+				addLabel(*label, &board, actualLabelList)
 			})
 
-			actualLabelList.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
-				if event.Rune() == 97 {
-					// a -> add label
-					addForm, label := buildAddLabelForm(deck_structs.Label{})
-					addForm.AddButton("Save", func() {
-						board.Updated = true
-						addLabel(*label, &board, actualLabelList)
-					})
-
-					deck_ui.BuildFullFlex(addForm, nil)
-				} else if event.Rune() == 101 {
-					// e -> edit label
-
-					selectedLabelIndex := actualLabelList.GetCurrentItem()
-					labelText, _ := actualLabelList.GetItemText(selectedLabelIndex)
-
-					labelId := utils.GetId(labelText)
-					label := deck_structs.Label{}
-					for _, l := range board.Labels {
-						if l.Id == labelId {
-							label = l
-							break
-						}
-					}
+			deck_ui.BuildFullFlex(addForm, nil)
+		})
+		deck_keys.SetHandler("board-labels.edit", func() {
+
+			selectedLabelIndex := actualLabelList.GetCurrentItem()
+			labelText, _ := actualLabelList.GetItemText(selectedLabelIndex)
 
-					editForm, editedLabel := buildAddLabelForm(label)
-					editForm.AddButton("Save", func() {
-						board.Updated = true
-						var err error
-						go func() {
-							err = editLabel(boardId, *editedLabel)
-						}()
-						actualLabelList.SetItemText(selectedLabelIndex, fmt.Sprintf("[#%s]#%d - %s", editedLabel.Color, editedLabel.Id, editedLabel.Title), "")
-						for i, l := range board.Labels {
-							if l.Id == editedLabel.Id {
-								board.Labels[i] = *editedLabel
-								break
-							}
-						}
-						deck_ui.BuildFullFlex(EditTagsFlex, err)
-					})
-					deck_ui.BuildFullFlex(editForm, nil)
+			labelId := utils.GetId(labelText)
+			label := deck_structs.Label{}
+			for _, l := range board.Labels {
+				if l.Id == labelId {
+					label = l
+					break
 				}
-				return event
-			})
+			}
 
-			EditTagsFlex.SetDirection(tview.FlexColumn)
-			EditTagsFlex.SetBorder(true)
-			EditTagsFlex.SetBorderColor(utils.GetColor(configuration.Color))
-			EditTagsFlex.SetTitle(fmt.Sprintf(" [#%s]%s[-:-:-] - EDIT TAGS ", board.Color, board.Title))
-			EditTagsFlex.AddItem(actualLabelList, 0, 1, true)
-			EditTagsFlex.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
-				if event.Key() == tcell.KeyEsc {
-					deck_ui.BuildFullFlex(BoardFlex, nil)
-					return nil
-				} else if event.Rune() == 63 {
-					// ? deck_help menu
-					deck_ui.BuildHelp(EditTagsFlex, deck_help.HelpLabels)
+			editForm, editedLabel := buildAddLabelForm(label)
+			editForm.AddButton("Save", func() {
+				board.Updated = true
+				var err error
+				go func() {
+					err = editLabel(boardId, *editedLabel)
+				}()
+				actualLabelList.SetItemText(selectedLabelIndex, fmt.Sprintf("[%s]#%d - %s", deck_theme.LabelColor(editedLabel.Color), editedLabel.Id, editedLabel.Title), "")
+				for i, l := range board.Labels {
+					if l.Id == editedLabel.Id {
+						board.Labels[i] = *editedLabel
+						break
+					}
 				}
-				return event
+				deck_ui.BuildFullFlex(EditTagsFlex, err)
 			})
-			deck_ui.BuildFullFlex(EditTagsFlex, nil)
-
-		} else if event.Rune() == 63 {
-			// ? deck_help menu
-			deck_ui.BuildHelp(BoardList, deck_help.HelpBoards)
-		}
-		return event
+			deck_ui.BuildFullFlex(editForm, nil)
+		})
+
+		EditTagsFlex.SetDirection(tview.FlexColumn)
+		EditTagsFlex.SetBorder(true)
+		EditTagsFlex.SetTitle(fmt.Sprintf(" [%s]%s[-:-:-] - EDIT TAGS ", deck_theme.LabelColor(board.Color), board.Title))
+		EditTagsFlex.AddItem(actualLabelList, 0, 1, true)
+		EditTagsFlex.SetInputCapture(deck_keys.Capture(deck_keys.BoardLabels))
+		deck_keys.SetHandler("board-labels.back", func() {
+			deck_ui.BuildFullFlex(BoardFlex, nil)
+		})
+		deck_keys.SetHandler("board-labels.help", func() {
+			deck_ui.BuildHelp(EditTagsFlex, deck_keys.BoardLabels)
+		})
+		deck_ui.BuildFullFlex(EditTagsFlex, nil)
+	})
+	deck_keys.SetHandler("boards.help", func() {
+		deck_ui.BuildHelp(BoardList, deck_keys.Boards)
 	})
 	BoardList.SetSelectedFunc(func(index int, name string, secondName string, shortcut rune) {
-		var err error
-		CurrentBoard, err = deck_db.GetBoardDetails(Boards[index].Id, Boards[index].Updated, configuration)
-		Boards[index] = CurrentBoard
-		deck_card.SetCurrentBoard(CurrentBoard)
-		if err != nil {
-			deck_ui.FooterBar.SetText(fmt.Sprintf("Error getting board detail: %s", err.Error()))
+		err := selectBoard(index)
+		deck_ui.BuildFullFlex(deck_ui.MainFlex, err)
+	})
+}
 
+func SwitchBoard(boardId int) error {
+	for i, b := range Boards {
+		if b.Id == boardId {
+			return selectBoard(i)
 		}
-		deck_ui.MainFlex.SetTitle(fmt.Sprintf(" TUI DECK: [#%s]%s ", CurrentBoard.Color, CurrentBoard.Title))
+	}
+	return fmt.Errorf("board #%d not found", boardId)
+}
 
-		deck_stack.Stacks, err = deck_db.GetStacks(CurrentBoard.Id, Boards[index].Updated, configuration)
-		if err != nil {
-			deck_ui.FooterBar.SetText(fmt.Sprintf("Error getting stacks: %s", err.Error()))
-		}
-		deck_card.BuildStacks()
-		deck_ui.BuildFullFlex(deck_ui.MainFlex, err)
-	})
+func selectBoard(index int) error {
+	var err error
+	CurrentBoard, err = deck_db.GetBoardDetails(Boards[index].Id, Boards[index].Updated, configuration)
+	Boards[index] = CurrentBoard
+	deck_card.SetCurrentBoard(CurrentBoard)
+	if err != nil {
+		deck_ui.FooterBar.SetText(fmt.Sprintf("Error getting board detail: %s", err.Error()))
+
+	}
+	deck_ui.MainFlex.SetTitle(fmt.Sprintf(" TUI DECK: [%s]%s ", deck_theme.LabelColor(CurrentBoard.Color), CurrentBoard.Title))
+
+	deck_stack.Stacks, err = deck_db.GetStacks(CurrentBoard.Id, Boards[index].Updated, configuration)
+	if err != nil {
+		deck_ui.FooterBar.SetText(fmt.Sprintf("Error getting stacks: %s", err.Error()))
+	}
+	deck_card.BuildStacks()
+	return err
 }
 
 func addBoard(board deck_structs.Board) {
@@ -266,7 +286,7 @@ func addBoard(board deck_structs.Board) {
 		deck_ui.FooterBar.SetText(fmt.Sprintf("Error crating new card: %s", err.Error()))
 	}
 	Boards = append(Boards, newBoard)
-	BoardList.AddItem(fmt.Sprintf("[#%s]#%d - %s", newBoard.Color, newBoard.Id, newBoard.Title), "", rune(0), nil)
+	BoardList.AddItem(fmt.Sprintf("[%s]#%d - %s", deck_theme.LabelColor(newBoard.Color), newBoard.Id, newBoard.Title), "", rune(0), nil)
 	if board.CreateDefaults {
 		var items []string = []string{"Todo", "Running", "Complete"}
 		for i, s := range items {
@@ -317,7 +337,7 @@ func addLabel(label deck_structs.Label, board *deck_structs.Board, actualLabelLi
 		}
 	}
 
-	actualLabelList.AddItem(fmt.Sprintf("[#%s]#%d - %s", newLabel.Color, newLabel.Id, newLabel.Title), "", rune(0), nil)
+	actualLabelList.AddItem(fmt.Sprintf("[%s]#%d - %s", deck_theme.LabelColor(newLabel.Color), newLabel.Id, newLabel.Title), "", rune(0), nil)
 
 	deck_ui.BuildFullFlex(EditTagsFlex, err)
 }
@@ -343,11 +363,7 @@ func buildAddLabelForm(l deck_structs.Label) (*tview.Form, *deck_structs.Label)
 	}
 	addForm.SetTitle(title)
 	addForm.SetBorder(true)
-	addForm.SetBorderColor(utils.GetColor(configuration.Color))
-	addForm.SetButtonBackgroundColor(utils.GetColor(configuration.Color))
-	addForm.SetFieldBackgroundColor(tcell.ColorWhite)
-	addForm.SetFieldTextColor(tcell.ColorBlack)
-	addForm.SetLabelColor(utils.GetColor(configuration.Color))
+	deck_theme.StyleForm(addForm)
 	addForm.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
 		if event.Key() == tcell.KeyEsc {
 			deck_ui.BuildFullFlex(EditTagsFlex, nil)
@@ -375,11 +391,7 @@ func buildAddBoardForm(b deck_structs.Board) (*tview.Form, *deck_structs.Board)
 	}
 	addForm.SetTitle(title)
 	addForm.SetBorder(true)
-	addForm.SetBorderColor(utils.GetColor(configuration.Color))
-	addForm.SetButtonBackgroundColor(utils.GetColor(configuration.Color))
-	addForm.SetFieldBackgroundColor(tcell.ColorWhite)
-	addForm.SetFieldTextColor(tcell.ColorBlack)
-	addForm.SetLabelColor(utils.GetColor(configuration.Color))
+	deck_theme.StyleForm(addForm)
 	addForm.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
 		if event.Key() == tcell.KeyEsc {
 			deck_ui.BuildFullFlex(deck_ui.MainFlex, nil)
@@ -1,32 +1,71 @@
 package deck_card
 
 import (
+	"errors"
 	"fmt"
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
 	"sort"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 	"tui-deck/deck_comment"
-	"tui-deck/deck_help"
 	"tui-deck/deck_http"
+	"tui-deck/deck_keys"
 	"tui-deck/deck_markdown"
 	"tui-deck/deck_stack"
 	"tui-deck/deck_structs"
+	"tui-deck/deck_theme"
 	"tui-deck/deck_ui"
+	"tui-deck/deck_undo"
 	"tui-deck/utils"
 )
 
+const allCommentsPageSize = 50
+const maxCommentLength = 1000
+const bulkConcurrency = 4
+
+const (
+	StacksLayout = iota
+	UsersLayout
+	LabelsLayout
+)
+
+var layoutNames = []string{"stacks", "swimlanes by assignee", "swimlanes by label"}
+
+type lane struct {
+	title  string
+	layout int
+	user   deck_structs.Owner
+	label  deck_structs.Label
+}
+
+var DetailFlex *tview.Flex
+var DetailHeader *tview.TextView
 var DetailText *tview.TextView
 var DetailEditText *tview.TextArea
+var DetailPreviewText *tview.TextView
+var EditFlex *tview.Flex
 var EditTagsFlex *tview.Flex
 var EditUsersFlex *tview.Flex
 var Modal *tview.Modal
 
 var CardsMap = make(map[int]deck_structs.Card)
+var markedCards = make(map[int]bool)
+var restoredCards = make(map[int]int)
 var EditableCard = deck_structs.Card{}
+var selectedCheckListItem = -1
+
+var previewVisible = true
+var cardBack func()
+
+var linkHintMode = false
+var linkHints []string
+var linkHintInput = ""
 
 var currentBoard deck_structs.Board
+var layout = StacksLayout
 
 var app *tview.Application
 var configuration utils.Configuration
@@ -36,356 +75,460 @@ func Init(application *tview.Application, conf utils.Configuration, board deck_s
 	app = application
 	configuration = conf
 
+	DetailFlex = tview.NewFlex()
+	DetailHeader = tview.NewTextView()
 	DetailText = tview.NewTextView()
 	DetailEditText = tview.NewTextArea()
+	DetailPreviewText = tview.NewTextView()
+	EditFlex = tview.NewFlex()
 	EditTagsFlex = tview.NewFlex()
 	EditUsersFlex = tview.NewFlex()
 
 	Modal = tview.NewModal()
 	currentBoard = board
+	deck_comment.SetBoardUsers(board.Users)
 }
 
 func SetCurrentBoard(board deck_structs.Board) {
 	currentBoard = board
+	deck_comment.SetBoardUsers(board.Users)
+	deck_undo.Clear()
+	markedCards = make(map[int]bool)
 }
 
 func BuildCardViewer() {
 	DetailText.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
-		if event.Key() == tcell.KeyEscape {
-			// ESC -> back to main view
-			deck_ui.BuildFullFlex(deck_ui.MainFlex, nil)
-		} else if event.Rune() == 101 {
-			// e -> edit description
-			DetailEditText.SetTitle(fmt.Sprintf(" %s- EDIT", DetailText.GetTitle()))
-			DetailEditText.SetText(utils.FormatDescription(EditableCard.Description), true)
-			deck_ui.BuildFullFlex(DetailEditText, nil)
-
-		} else if event.Rune() == 99 {
-			// c -> comments
-			cardId := utils.GetId(DetailText.GetTitle())
-			deck_comment.GetComments(cardId)
-			deck_comment.CommentTree.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
-				if event.Key() == tcell.KeyEscape {
-					// ESC -> back to main view
-					deck_ui.BuildFullFlex(DetailText, nil)
-					return nil
-				}
-				if event.Key() == tcell.KeyTAB {
-					return nil
-				}
-				if event.Key() == tcell.KeyRight {
-					return nil
-				}
-				if event.Key() == tcell.KeyLeft {
-					return nil
-				}
-				if event.Rune() == 97 {
-					// a -> add comment
-					addForm, comment := deck_comment.BuildAddForm(deck_structs.Comment{})
-					addForm.AddButton("Save", func() {
-						err := deck_comment.AddComment(cardId, *comment)
-						deck_comment.CreateCommentsTree()
-						deck_ui.BuildFullFlex(deck_comment.CommentTree, err)
-					})
-					deck_ui.BuildFullFlex(addForm, nil)
-					return nil
-				} else if event.Rune() == 100 {
-					// d -> delete comment
-					commentId := deck_comment.CommentTree.GetCurrentNode().GetReference().(int)
-					deck_comment.DeleteComment(cardId, commentId)
-					return nil
-				} else if event.Rune() == 114 {
-					// r -> reply comment
-					parentId := deck_comment.CommentTree.GetCurrentNode().GetReference().(int)
-					addForm, comment := deck_comment.BuildAddForm(deck_structs.Comment{})
-					addForm.AddButton("Save", func() {
-						err := deck_comment.ReplyComment(cardId, parentId, *comment)
-						deck_comment.CreateCommentsTree()
-						deck_ui.BuildFullFlex(deck_comment.CommentTree, err)
-					})
-					deck_ui.BuildFullFlex(addForm, nil)
-					return nil
-				} else if event.Rune() == 101 {
-					// e -> edit comment
-					commentId := deck_comment.CommentTree.GetCurrentNode().GetReference().(int)
-					comment := deck_comment.CommentsMap[commentId]
-					editForm, editComment := deck_comment.BuildAddForm(comment)
-					editForm.AddButton("Save", func() {
-						go func() {
-							err := deck_comment.EditComment(cardId, *editComment)
-							if err != nil {
-								deck_ui.FooterBar.SetText(fmt.Sprintf("Error editing new comment: %s", err.Error()))
-							}
-						}()
-						deck_comment.CreateCommentsTree()
-						deck_ui.BuildFullFlex(deck_comment.CommentTree, nil)
-					})
-					deck_ui.BuildFullFlex(editForm, nil)
-					return nil
-				} else if event.Rune() == 63 {
-					// ? -> help
-					deck_ui.BuildHelp(deck_comment.CommentTree, deck_help.HelpComments)
-					return nil
-				}
-				return event
-			})
+		if linkHintMode {
+			return linkHintInputCapture(event)
+		}
+		return deck_keys.Handle(deck_keys.Card, event)
+	})
 
-			deck_comment.CreateCommentsTree()
+	deck_keys.SetHandler("main.open-card", func() {
+		card, ok := GetSelectedCard()
+		if ok {
+			showCard(card)
+		}
+	})
+	deck_keys.SetHandler("main.mark", toggleMark)
+	deck_keys.SetHandler("main.clear-marks", func() {
+		if len(markedCards) == 0 {
+			return
+		}
+		ClearMarks()
+		deck_ui.FooterBar.SetText("Marks cleared")
+	})
+	deck_keys.SetHandler("main.move-card-next", func() {
+		moveFocusedCard(tcell.KeyRight)
+	})
+	deck_keys.SetHandler("main.move-card-previous", func() {
+		moveFocusedCard(tcell.KeyLeft)
+	})
+	deck_keys.SetHandler("main.next-lane", func() {
+		SwitchLane(1)
+	})
+	deck_keys.SetHandler("main.previous-lane", func() {
+		SwitchLane(-1)
+	})
+	deck_keys.SetHandler("main.move-card-next-lane", func() {
+		MoveCardToLane(1)
+	})
+	deck_keys.SetHandler("main.move-card-previous-lane", func() {
+		MoveCardToLane(-1)
+	})
+	deck_keys.SetHandler("main.layout", SwitchLayout)
 
-			deck_comment.CommentTree.SetTitle(fmt.Sprintf(" %s- COMMENTS ", DetailText.GetTitle()))
-			deck_ui.BuildFullFlex(deck_comment.CommentTree, nil)
-
-		} else if event.Rune() == 108 {
-			// l -> labels
-			EditTagsFlex.Clear()
-			actualLabelList := tview.NewList()
-			actualLabelList.SetBorder(true)
-			actualLabelList.SetTitle(" delete labels ")
-			actualLabelList.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
-				if event.Key() == tcell.KeyTab {
-					return nil
-				}
-				return event
+	deck_keys.SetHandler("card.back", func() {
+		if cardBack != nil {
+			cardBack()
+			return
+		}
+		deck_ui.BuildFullFlex(deck_ui.MainFlex, nil)
+	})
+	deck_keys.SetHandler("card.edit-description", func() {
+		DetailEditText.SetTitle(fmt.Sprintf(" %s- EDIT", DetailText.GetTitle()))
+		DetailEditText.SetText(utils.FormatDescription(EditableCard.Description), true)
+		updatePreview()
+		deck_ui.BuildFullFlex(EditFlex, nil)
+	})
+	deck_keys.SetHandler("card.comments", func() {
+		cardId := utils.GetId(DetailText.GetTitle())
+		deck_comment.GetComments(cardId)
+		deck_comment.CommentTree.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+			if event.Key() == tcell.KeyRight || event.Key() == tcell.KeyLeft {
+				return nil
+			}
+			return deck_keys.Handle(deck_keys.Comments, event)
+		})
+		deck_keys.SetHandler("comments.back", func() {
+			showDetail(nil)
+		})
+		deck_keys.SetHandler("comments.focus-message", func() {
+			app.SetFocus(deck_comment.CommentText)
+		})
+		deck_keys.SetHandler("comments.add-external", func() {
+			var message string
+			var err error
+			app.Suspend(func() {
+				message, err = utils.EditInExternalEditor("")
 			})
-			for _, label := range EditableCard.Labels {
-				actualLabelList.AddItem(fmt.Sprintf("[#%s]%s", label.Color, label.Title), "",
-					rune(0), nil)
-			}
-			actualLabelList.SetSelectedFunc(func(index int, name string, secondName string, rune rune) {
-				label := EditableCard.Labels[index]
-				jsonBody := fmt.Sprintf(`{"labelId": %d}`, label.Id)
-				go DeleteLabel(jsonBody)
-				EditableCard.Labels = append(EditableCard.Labels[:index], EditableCard.Labels[index+1:]...)
-				CardsMap[EditableCard.Id] = EditableCard
-				actualLabelList.RemoveItem(index)
-
-				updateStacks()
-				BuildStacks()
-				app.SetFocus(actualLabelList)
+			if err != nil {
+				deck_ui.FooterBar.SetText(fmt.Sprintf("Error running editor: %s", err.Error()))
+				return
+			}
+			if len(strings.TrimSpace(message)) == 0 {
+				return
+			}
+			err = deck_comment.AddComment(cardId, deck_structs.Comment{Message: message})
+			deck_comment.CreateCommentsTree()
+			deck_ui.BuildFullFlex(deck_comment.CommentFlex, err)
+		})
+		deck_keys.SetHandler("comments.add", func() {
+			addForm, comment := deck_comment.BuildAddForm(deck_structs.Comment{})
+			addForm.AddButton("Save", func() {
+				err := deck_comment.AddComment(cardId, *comment)
+				deck_comment.CreateCommentsTree()
+				deck_ui.BuildFullFlex(deck_comment.CommentFlex, err)
 			})
-
-			labelList := tview.NewList()
-			labelList.SetBorder(true)
-			labelList.SetTitle(" add labels")
-			labelList.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
-				if event.Key() == tcell.KeyTab {
-					return nil
-				}
-				return event
+			deck_ui.BuildFullFlex(addForm, nil)
+		})
+		deck_keys.SetHandler("comments.delete", func() {
+			commentId, ok := deck_comment.GetSelectedCommentId()
+			if !ok {
+				return
+			}
+			deck_comment.DeleteComment(cardId, commentId)
+		})
+		deck_keys.SetHandler("comments.reply", func() {
+			parentId, ok := deck_comment.GetSelectedCommentId()
+			if !ok {
+				return
+			}
+			addForm, comment := deck_comment.BuildAddForm(deck_structs.Comment{})
+			addForm.AddButton("Save", func() {
+				err := deck_comment.ReplyComment(cardId, parentId, *comment)
+				deck_comment.CreateCommentsTree()
+				deck_ui.BuildFullFlex(deck_comment.CommentFlex, err)
 			})
-			for _, label := range currentBoard.Labels {
-				labelList.AddItem(fmt.Sprintf("[#%s]%s", label.Color, label.Title), "",
-					rune(0), nil)
+			deck_ui.BuildFullFlex(addForm, nil)
+		})
+		deck_keys.SetHandler("comments.edit", func() {
+			commentId, ok := deck_comment.GetSelectedCommentId()
+			if !ok {
+				return
 			}
-
-			labelList.SetSelectedFunc(func(index int, name string, secondName string, rune rune) {
-				label := currentBoard.Labels[index]
-
-				for _, l := range EditableCard.Labels {
-					if l.Id == label.Id {
-						deck_ui.FooterBar.SetText("label already assigned")
-						return
+			comment := deck_comment.CommentsMap[commentId]
+			editForm, editComment := deck_comment.BuildAddForm(comment)
+			editForm.AddButton("Save", func() {
+				go func() {
+					err := deck_comment.EditComment(cardId, *editComment)
+					if err != nil {
+						deck_ui.FooterBar.SetText(fmt.Sprintf("Error editing new comment: %s", err.Error()))
 					}
-				}
-
-				jsonBody := fmt.Sprintf(`{"labelId": %d }`, label.Id)
-				go AssignLabel(jsonBody)
-				EditableCard.Labels = append(EditableCard.Labels, label)
-				CardsMap[EditableCard.Id] = EditableCard
-				actualLabelList.AddItem(fmt.Sprintf("[#%s]%s", label.Color, label.Title), "",
-					rune, nil)
-				updateStacks()
-				BuildStacks()
-				app.SetFocus(labelList)
+				}()
+				deck_comment.CreateCommentsTree()
+				deck_ui.BuildFullFlex(deck_comment.CommentFlex, nil)
 			})
+			deck_ui.BuildFullFlex(editForm, nil)
+		})
+		deck_keys.SetHandler("comments.help", func() {
+			deck_ui.BuildHelp(deck_comment.CommentFlex, deck_keys.Comments)
+		})
 
-			EditTagsFlex.SetDirection(tview.FlexColumn)
-			EditTagsFlex.SetBorder(true)
-			EditTagsFlex.SetBorderColor(utils.GetColor(configuration.Color))
-			EditTagsFlex.SetTitle(fmt.Sprintf(" %s- EDIT TAGS ", DetailText.GetTitle()))
-
-			EditTagsFlex.AddItem(actualLabelList, 0, 1, true)
-			EditTagsFlex.AddItem(labelList, 0, 1, true)
-			EditTagsFlex.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
-				if event.Key() == tcell.KeyEsc {
-					deck_ui.BuildFullFlex(DetailText, nil)
-					return nil
-				}
-				if event.Key() == tcell.KeyTab {
-					focus := app.GetFocus().(*tview.List)
-					if focus == actualLabelList {
-						app.SetFocus(labelList)
-					} else {
-						app.SetFocus(actualLabelList)
-					}
-				} else if event.Rune() == 63 {
-					// ? deck_help menu
-					deck_ui.BuildHelp(EditTagsFlex, deck_help.HelpLabels)
-				}
-				return event
-			})
+		deck_comment.CreateCommentsTree()
 
-			deck_ui.BuildFullFlex(EditTagsFlex, nil)
-
-		} else if event.Rune() == 117 {
-			// u -> edit users
-			EditUsersFlex.Clear()
-			actualUserList := tview.NewList()
-			actualUserList.SetBorder(true)
-			actualUserList.SetTitle(" delete user ")
-			actualUserList.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
-				if event.Key() == tcell.KeyTab {
-					return nil
-				}
-				return event
-			})
-			for _, user := range EditableCard.AssignedUsers {
-				actualUserList.AddItem(fmt.Sprintf("%s", user.Participant.DisplayName), "",
-					rune(0), nil)
-			}
-			actualUserList.SetSelectedFunc(func(index int, name string, secondName string, rune rune) {
-				user := EditableCard.AssignedUsers[index]
-				// delete user
-				jsonBody := fmt.Sprintf(`{"userId": "%s"}`, user.Participant.Uid)
-				go DeleteUser(jsonBody)
-				EditableCard.AssignedUsers = append(EditableCard.AssignedUsers[:index], EditableCard.AssignedUsers[index+1:]...)
-				CardsMap[EditableCard.Id] = EditableCard
-				actualUserList.RemoveItem(index)
-
-				updateStacks()
-				BuildStacks()
-				app.SetFocus(actualUserList)
+		deck_comment.CommentTree.SetTitle(fmt.Sprintf(" %s- COMMENTS ", DetailText.GetTitle()))
+		deck_ui.BuildFullFlex(deck_comment.CommentFlex, nil)
+	})
+	deck_keys.SetHandler("card.labels", func() {
+		EditTagsFlex.Clear()
+		actualLabelList := tview.NewList()
+		deck_theme.StyleList(actualLabelList)
+		actualLabelList.SetBorder(true)
+		actualLabelList.SetTitle(" delete labels ")
+		actualLabelList.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+			if event.Key() == tcell.KeyTab {
+				return nil
+			}
+			return event
+		})
+		for _, label := range EditableCard.Labels {
+			actualLabelList.AddItem(fmt.Sprintf("[%s]%s", deck_theme.LabelColor(label.Color), label.Title), "",
+				rune(0), nil)
+		}
+		actualLabelList.SetSelectedFunc(func(index int, name string, secondName string, rune rune) {
+			RemoveCardLabel(index)
+			actualLabelList.RemoveItem(index)
+		})
 
-			})
+		labelList := tview.NewList()
+		deck_theme.StyleList(labelList)
+		labelList.SetBorder(true)
+		labelList.SetTitle(" add labels")
+		labelList.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+			if event.Key() == tcell.KeyTab {
+				return nil
+			}
+			return event
+		})
+		for _, label := range currentBoard.Labels {
+			labelList.AddItem(fmt.Sprintf("[%s]%s", deck_theme.LabelColor(label.Color), label.Title), "",
+				rune(0), nil)
+		}
 
-			userList := tview.NewList()
-			userList.SetBorder(true)
-			userList.SetTitle(" add users")
-			userList.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
-				if event.Key() == tcell.KeyTab {
-					return nil
-				}
-				return event
-			})
-			for _, user := range currentBoard.Users {
-				userList.AddItem(fmt.Sprintf("%s", user.DisplayName), "",
-					rune(0), nil)
+		labelList.SetSelectedFunc(func(index int, name string, secondName string, rune rune) {
+			label := currentBoard.Labels[index]
+			if !AddCardLabel(label) {
+				return
 			}
+			actualLabelList.AddItem(fmt.Sprintf("[%s]%s", deck_theme.LabelColor(label.Color), label.Title), "",
+				rune, nil)
+		})
 
-			userList.SetSelectedFunc(func(index int, name string, secondName string, rune rune) {
-				user := currentBoard.Users[index]
+		EditTagsFlex.SetDirection(tview.FlexColumn)
+		EditTagsFlex.SetBorder(true)
+		EditTagsFlex.SetTitle(fmt.Sprintf(" %s- EDIT TAGS ", DetailText.GetTitle()))
 
-				for _, u := range EditableCard.AssignedUsers {
-					if u.Participant.Uid == user.Uid {
-						deck_ui.FooterBar.SetText("user already assigned")
-						return
-					}
-				}
+		EditTagsFlex.AddItem(actualLabelList, 0, 1, true)
+		EditTagsFlex.AddItem(labelList, 0, 1, true)
+		EditTagsFlex.SetInputCapture(deck_keys.Capture(deck_keys.Labels))
+		deck_keys.SetHandler("labels.back", func() {
+			showDetail(nil)
+		})
+		deck_keys.SetHandler("labels.switch-list", func() {
+			if app.GetFocus() == actualLabelList {
+				app.SetFocus(labelList)
+			} else {
+				app.SetFocus(actualLabelList)
+			}
+		})
+		deck_keys.SetHandler("labels.help", func() {
+			deck_ui.BuildHelp(EditTagsFlex, deck_keys.Labels)
+		})
 
-				jsonBody := fmt.Sprintf(`{"userId": "%s" }`, user.Uid)
-				go AssignUser(jsonBody)
+		deck_ui.BuildFullFlex(EditTagsFlex, nil)
+	})
+	deck_keys.SetHandler("card.users", func() {
+		EditUsersFlex.Clear()
+		actualUserList := tview.NewList()
+		deck_theme.StyleList(actualUserList)
+		actualUserList.SetBorder(true)
+		actualUserList.SetTitle(" delete user ")
+		actualUserList.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+			if event.Key() == tcell.KeyTab {
+				return nil
+			}
+			return event
+		})
+		for _, user := range EditableCard.AssignedUsers {
+			actualUserList.AddItem(fmt.Sprintf("%s", user.Participant.DisplayName), "",
+				rune(0), nil)
+		}
+		actualUserList.SetSelectedFunc(func(index int, name string, secondName string, rune rune) {
+			UnassignCardUser(index)
+			actualUserList.RemoveItem(index)
+		})
 
-				au := deck_structs.AssignedUser{
-					CardId: EditableCard.Id,
-					Type:   0,
-					Participant: deck_structs.Owner{
-						PrimaryKey:  user.PrimaryKey,
-						Uid:         user.Uid,
-						DisplayName: user.DisplayName,
-					},
-				}
-				EditableCard.AssignedUsers = append(EditableCard.AssignedUsers, au)
-				CardsMap[EditableCard.Id] = EditableCard
-				actualUserList.AddItem(fmt.Sprintf("%s", user.DisplayName), "",
-					rune, nil)
-				updateStacks()
-				BuildStacks()
-				app.SetFocus(userList)
-			})
+		userList := tview.NewList()
+		deck_theme.StyleList(userList)
+		userList.SetBorder(true)
+		userList.SetTitle(" add users")
+		userList.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+			if event.Key() == tcell.KeyTab {
+				return nil
+			}
+			return event
+		})
+		for _, user := range currentBoard.Users {
+			userList.AddItem(fmt.Sprintf("%s", user.DisplayName), "",
+				rune(0), nil)
+		}
 
-			EditUsersFlex.SetDirection(tview.FlexColumn)
-			EditUsersFlex.SetBorder(true)
-			EditUsersFlex.SetBorderColor(utils.GetColor(configuration.Color))
-			EditUsersFlex.SetTitle(fmt.Sprintf(" %s- EDIT Users ", DetailText.GetTitle()))
-
-			EditUsersFlex.AddItem(actualUserList, 0, 1, true)
-			EditUsersFlex.AddItem(userList, 0, 1, true)
-			EditUsersFlex.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
-				if event.Key() == tcell.KeyEsc {
-					deck_ui.BuildFullFlex(DetailText, nil)
-					return nil
-				}
-				if event.Key() == tcell.KeyTab {
-					focus := app.GetFocus().(*tview.List)
-					if focus == actualUserList {
-						app.SetFocus(userList)
-					} else {
-						app.SetFocus(actualUserList)
-					}
-				} else if event.Rune() == 63 {
-					// ? deck_help menu
-					deck_ui.BuildHelp(EditUsersFlex, deck_help.HelpUsers)
-				}
-				return event
-			})
+		userList.SetSelectedFunc(func(index int, name string, secondName string, rune rune) {
+			user := currentBoard.Users[index]
+			if !AssignCardUser(user) {
+				return
+			}
+			actualUserList.AddItem(fmt.Sprintf("%s", user.DisplayName), "",
+				rune, nil)
+		})
 
-			deck_ui.BuildFullFlex(EditUsersFlex, nil)
+		EditUsersFlex.SetDirection(tview.FlexColumn)
+		EditUsersFlex.SetBorder(true)
+		EditUsersFlex.SetTitle(fmt.Sprintf(" %s- EDIT Users ", DetailText.GetTitle()))
 
-		} else if event.Rune() == 116 {
-			// t -> edit detail
-			var form *tview.Form
-			form, card := BuildDetailForm(&EditableCard)
-			EditableCard = *card
+		EditUsersFlex.AddItem(actualUserList, 0, 1, true)
+		EditUsersFlex.AddItem(userList, 0, 1, true)
+		EditUsersFlex.SetInputCapture(deck_keys.Capture(deck_keys.Users))
+		deck_keys.SetHandler("users.back", func() {
+			showDetail(nil)
+		})
+		deck_keys.SetHandler("users.switch-list", func() {
+			if app.GetFocus() == actualUserList {
+				app.SetFocus(userList)
+			} else {
+				app.SetFocus(actualUserList)
+			}
+		})
+		deck_keys.SetHandler("users.help", func() {
+			deck_ui.BuildHelp(EditUsersFlex, deck_keys.Users)
+		})
 
-			form.AddButton("Save", func() {
-				go editCard()
-				if len(EditableCard.DueDate) > 0 {
-					pattern := "02/01/2006 15:04"
-					parse, _ := time.Parse(pattern, EditableCard.DueDate)
-					EditableCard.DueDate = parse.Format("2006-01-02T15:04:05+00:00")
-				}
-				CardsMap[EditableCard.Id] = EditableCard
-				DetailText.SetTitle(fmt.Sprintf(" %s ", EditableCard.Title))
-				updateStacks()
-				BuildStacks()
-				deck_ui.BuildFullFlex(DetailText, nil)
-			})
-			deck_ui.BuildFullFlex(form, nil)
-		} else if event.Rune() == 63 {
-			// ? -> deck_help menu
-			deck_ui.BuildHelp(DetailText, deck_help.HelpView)
-		}
-		return event
+		deck_ui.BuildFullFlex(EditUsersFlex, nil)
 	})
-
-	DetailEditText.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
-		if event.Key() == tcell.KeyEscape {
-			DetailText.Clear()
-			DetailText.SetTitle(fmt.Sprintf(" %s ", EditableCard.Title))
-			DetailText.SetText(deck_markdown.GetMarkDownDescription(utils.FormatDescription(EditableCard.Description), configuration))
-			deck_ui.BuildFullFlex(DetailText, nil)
-		} else if event.Key() == tcell.KeyF2 {
-			EditableCard.Description = DetailEditText.GetText()
-			go editCard()
+	deck_keys.SetHandler("card.edit-details", func() {
+		previous := EditableCard
+		var form *tview.Form
+		form, card := BuildDetailForm(&EditableCard)
+		EditableCard = *card
+
+		form.AddButton("Save", func() {
+			if len(EditableCard.DueDate) > 0 {
+				pattern := "02/01/2006 15:04"
+				parse, _ := time.Parse(pattern, EditableCard.DueDate)
+				EditableCard.DueDate = parse.Format("2006-01-02T15:04:05+00:00")
+			}
+			go editCard(previous, EditableCard)
 			CardsMap[EditableCard.Id] = EditableCard
-			DetailText.SetText(deck_markdown.GetMarkDownDescription(utils.FormatDescription(EditableCard.Description), configuration))
-			deck_ui.BuildFullFlex(DetailText, nil)
+			DetailText.SetTitle(fmt.Sprintf(" %s ", EditableCard.Title))
+			updateStacks()
+			BuildStacks()
+			showDetail(nil)
+		})
+		deck_ui.BuildFullFlex(form, nil)
+	})
+	deck_keys.SetHandler("card.next-checklist-item", func() {
+		moveCheckListSelection(1)
+	})
+	deck_keys.SetHandler("card.previous-checklist-item", func() {
+		moveCheckListSelection(-1)
+	})
+	deck_keys.SetHandler("card.toggle-checklist-item", toggleCheckListItem)
+	deck_keys.SetHandler("card.edit-description-external", editDescriptionInEditor)
+	deck_keys.SetHandler("card.link-hints", showLinkHints)
+	deck_keys.SetHandler("card.open-browser", func() {
+		err := utils.OpenUrl(utils.GetCardUrl(configuration.Url, currentBoard.Id, EditableCard.Id), configuration.Opener)
+		if err != nil {
+			deck_ui.FooterBar.SetText(fmt.Sprintf("Error opening card: %s", err.Error()))
 		}
-		return event
+	})
+	deck_keys.SetHandler("card.help", func() {
+		deck_ui.BuildHelp(DetailFlex, deck_keys.Card)
+	})
+
+	DetailEditText.SetInputCapture(deck_keys.Capture(deck_keys.Edit))
+	deck_keys.SetHandler("edit.toggle-preview", func() {
+		previewVisible = !previewVisible
+		buildEditFlex()
+		app.SetFocus(DetailEditText)
+	})
+	deck_keys.SetHandler("edit.back", func() {
+		DetailText.Clear()
+		DetailText.SetTitle(fmt.Sprintf(" %s ", EditableCard.Title))
+		renderDescription()
+		showDetail(nil)
+	})
+	deck_keys.SetHandler("edit.save", func() {
+		previous := EditableCard
+		EditableCard.Description = DetailEditText.GetText()
+		go editCard(previous, EditableCard)
+		CardsMap[EditableCard.Id] = EditableCard
+		selectedCheckListItem = -1
+		renderDescription()
+		updateStacks()
+		BuildStacks()
+		showDetail(nil)
 	})
 	DetailText.SetBorder(true)
-	DetailText.SetBorderColor(utils.GetColor(configuration.Color))
+
+	DetailHeader.SetBorder(true)
+	DetailHeader.SetTitle(" Details ")
+	DetailHeader.SetDynamicColors(true)
+	DetailHeader.SetWrap(false)
+
+	DetailFlex.SetDirection(tview.FlexRow)
+	DetailFlex.AddItem(DetailHeader, 5, 0, false)
+	DetailFlex.AddItem(DetailText, 0, 1, true)
 
 	DetailEditText.SetBorder(true)
-	DetailEditText.SetBorderColor(utils.GetColor(configuration.Color))
+	DetailEditText.SetChangedFunc(updatePreview)
+	DetailEditText.SetMovedFunc(syncPreviewScroll)
+
+	DetailPreviewText.SetBorder(true)
+	DetailPreviewText.SetTitle(" PREVIEW ")
+	DetailPreviewText.SetDynamicColors(true)
+	DetailPreviewText.SetWordWrap(true)
+
+	EditFlex.SetDirection(tview.FlexColumn)
+	buildEditFlex()
+
+	applyTheme()
+	deck_theme.OnChange(reloadTheme)
+	deck_undo.OnChange(rebuildStacks)
+}
+
+func applyTheme() {
+	deck_theme.StyleBox(DetailFlex.Box)
+	deck_theme.StyleBox(DetailHeader.Box)
+	DetailHeader.SetTextColor(deck_theme.GetColor(deck_theme.Current.Text))
+	deck_theme.StyleBox(DetailText.Box)
+	DetailText.SetTextColor(deck_theme.GetColor(deck_theme.Current.Text))
+	deck_theme.StyleBox(DetailEditText.Box)
+	deck_theme.StyleTextArea(DetailEditText)
+	deck_theme.StyleBox(DetailPreviewText.Box)
+	DetailPreviewText.SetTextColor(deck_theme.GetColor(deck_theme.Current.Text))
+	deck_theme.StyleBox(EditTagsFlex.Box)
+	deck_theme.StyleBox(EditUsersFlex.Box)
+	deck_theme.StyleModal(Modal)
+}
+
+func reloadTheme() {
+	applyTheme()
+	rebuildStacks()
+	if EditableCard.Id != 0 {
+		renderDescription()
+		updatePreview()
+	}
+}
+
+func buildEditFlex() {
+	EditFlex.Clear()
+	EditFlex.AddItem(DetailEditText, 0, 1, true)
+	if previewVisible {
+		EditFlex.AddItem(DetailPreviewText, 0, 1, false)
+	}
+}
+
+func updatePreview() {
+	DetailPreviewText.SetText(deck_markdown.GetMarkDownDescription(DetailEditText.GetText(), configuration))
+	syncPreviewScroll()
+}
+
+func syncPreviewScroll() {
+	text := DetailEditText.GetText()
+	_, cursor, _ := DetailEditText.GetSelection()
+	lines := strings.Count(text, "\n") + 1
+	cursorLine := strings.Count(text[:cursor], "\n")
+
+	previewLines := DetailPreviewText.GetOriginalLineCount()
+	_, _, _, height := DetailPreviewText.GetInnerRect()
+	row := cursorLine*previewLines/lines - height/2
+	if row < 0 {
+		row = 0
+	}
+	DetailPreviewText.ScrollTo(row, 0)
 }
 
 func updateStacks() {
+	updateStackCard(EditableCard)
+}
+
+func updateStackCard(card deck_structs.Card) {
 	for i, s := range deck_stack.Stacks {
-		if s.Id == EditableCard.StackId {
+		if s.Id == card.StackId {
 			for j, c := range s.Cards {
-				if c.Id == EditableCard.Id {
-					deck_stack.Stacks[i].Cards[j] = EditableCard
+				if c.Id == card.Id {
+					deck_stack.Stacks[i].Cards[j] = card
 					break
 				}
 			}
@@ -394,12 +537,27 @@ func updateStacks() {
 	}
 }
 
-func moveCardToStack(todoList *tview.List, primitive *tview.Primitive, key tcell.Key) {
-	i := todoList.GetCurrentItem()
-	name, _ := todoList.GetItemText(i)
-	cardId := utils.GetId(name)
-	card := CardsMap[cardId]
+func addStackCard(card deck_structs.Card) {
+	for i, s := range deck_stack.Stacks {
+		if s.Id == card.StackId {
+			deck_stack.Stacks[i].Cards = append([]deck_structs.Card{card}, s.Cards...)
+			break
+		}
+	}
+}
+
+func removeStackCard(cardId int) {
+	for i, s := range deck_stack.Stacks {
+		for j, c := range s.Cards {
+			if c.Id == cardId {
+				deck_stack.Stacks[i].Cards = append(s.Cards[:j:j], s.Cards[j+1:]...)
+				return
+			}
+		}
+	}
+}
 
+func moveCardToStack(todoList *tview.List, primitive *tview.Primitive, key tcell.Key) {
 	actualPrimitiveIndex := deck_ui.Primitives[*primitive]
 
 	var operator int
@@ -420,52 +578,93 @@ func moveCardToStack(todoList *tview.List, primitive *tview.Primitive, key tcell
 		break
 	}
 
-	nextStack := deck_stack.Stacks[actualPrimitiveIndex+operator]
+	moveCardToStackIndex(todoList, actualPrimitiveIndex+operator)
+}
 
-	jsonBody := fmt.Sprintf(`{"stackId": "%d", "title": "%s", "type": "plain", "owner":"%s"}`,
-		nextStack.Id, utils.CleanText(card.Title), configuration.User)
+func GetSelectedCard() (deck_structs.Card, bool) {
+	return GetListCard(app.GetFocus())
+}
 
-	go updateCard(currentBoard.Id, card.StackId, card.Id, jsonBody)
+func GetListCard(primitive tview.Primitive) (deck_structs.Card, bool) {
+	todoList, ok := primitive.(*tview.List)
+	if !ok || todoList.GetItemCount() == 0 {
+		return deck_structs.Card{}, false
+	}
+	name, _ := todoList.GetItemText(todoList.GetCurrentItem())
+	card, ok := CardsMap[utils.GetId(name)]
+	return card, ok
+}
 
-	var labels = utils.BuildLabels(card)
-	card.StackId = nextStack.Id
-	CardsMap[card.Id] = card
+func MoveSelectedCard(stackIndex int) {
+	todoList, ok := app.GetFocus().(*tview.List)
+	if !ok || todoList.GetItemCount() == 0 || deck_ui.Primitives[todoList] == stackIndex {
+		return
+	}
+	moveCardToStackIndex(todoList, stackIndex)
+}
 
-	destList := deck_ui.GetNextFocus(actualPrimitiveIndex + operator).(*tview.List)
-	todoList.RemoveItem(i)
+func moveCardToStackIndex(todoList *tview.List, stackIndex int) {
+	i := todoList.GetCurrentItem()
+	name, _ := todoList.GetItemText(i)
+	cardId := utils.GetId(name)
+	card := CardsMap[cardId]
 
-	dueDate := ""
-	if len(card.DueDate) > 0 {
-		parse, _ := time.Parse("2006-01-02T15:04:05+00:00", card.DueDate)
-		card.DueDate = parse.Format("02/01/2006 15:04")
-		dueDate = fmt.Sprintf("- [red:-:-](%s)[white]", card.DueDate)
-	}
+	nextStack := deck_stack.Stacks[stackIndex]
 
-	assigners := make([]string, 0)
-	for _, o := range card.AssignedUsers {
-		assigners = append(assigners, o.Participant.GetAbbrv())
-	}
+	go updateCard(currentBoard.Id, card.StackId, card.Id, getMoveJson(card, nextStack.Id))
 
-	assignersFormatter := ""
-	if len(assigners) > 0 {
-		assignersFormatter = fmt.Sprintf("- [red:gray:-]%s[-:-:-] ", utils.CommaString(assigners))
+	var labels = utils.BuildLabels(card, !deck_theme.IsNoColor())
+	previousStackId := card.StackId
+	removeStackCard(card.Id)
+	card.StackId = nextStack.Id
+	CardsMap[card.Id] = card
+	addStackCard(card)
+	recordMoveCard(card, previousStackId, nextStack.Id)
+
+	if laneIndex, ok := deck_ui.Lanes[todoList]; ok {
+		BuildStacks()
+		focusLaneCard(laneIndex, stackIndex, card.Id)
+		return
 	}
 
-	destList.InsertItem(0, fmt.Sprintf("[%s]#%d[white] %s- %s %s", configuration.Color, card.Id, assignersFormatter, card.Title, dueDate), labels, rune(0), nil)
+	destList := deck_ui.GetNextFocus(stackIndex).(*tview.List)
+	todoList.RemoveItem(i)
+
+	destList.InsertItem(0, getCardItemText(card), labels, rune(0), nil)
 	destList.SetCurrentItem(0)
 	app.SetFocus(destList)
 }
 
-func BuildAddForm() (*tview.Form, *deck_structs.Card) {
+func getMoveJson(card deck_structs.Card, stackId int) string {
+	return fmt.Sprintf(`{"stackId": "%d", "title": "%s", "type": "plain", "owner":"%s"}`,
+		stackId, utils.CleanText(card.Title), configuration.User)
+}
+
+func ShowAddForm(actualList *tview.List, c deck_structs.Card, title string) {
+	addForm, card := BuildAddForm(c)
+	addForm.SetTitle(title)
+	addForm.AddButton("Save", func() {
+		newCard := *card
+		if len(newCard.DueDate) > 0 {
+			pattern := "02/01/2006 15:04"
+			parse, err := time.Parse(pattern, newCard.DueDate)
+			if err != nil {
+				deck_ui.FooterBar.SetText(fmt.Sprintf("Not a valid date, format must be dd/MM/YYYY HH:mm: %s", err.Error()))
+				return
+			}
+			newCard.DueDate = parse.Format("2006-01-02T15:04:05+00:00")
+		}
+		AddCard(actualList, newCard)
+	})
+	deck_ui.BuildFullFlex(addForm, nil)
+}
+
+func BuildAddForm(c deck_structs.Card) (*tview.Form, *deck_structs.Card) {
 	addForm := tview.NewForm()
-	card := deck_structs.Card{}
+	card := c
 	addForm.SetTitle(" Add Card ")
 	addForm.SetBorder(true)
-	addForm.SetBorderColor(utils.GetColor(configuration.Color))
-	addForm.SetButtonBackgroundColor(utils.GetColor(configuration.Color))
-	addForm.SetFieldBackgroundColor(tcell.ColorWhite)
-	addForm.SetFieldTextColor(tcell.ColorBlack)
-	addForm.SetLabelColor(utils.GetColor(configuration.Color))
+	deck_theme.StyleForm(addForm)
 	addForm.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
 		if event.Key() == tcell.KeyEsc {
 			deck_ui.BuildFullFlex(deck_ui.MainFlex, nil)
@@ -473,14 +672,14 @@ func BuildAddForm() (*tview.Form, *deck_structs.Card) {
 		}
 		return event
 	})
-	addForm.AddInputField("Title", "", 20, nil, func(title string) {
+	addForm.AddInputField("Title", card.Title, 20, nil, func(title string) {
 		card.Title = title
 	})
-	addForm.AddTextArea("Description", "", 60, 10, 300, func(description string) {
+	addForm.AddTextArea("Description", card.Description, 60, 10, 0, func(description string) {
 		card.Description = description
 	})
 
-	addForm.AddInputField("Due Date", "", 18, func(textToCheck string, lastChar rune) bool {
+	addForm.AddInputField("Due Date", card.DueDate, 18, func(textToCheck string, lastChar rune) bool {
 
 		//re := regexp.MustCompile(`[0-9]{2}/[0-9]{2}/[0-9]{4} [0-9]{2}:[0-9]{2}`)
 		//match := re.FindAllStringSubmatch(textToCheck, -1)
@@ -494,7 +693,7 @@ func BuildAddForm() (*tview.Form, *deck_structs.Card) {
 		card.DueDate = date
 	})
 
-	addForm.AddInputField("Order", "0", 5, func(textToCheck string, lastChar rune) bool {
+	addForm.AddInputField("Order", strconv.Itoa(card.Order), 5, func(textToCheck string, lastChar rune) bool {
 		if lastChar < 48 || lastChar > 57 {
 			return false
 		}
@@ -504,6 +703,21 @@ func BuildAddForm() (*tview.Form, *deck_structs.Card) {
 		card.Order = orderInt
 	})
 
+	if len(card.Labels) > 0 {
+		labels := make([]string, 0)
+		for _, label := range card.Labels {
+			labels = append(labels, fmt.Sprintf("[%s]%s[-]", deck_theme.LabelColor(label.Color), label.Title))
+		}
+		addForm.AddTextView("Labels", strings.Join(labels, ", "), 60, 1, true, false)
+	}
+	if len(card.AssignedUsers) > 0 {
+		users := make([]string, 0)
+		for _, user := range card.AssignedUsers {
+			users = append(users, user.Participant.DisplayName)
+		}
+		addForm.AddTextView("Users", strings.Join(users, ", "), 60, 1, false, false)
+	}
+
 	return addForm, &card
 }
 
@@ -511,11 +725,7 @@ func BuildDetailForm(card *deck_structs.Card) (*tview.Form, *deck_structs.Card)
 	addForm := tview.NewForm()
 	addForm.SetTitle(" Edit Card Details ")
 	addForm.SetBorder(true)
-	addForm.SetBorderColor(utils.GetColor(configuration.Color))
-	addForm.SetButtonBackgroundColor(utils.GetColor(configuration.Color))
-	addForm.SetFieldBackgroundColor(tcell.ColorWhite)
-	addForm.SetFieldTextColor(tcell.ColorBlack)
-	addForm.SetLabelColor(utils.GetColor(configuration.Color))
+	deck_theme.StyleForm(addForm)
 	addForm.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
 		if event.Key() == tcell.KeyEsc {
 			deck_ui.BuildFullFlex(deck_ui.MainFlex, nil)
@@ -573,6 +783,31 @@ func AddCard(actualList *tview.List, card deck_structs.Card) {
 		return
 	}
 
+	errs := make([]string, 0)
+	for _, label := range card.Labels {
+		_, err = deck_http.AssignLabel(currentBoard.Id, stack.Id, newCard.Id, fmt.Sprintf(`{"labelId": %d}`, label.Id), configuration)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("label %s: %s", label.Title, err.Error()))
+			continue
+		}
+		newCard.Labels = append(newCard.Labels, label)
+	}
+	for _, user := range card.AssignedUsers {
+		_, err = deck_http.AssignUser(currentBoard.Id, stack.Id, newCard.Id, fmt.Sprintf(`{"userId": "%s"}`, user.Participant.Uid), configuration)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("user %s: %s", user.Participant.DisplayName, err.Error()))
+			continue
+		}
+		user.CardId = newCard.Id
+		newCard.AssignedUsers = append(newCard.AssignedUsers, user)
+	}
+	if len(errs) > 0 {
+		err = fmt.Errorf("error setting up new card: %s", strings.Join(errs, ", "))
+		deck_ui.FooterBar.SetText(fmt.Sprintf("Error setting up new card: %s", strings.Join(errs, ", ")))
+	}
+
+	recordAddCard(newCard)
+
 	dueDate := ""
 	if len(card.DueDate) > 0 {
 		parse, _ := time.Parse("2006-01-02T15:04:05+00:00", newCard.DueDate)
@@ -580,7 +815,7 @@ func AddCard(actualList *tview.List, card deck_structs.Card) {
 		dueDate = fmt.Sprintf("(%s)", newCard.DueDate)
 	}
 
-	actualList.InsertItem(card.Order, fmt.Sprintf("[%s]#%d[white] - %s [red:-:-]%s[white]", configuration.Color, newCard.Id, newCard.Title, dueDate), "", rune(0), nil)
+	actualList.InsertItem(card.Order, fmt.Sprintf("[%s]#%d[-] - %s [%s:-:-]%s[-]", deck_theme.Current.Accent, newCard.Id, newCard.Title, deck_theme.Current.Danger, dueDate), "", rune(0), nil)
 	CardsMap[newCard.Id] = newCard
 	DetailText.Clear()
 	EditableCard = newCard
@@ -592,36 +827,41 @@ func AddCard(actualList *tview.List, card deck_structs.Card) {
 	}
 	DetailText.SetTitle(fmt.Sprintf(" %s ", newCard.Title))
 	DetailText.SetText(utils.FormatDescription(newCard.Description))
-	deck_ui.BuildFullFlex(DetailText, err)
+	showDetail(err)
 }
 
-func editCard() {
-	description := utils.CleanText(EditableCard.Description)
-	title := utils.CleanText(EditableCard.Title)
-	dueDateFormat := ""
-	if len(EditableCard.DueDate) > 0 {
-		dueDateFormat = fmt.Sprintf(`,"duedate": "%s"`, EditableCard.DueDate)
-	}
-	jsonBody := fmt.Sprintf(`{"description": "%s", "title": "%s", "type": "plain", "owner":"%s"%s}`, utils.CleanText(description), utils.CleanText(title), configuration.User, dueDateFormat)
-	var err error
-	_, err = deck_http.UpdateCard(currentBoard.Id, EditableCard.StackId, EditableCard.Id, jsonBody, configuration)
-	if err != nil {
-		deck_ui.FooterBar.SetText(fmt.Sprintf("Error updating card: %s", err.Error()))
+func editCard(previous deck_structs.Card, card deck_structs.Card) {
+	_, err := deck_http.UpdateCard(currentBoard.Id, card.StackId, card.Id, getCardJson(card), configuration)
+	app.QueueUpdateDraw(func() {
+		if err != nil {
+			deck_ui.FooterBar.SetText(fmt.Sprintf("Error updating card: %s", err.Error()))
+			return
+		}
+		recordEditCard(previous, card)
+	})
+}
+
+func getCardJson(card deck_structs.Card) string {
+	dueDateFormat := `,"duedate": null`
+	if len(card.DueDate) > 0 {
+		dueDateFormat = fmt.Sprintf(`,"duedate": "%s"`, card.DueDate)
 	}
+	return fmt.Sprintf(`{"description": "%s", "title": "%s", "type": "plain", "owner":"%s"%s}`, utils.CleanText(card.Description), utils.CleanText(card.Title), configuration.User, dueDateFormat)
 }
 
 func updateCard(boardId, stackId int, cardId int, jsonBody string) {
 	_, err := deck_http.UpdateCard(boardId, stackId, cardId, jsonBody, configuration)
 	if err != nil {
-		deck_ui.FooterBar.SetText(fmt.Sprintf("Error moving card: %s", err.Error()))
-		return
+		app.QueueUpdateDraw(func() {
+			deck_ui.FooterBar.SetText(fmt.Sprintf("Error moving card: %s", err.Error()))
+		})
 	}
 }
 
 func DeleteCard(cardId int, stack deck_structs.Stack, actualList *tview.List, currentItemIndex int) {
 	Modal.ClearButtons()
 	Modal.SetText(fmt.Sprintf("Are you sure to delete card #%d?", cardId))
-	Modal.SetBackgroundColor(utils.GetColor(configuration.Color))
+	deck_theme.StyleModal(Modal)
 
 	Modal.AddButtons([]string{"Yes", "No"})
 
@@ -638,12 +878,23 @@ func DeleteCard(cardId int, stack deck_structs.Stack, actualList *tview.List, cu
 
 	Modal.SetDoneFunc(func(buttonIndex int, buttonLabel string) {
 		if buttonLabel == "Yes" {
+			card := CardsMap[cardId]
 			go func() {
+				comments, commentsErr := getAllComments(cardId)
 				_, err := deck_http.DeleteCard(currentBoard.Id, stack.Id, cardId, configuration)
-				if err != nil {
-					deck_ui.FooterBar.SetText(fmt.Sprintf("Error deleting card: %s", err.Error()))
-				}
+				app.QueueUpdateDraw(func() {
+					if err != nil {
+						deck_ui.FooterBar.SetText(fmt.Sprintf("Error deleting card: %s", err.Error()))
+						return
+					}
+					recordDeleteCard(card, comments)
+					if commentsErr != nil {
+						deck_ui.FooterBar.SetText(fmt.Sprintf("Error %s", commentsErr.Error()))
+					}
+				})
 			}()
+			removeStackCard(cardId)
+			delete(CardsMap, cardId)
 			actualList.RemoveItem(currentItemIndex)
 			deck_ui.MainFlex.RemoveItem(Modal)
 			app.SetFocus(actualList)
@@ -657,15 +908,141 @@ func DeleteCard(cardId int, stack deck_structs.Stack, actualList *tview.List, cu
 	app.SetFocus(Modal)
 }
 
-func AssignLabel(jsonBody string) {
-	_, err := deck_http.AssignLabel(currentBoard.Id, EditableCard.StackId, EditableCard.Id, jsonBody, configuration)
-	if err != nil {
-		deck_ui.FooterBar.SetText(fmt.Sprintf("Error assigning tag to card: %s", err.Error()))
+func AddCardLabel(label deck_structs.Label) bool {
+	for _, l := range EditableCard.Labels {
+		if l.Id == label.Id {
+			deck_ui.FooterBar.SetText("label already assigned")
+			return false
+		}
 	}
+
+	jsonBody := fmt.Sprintf(`{"labelId": %d }`, label.Id)
+	go AssignLabel(jsonBody)
+	EditableCard.Labels = append(EditableCard.Labels, label)
+	refreshEditableCard()
+	recordCardLabel(EditableCard, label, true)
+	return true
 }
 
-func DeleteLabel(jsonBody string) {
-	_, err := deck_http.DeleteLabel(currentBoard.Id, EditableCard.StackId, EditableCard.Id, jsonBody, configuration)
+func RemoveCardLabel(index int) {
+	label := EditableCard.Labels[index]
+	jsonBody := fmt.Sprintf(`{"labelId": %d}`, label.Id)
+	go DeleteLabel(jsonBody)
+	EditableCard.Labels = append(EditableCard.Labels[:index], EditableCard.Labels[index+1:]...)
+	refreshEditableCard()
+	recordCardLabel(EditableCard, label, false)
+}
+
+func AssignCardUser(user deck_structs.Owner) bool {
+	for _, u := range EditableCard.AssignedUsers {
+		if u.Participant.Uid == user.Uid {
+			deck_ui.FooterBar.SetText("user already assigned")
+			return false
+		}
+	}
+
+	jsonBody := fmt.Sprintf(`{"userId": "%s" }`, user.Uid)
+	go AssignUser(jsonBody)
+
+	au := deck_structs.AssignedUser{
+		CardId: EditableCard.Id,
+		Type:   0,
+		Participant: deck_structs.Owner{
+			PrimaryKey:  user.PrimaryKey,
+			Uid:         user.Uid,
+			DisplayName: user.DisplayName,
+		},
+	}
+	EditableCard.AssignedUsers = append(EditableCard.AssignedUsers, au)
+	refreshEditableCard()
+	recordCardUser(EditableCard, au.Participant, true)
+	return true
+}
+
+func UnassignCardUser(index int) {
+	user := EditableCard.AssignedUsers[index]
+	jsonBody := fmt.Sprintf(`{"userId": "%s"}`, user.Participant.Uid)
+	go DeleteUser(jsonBody)
+	EditableCard.AssignedUsers = append(EditableCard.AssignedUsers[:index], EditableCard.AssignedUsers[index+1:]...)
+	refreshEditableCard()
+	recordCardUser(EditableCard, user.Participant, false)
+}
+
+func AssignUserToCard(card deck_structs.Card, user deck_structs.Owner) bool {
+	if c, ok := CardsMap[card.Id]; ok {
+		card = c
+	}
+	if hasUser(card, user) {
+		deck_ui.FooterBar.SetText("user already assigned")
+		return false
+	}
+
+	go func() {
+		_, err := deck_http.AssignUser(currentBoard.Id, card.StackId, card.Id, fmt.Sprintf(`{"userId": "%s"}`, user.Uid), configuration)
+		if err != nil {
+			app.QueueUpdateDraw(func() {
+				deck_ui.FooterBar.SetText(fmt.Sprintf("Error assigning user to card: %s", err.Error()))
+			})
+		}
+	}()
+	updateCardState(withUser(card, user, true))
+	rebuildStacks()
+	recordCardUser(card, user, true)
+	return true
+}
+
+func AddLabelToCard(card deck_structs.Card, label deck_structs.Label) bool {
+	if c, ok := CardsMap[card.Id]; ok {
+		card = c
+	}
+	if hasLabel(card, label) {
+		deck_ui.FooterBar.SetText("label already assigned")
+		return false
+	}
+
+	go func() {
+		_, err := deck_http.AssignLabel(currentBoard.Id, card.StackId, card.Id, fmt.Sprintf(`{"labelId": %d}`, label.Id), configuration)
+		if err != nil {
+			app.QueueUpdateDraw(func() {
+				deck_ui.FooterBar.SetText(fmt.Sprintf("Error assigning tag to card: %s", err.Error()))
+			})
+		}
+	}()
+	updateCardState(withLabel(card, label, true))
+	rebuildStacks()
+	recordCardLabel(card, label, true)
+	return true
+}
+
+func refreshEditableCard() {
+	CardsMap[EditableCard.Id] = EditableCard
+	updateStacks()
+	rebuildStacks()
+}
+
+func rebuildStacks() {
+	focus := app.GetFocus()
+	stackIndex, onStack := deck_ui.Primitives[focus]
+	laneIndex, onLane := deck_ui.Lanes[focus]
+	BuildStacks()
+	if onLane {
+		app.SetFocus(deck_ui.GetLaneFocus(laneIndex, stackIndex))
+	} else if onStack {
+		app.SetFocus(deck_ui.GetNextFocus(stackIndex))
+	} else {
+		app.SetFocus(focus)
+	}
+}
+
+func AssignLabel(jsonBody string) {
+	_, err := deck_http.AssignLabel(currentBoard.Id, EditableCard.StackId, EditableCard.Id, jsonBody, configuration)
+	if err != nil {
+		deck_ui.FooterBar.SetText(fmt.Sprintf("Error assigning tag to card: %s", err.Error()))
+	}
+}
+
+func DeleteLabel(jsonBody string) {
+	_, err := deck_http.DeleteLabel(currentBoard.Id, EditableCard.StackId, EditableCard.Id, jsonBody, configuration)
 	if err != nil {
 		deck_ui.FooterBar.SetText(fmt.Sprintf("Error deleting tag from card: %s", err.Error()))
 	}
@@ -688,133 +1065,1563 @@ func BuildStacks() {
 	deck_ui.MainFlex.Clear()
 	deck_ui.Primitives = make(map[tview.Primitive]int)
 	deck_ui.PrimitivesIndexMap = make(map[int]tview.Primitive)
+	deck_ui.Lanes = make(map[tview.Primitive]int)
+	deck_ui.LanesIndexMap = make(map[int]map[int]tview.Primitive)
 
 	sort.Slice(deck_stack.Stacks, func(i, j int) bool {
 		return deck_stack.Stacks[i].Order < deck_stack.Stacks[j].Order
 	})
 
+	if layout != StacksLayout {
+		buildSwimlanes()
+		return
+	}
+	deck_ui.MainFlex.SetDirection(tview.FlexColumn)
+
 	for index, s := range deck_stack.Stacks {
-		todoList := tview.NewList()
-		todoList.SetTitle(fmt.Sprintf(" %s ", s.Title))
-		todoList.SetBorder(true)
+		todoList := buildCardList(s.Title, s.Cards)
 
-		todoList.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
-			if event.Key() == tcell.KeyTAB {
-				return nil
-			}
-			if event.Key() == tcell.KeyRight {
-				if todoList.GetItemCount() == 0 {
-					return nil
+		deck_ui.Primitives[todoList] = index
+		deck_ui.PrimitivesIndexMap[index] = todoList
+
+		deck_ui.MainFlex.AddItem(todoList, 0, 1, true)
+		primitive := deck_ui.MainFlex.GetItem(0)
+		app.SetFocus(primitive)
+	}
+}
+
+func buildSwimlanes() {
+	deck_ui.MainFlex.SetDirection(tview.FlexRow)
+
+	for laneIndex, l := range getLanes() {
+		laneFlex := tview.NewFlex()
+		deck_theme.StyleBox(laneFlex.Box)
+		deck_ui.LanesIndexMap[laneIndex] = make(map[int]tview.Primitive)
+
+		for index, s := range deck_stack.Stacks {
+			cards := make([]deck_structs.Card, 0)
+			for _, card := range s.Cards {
+				if l.matches(card) {
+					cards = append(cards, card)
 				}
-				moveStackModal(todoList, tcell.KeyRight)
-				return nil
 			}
-			if event.Key() == tcell.KeyLeft {
-				if todoList.GetItemCount() == 0 {
-					return nil
-				}
-				moveStackModal(todoList, tcell.KeyLeft)
-				return nil
+			title := s.Title
+			if index == 0 {
+				title = fmt.Sprintf("%s · %s", l.title, s.Title)
 			}
-			return event
-		})
-
-		sort.Slice(s.Cards, func(i, j int) bool {
-			return s.Cards[i].Order < s.Cards[j].Order
-		})
+			todoList := buildCardList(title, cards)
 
-		for _, card := range s.Cards {
-			var labels = utils.BuildLabels(card)
-			secondLine := fmt.Sprintf("%s", labels)
-			checked, total, err := deck_markdown.CountCheckList(card.Description)
-			if err == nil {
-				secondLine = fmt.Sprintf("[white]%s | [-:-:-]%s", fmt.Sprintf("%d/%d", checked, total), labels)
+			deck_ui.Primitives[todoList] = index
+			deck_ui.Lanes[todoList] = laneIndex
+			deck_ui.LanesIndexMap[laneIndex][index] = todoList
+			if laneIndex == 0 {
+				deck_ui.PrimitivesIndexMap[index] = todoList
 			}
 
-			CardsMap[card.Id] = card
+			laneFlex.AddItem(todoList, 0, 1, true)
+		}
+		deck_ui.MainFlex.AddItem(laneFlex, 0, 1, laneIndex == 0)
+	}
+	if len(deck_stack.Stacks) > 0 {
+		app.SetFocus(deck_ui.GetLaneFocus(0, 0))
+	}
+}
 
-			dueDate := ""
-			if len(card.DueDate) > 0 {
-				parse, _ := time.Parse("2006-01-02T15:04:05+00:00", card.DueDate)
-				card.DueDate = parse.Format("02/01/2006 15:04")
-				dueDate = fmt.Sprintf("- [red:-:-](%s)[white]", card.DueDate)
-			}
+func buildCardList(title string, cards []deck_structs.Card) *tview.List {
+	todoList := tview.NewList()
+	deck_theme.StyleList(todoList)
+	todoList.SetTitle(fmt.Sprintf(" %s ", title))
+	todoList.SetBorder(true)
 
-			assigners := make([]string, 0)
-			for _, o := range card.AssignedUsers {
-				assigners = append(assigners, o.Participant.GetAbbrv())
-			}
+	todoList.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyTAB {
+			return nil
+		}
+		return event
+	})
 
-			assignersFormatter := ""
-			if len(assigners) > 0 {
-				assignersFormatter = fmt.Sprintf("- [red:gray:-]%s[-:-:-] ", utils.CommaString(assigners))
-			}
+	sort.Slice(cards, func(i, j int) bool {
+		return cards[i].Order < cards[j].Order
+	})
 
-			todoList.AddItem(fmt.Sprintf("[%s]#%d[white] %s- %s %s", configuration.Color, card.Id, assignersFormatter, card.Title, dueDate), secondLine, rune(0), nil)
+	for _, card := range cards {
+		var labels = utils.BuildLabels(card, !deck_theme.IsNoColor())
+		secondLine := fmt.Sprintf("%s", labels)
+		checked, total, err := deck_markdown.CountCheckList(card.Description)
+		if err == nil {
+			secondLine = fmt.Sprintf("[-]%s | [-:-:-]%s", fmt.Sprintf("%d/%d", checked, total), labels)
 		}
 
-		todoList.SetSelectedFunc(func(index int, name string, secondName string, shortcut rune) {
-			cardId := utils.GetId(name)
+		CardsMap[card.Id] = card
 
-			DetailText.SetTitle(fmt.Sprintf(" #%d - %s ", CardsMap[cardId].Id, CardsMap[cardId].Title))
-			DetailText.SetDynamicColors(true)
+		todoList.AddItem(getCardItemText(card), secondLine, rune(0), nil)
+	}
 
-			description := utils.FormatDescription(CardsMap[cardId].Description)
-			DetailText.SetText(deck_markdown.GetMarkDownDescription(description, configuration))
-			EditableCard = CardsMap[cardId]
-			deck_ui.BuildFullFlex(DetailText, nil)
-		})
+	todoList.SetSelectedFunc(func(index int, name string, secondName string, shortcut rune) {
+		cardId := utils.GetId(name)
+		showCard(CardsMap[cardId])
+	})
 
-		todoList.SetFocusFunc(func() {
-			todoList.SetTitleColor(utils.GetColor(configuration.Color))
-		})
+	todoList.SetFocusFunc(func() {
+		todoList.SetTitleColor(deck_theme.Accent())
+	})
+	return todoList
+}
 
-		deck_ui.Primitives[todoList] = index
-		deck_ui.PrimitivesIndexMap[index] = todoList
+func getLanes() []lane {
+	lanes := make([]lane, 0)
+	switch layout {
+	case UsersLayout:
+		seen := make(map[string]bool)
+		for _, user := range currentBoard.Users {
+			if !seen[user.Uid] {
+				seen[user.Uid] = true
+				lanes = append(lanes, lane{title: user.DisplayName, layout: layout, user: user})
+			}
+		}
+		for _, s := range deck_stack.Stacks {
+			for _, card := range s.Cards {
+				for _, u := range card.AssignedUsers {
+					if !seen[u.Participant.Uid] {
+						seen[u.Participant.Uid] = true
+						lanes = append(lanes, lane{title: u.Participant.DisplayName, layout: layout, user: u.Participant})
+					}
+				}
+			}
+		}
+		lanes = append(lanes, lane{title: "Unassigned", layout: layout})
+	case LabelsLayout:
+		for _, label := range currentBoard.Labels {
+			lanes = append(lanes, lane{title: label.Title, layout: layout, label: label})
+		}
+		lanes = append(lanes, lane{title: "No label", layout: layout})
+	}
+	return lanes
+}
 
-		deck_ui.MainFlex.AddItem(todoList, 0, 1, true)
-		primitive := deck_ui.MainFlex.GetItem(0)
-		app.SetFocus(primitive)
+func (l lane) matches(card deck_structs.Card) bool {
+	if l.layout == UsersLayout {
+		if len(l.user.Uid) == 0 {
+			return len(card.AssignedUsers) == 0
+		}
+		return hasUser(card, l.user)
+	}
+	if l.label.Id == 0 {
+		return len(card.Labels) == 0
 	}
+	return hasLabel(card, l.label)
 }
 
-func moveStackModal(todoList *tview.List, key tcell.Key) {
-	currentIndex := todoList.GetCurrentItem()
-	currentText, _ := todoList.GetItemText(currentIndex)
-	cardId := utils.GetId(currentText)
+func (l lane) apply(card deck_structs.Card, assign bool) deck_structs.Card {
+	if len(l.user.Uid) > 0 {
+		return withUser(card, l.user, assign)
+	}
+	if l.label.Id != 0 {
+		return withLabel(card, l.label, assign)
+	}
+	return card
+}
 
-	primitive := app.GetFocus()
+func SwitchLayout() {
+	layout = (layout + 1) % 3
+	focus := app.GetFocus()
+	stackIndex := deck_ui.Primitives[focus]
+	BuildStacks()
+	if len(deck_stack.Stacks) > 0 {
+		app.SetFocus(deck_ui.GetLaneFocus(0, stackIndex))
+	}
+	deck_ui.FooterBar.SetText(fmt.Sprintf("Layout: %s", layoutNames[layout]))
+}
 
-	Modal.ClearButtons()
-	moveText := "next"
-	if key == tcell.KeyLeft {
-		moveText = "prev"
+func SwitchLane(delta int) {
+	todoList, ok := app.GetFocus().(*tview.List)
+	if !ok {
+		return
 	}
-	Modal.SetText(fmt.Sprintf("Are you sure to move card #%d to %s stack??", cardId, moveText))
-	Modal.SetBackgroundColor(utils.GetColor(configuration.Color))
+	laneIndex, ok := deck_ui.Lanes[todoList]
+	if !ok {
+		return
+	}
+	laneIndex += delta
+	if laneIndex < 0 {
+		laneIndex = len(deck_ui.LanesIndexMap) - 1
+	} else if laneIndex >= len(deck_ui.LanesIndexMap) {
+		laneIndex = 0
+	}
+	todoList.SetTitleColor(deck_theme.GetColor(deck_theme.Current.Text))
+	app.SetFocus(deck_ui.GetLaneFocus(laneIndex, deck_ui.Primitives[todoList]))
+}
 
-	Modal.AddButtons([]string{"Yes", "No"})
+func GetLaneCard(actualList *tview.List) deck_structs.Card {
+	card := deck_structs.Card{}
+	laneIndex, ok := deck_ui.Lanes[actualList]
+	if !ok {
+		return card
+	}
+	lanes := getLanes()
+	if laneIndex >= len(lanes) {
+		return card
+	}
+	return lanes[laneIndex].apply(card, true)
+}
 
-	Modal.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
-		if event.Key() == tcell.KeyEsc {
-			deck_ui.MainFlex.RemoveItem(Modal)
-			app.SetFocus(todoList)
+func MoveCardToLane(delta int) {
+	todoList, ok := app.GetFocus().(*tview.List)
+	if !ok || todoList.GetItemCount() == 0 {
+		return
+	}
+	laneIndex, ok := deck_ui.Lanes[todoList]
+	if !ok {
+		return
+	}
+	lanes := getLanes()
+	target := laneIndex + delta
+	if target < 0 || target >= len(lanes) || laneIndex >= len(lanes) {
+		return
+	}
+	name, _ := todoList.GetItemText(todoList.GetCurrentItem())
+	card, ok := CardsMap[utils.GetId(name)]
+	if !ok {
+		return
+	}
+	from, to := lanes[laneIndex], lanes[target]
+	if to.matches(card) {
+		to = lane{title: to.title, layout: to.layout}
+	}
+
+	go func() {
+		err := requestLane(card, from, to)
+		if err != nil {
+			app.QueueUpdateDraw(func() {
+				deck_ui.FooterBar.SetText(fmt.Sprintf("Error moving card to %s: %s", to.title, err.Error()))
+			})
 		}
-		if event.Key() == tcell.KeyRight || event.Key() == tcell.KeyLeft || event.Key() == tcell.KeyEnter {
-			return event
+	}()
+	updateCardState(to.apply(from.apply(card, false), true))
+	recordMoveLane(card, from, to)
+
+	stackIndex := deck_ui.Primitives[todoList]
+	BuildStacks()
+	focusLaneCard(target, stackIndex, card.Id)
+}
+
+func focusLaneCard(laneIndex int, stackIndex int, cardId int) {
+	list, ok := deck_ui.GetLaneFocus(laneIndex, stackIndex).(*tview.List)
+	if !ok {
+		return
+	}
+	for i := 0; i < list.GetItemCount(); i++ {
+		name, _ := list.GetItemText(i)
+		if utils.GetId(name) == cardId {
+			list.SetCurrentItem(i)
+			break
 		}
-		return nil
+	}
+	app.SetFocus(list)
+}
+
+func requestLane(card deck_structs.Card, from lane, to lane) error {
+	var err error
+	if len(from.user.Uid) > 0 && hasUser(card, from.user) {
+		_, err = deck_http.DeleteUser(currentBoard.Id, card.StackId, card.Id, fmt.Sprintf(`{"userId": "%s"}`, from.user.Uid), configuration)
+	}
+	if err == nil && len(to.user.Uid) > 0 && !hasUser(card, to.user) {
+		_, err = deck_http.AssignUser(currentBoard.Id, card.StackId, card.Id, fmt.Sprintf(`{"userId": "%s"}`, to.user.Uid), configuration)
+	}
+	if err == nil && from.label.Id != 0 && hasLabel(card, from.label) {
+		_, err = deck_http.DeleteLabel(currentBoard.Id, card.StackId, card.Id, fmt.Sprintf(`{"labelId": %d}`, from.label.Id), configuration)
+	}
+	if err == nil && to.label.Id != 0 && !hasLabel(card, to.label) {
+		_, err = deck_http.AssignLabel(currentBoard.Id, card.StackId, card.Id, fmt.Sprintf(`{"labelId": %d}`, to.label.Id), configuration)
+	}
+	return err
+}
+
+func setCardLane(cardId int, from lane, to lane) error {
+	card, err := getCard(cardId)
+	if err != nil {
+		return err
+	}
+	err = requestLane(card, from, to)
+	if err != nil {
+		return err
+	}
+	app.QueueUpdate(func() {
+		updateCardState(to.apply(from.apply(card, false), true))
 	})
+	return nil
+}
 
-	Modal.SetDoneFunc(func(buttonIndex int, buttonLabel string) {
-		if buttonLabel == "Yes" {
-			moveCardToStack(todoList, &primitive, key)
-			deck_ui.MainFlex.RemoveItem(Modal)
-		} else if buttonLabel == "No" {
-			deck_ui.MainFlex.RemoveItem(Modal)
-			app.SetFocus(todoList)
+func getCardItemText(card deck_structs.Card) string {
+	dueDate := ""
+	if len(card.DueDate) > 0 {
+		parse, _ := time.Parse("2006-01-02T15:04:05+00:00", card.DueDate)
+		dueDate = fmt.Sprintf("- [%s:-:-](%s)[-]", deck_theme.Current.Danger, parse.Format("02/01/2006 15:04"))
+	}
+
+	assigners := make([]string, 0)
+	for _, o := range card.AssignedUsers {
+		assigners = append(assigners, o.Participant.GetAbbrv())
+	}
+
+	assignersFormatter := ""
+	if len(assigners) > 0 {
+		assignersFormatter = fmt.Sprintf("- [%s:%s:-]%s[-:-:-] ", deck_theme.Current.Danger, deck_theme.Current.Muted, utils.CommaString(assigners))
+	}
+
+	mark := ""
+	if markedCards[card.Id] {
+		mark = fmt.Sprintf("[%s::b]*[-::-] ", deck_theme.Current.Warning)
+	}
+
+	return fmt.Sprintf("[%s]#%d[-] %s%s- %s %s", deck_theme.Current.Accent, card.Id, mark, assignersFormatter, card.Title, dueDate)
+}
+
+func OpenCard(cardId int) error {
+	return OpenCardFrom(cardId, nil)
+}
+
+func OpenCardFrom(cardId int, back func()) error {
+	for _, s := range deck_stack.Stacks {
+		for _, c := range s.Cards {
+			if c.Id == cardId {
+				showCard(CardsMap[cardId])
+				cardBack = back
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("card #%d not found in board %s", cardId, currentBoard.Title)
+}
+
+func showCard(card deck_structs.Card) {
+	DetailText.SetTitle(fmt.Sprintf(" #%d - %s ", card.Id, card.Title))
+	DetailText.SetDynamicColors(true)
+	DetailText.SetRegions(true)
+
+	EditableCard = card
+	selectedCheckListItem = -1
+	cardBack = nil
+	renderDescription()
+	showDetail(nil)
+}
+
+func showDetail(err error) {
+	renderHeader()
+	deck_ui.BuildFullFlex(DetailFlex, err)
+}
+
+func renderHeader() {
+	card := EditableCard
+	muted := deck_theme.Current.Muted
+
+	labels := utils.BuildLabels(card, !deck_theme.IsNoColor())
+	if len(labels) == 0 {
+		labels = "-"
+	}
+	assignees := make([]string, 0)
+	for _, u := range card.AssignedUsers {
+		assignees = append(assignees, u.Participant.DisplayName)
+	}
+	if len(assignees) == 0 {
+		assignees = append(assignees, "-")
+	}
+
+	dueDate := "-"
+	if len(card.DueDate) > 0 {
+		parse, err := time.Parse("2006-01-02T15:04:05+00:00", card.DueDate)
+		if err == nil {
+			dueDate = parse.Format("02/01/2006 15:04")
+			if parse.Before(time.Now()) {
+				dueDate = fmt.Sprintf("[%s]%s (overdue)[-]", deck_theme.Current.Danger, dueDate)
+			}
+		}
+	}
+
+	checkList := "-"
+	checked, total, err := deck_markdown.CountCheckList(card.Description)
+	if err == nil && total > 0 {
+		checkList = fmt.Sprintf("%d/%d", checked, total)
+	}
+
+	DetailHeader.SetText(fmt.Sprintf(
+		"[%s]Stack:[-] %s   [%s]Owner:[-] %s   [%s]Due:[-] %s\n"+
+			"[%s]Labels:[-] %s   [%s]Assignees:[-] %s\n"+
+			"[%s]Created:[-] %s   [%s]Modified:[-] %s   [%s]Checklist:[-] %s   [%s]Comments:[-] %d",
+		muted, tview.Escape(getStackTitle(card.StackId)), muted, tview.Escape(getOwnerName(card.Owner)), muted, dueDate,
+		muted, labels, muted, tview.Escape(strings.Join(assignees, ", ")),
+		muted, formatTimestamp(card.CreatedAt), muted, formatTimestamp(card.LastModified), muted, checkList, muted, card.CommentsCount))
+}
+
+func getOwnerName(owner deck_structs.Owner) string {
+	for _, user := range currentBoard.Users {
+		if len(owner.Uid) > 0 && user.Uid == owner.Uid {
+			return user.DisplayName
 		}
+	}
+	if len(owner.DisplayName) > 0 {
+		return owner.DisplayName
+	}
+	if len(owner.Uid) > 0 {
+		return owner.Uid
+	}
+	return "-"
+}
+
+func formatTimestamp(timestamp int64) string {
+	if timestamp == 0 {
+		return "-"
+	}
+	return time.Unix(timestamp, 0).Format("02/01/2006 15:04")
+}
+
+func renderDescription() {
+	renderHeader()
+	description := utils.FormatDescription(EditableCard.Description)
+	DetailText.SetText(deck_markdown.GetMarkDownDescriptionWithCheckList(description, configuration))
+	if selectedCheckListItem >= 0 {
+		DetailText.Highlight(deck_markdown.CheckListRegion(selectedCheckListItem))
+	} else {
+		DetailText.Highlight()
+	}
+}
+
+func editDescriptionInEditor() {
+	description := utils.FormatDescription(EditableCard.Description)
+	var edited string
+	var err error
+	app.Suspend(func() {
+		edited, err = utils.EditInExternalEditor(description)
 	})
-	deck_ui.MainFlex.AddItem(Modal, 0, 0, false)
-	app.SetFocus(Modal)
+	if err != nil {
+		deck_ui.FooterBar.SetText(fmt.Sprintf("Error running editor: %s", err.Error()))
+		return
+	}
+	if edited == description {
+		return
+	}
+	previous := EditableCard
+	EditableCard.Description = edited
+	go editCard(previous, EditableCard)
+	CardsMap[EditableCard.Id] = EditableCard
+	selectedCheckListItem = -1
+	renderDescription()
+	updateStacks()
+	BuildStacks()
+	showDetail(nil)
+}
+
+func showLinkHints() {
+	description := utils.FormatDescription(EditableCard.Description)
+	text, links := deck_markdown.GetMarkDownDescriptionWithLinkHints(description, configuration)
+
+	comments, err := getAllComments(EditableCard.Id)
+	if err != nil {
+		deck_ui.FooterBar.SetText(fmt.Sprintf("Error %s", err.Error()))
+	}
+	commentLinks := ""
+	for _, c := range comments {
+		for _, link := range deck_markdown.GetLinks(c.Message) {
+			found := false
+			for _, l := range links {
+				if l == link {
+					found = true
+					break
+				}
+			}
+			if !found {
+				commentLinks = commentLinks + fmt.Sprintf("%s [%s:-:u]%s[-:-:-] (comment #%d - %s)\n",
+					deck_markdown.LinkHint(len(links)), deck_theme.Current.LinkUrl, tview.Escape(link), c.Id, tview.Escape(c.ActorDisplayName))
+				links = append(links, link)
+			}
+		}
+	}
+	if len(links) == 0 {
+		deck_ui.FooterBar.SetText("No links found")
+		return
+	}
+	if len(commentLinks) > 0 {
+		text = text + "\n\n[::b]Links in comments[::-]\n" + commentLinks
+	}
+
+	linkHintMode = true
+	linkHints = links
+	linkHintInput = ""
+	DetailText.SetText(text)
+	deck_ui.FooterBar.SetText(fmt.Sprintf("Type a link number, then %s to open it or %s to copy it, %s to cancel",
+		deck_theme.Key("ENTER"), deck_theme.Key("y"), deck_theme.Key("ESC")))
+}
+
+func linkHintInputCapture(event *tcell.EventKey) *tcell.EventKey {
+	if event.Key() == tcell.KeyEscape {
+		// ESC -> leave link hints
+		exitLinkHints()
+		deck_ui.SetFooterHelp(false)
+		return nil
+	} else if event.Key() == tcell.KeyBackspace || event.Key() == tcell.KeyBackspace2 {
+		if len(linkHintInput) > 0 {
+			linkHintInput = linkHintInput[:len(linkHintInput)-1]
+		}
+	} else if event.Rune() >= 48 && event.Rune() <= 57 {
+		linkHintInput = linkHintInput + string(event.Rune())
+	} else if event.Key() == tcell.KeyEnter || event.Rune() == 121 {
+		// ENTER -> open link, y -> copy link
+		index, err := strconv.Atoi(linkHintInput)
+		if err != nil || index < 1 || index > len(linkHints) {
+			deck_ui.FooterBar.SetText(fmt.Sprintf("Not a valid link number: %s", linkHintInput))
+			linkHintInput = ""
+			return nil
+		}
+		link := linkHints[index-1]
+		exitLinkHints()
+		if event.Key() == tcell.KeyEnter {
+			err = utils.OpenUrl(link, configuration.Opener)
+			if err != nil {
+				deck_ui.FooterBar.SetText(fmt.Sprintf("Error opening link: %s", err.Error()))
+				return nil
+			}
+			deck_ui.FooterBar.SetText(fmt.Sprintf("Opened %s", tview.Escape(link)))
+		} else {
+			err = utils.CopyToClipboard(link)
+			if err != nil {
+				deck_ui.FooterBar.SetText(fmt.Sprintf("Error copying link: %s", err.Error()))
+				return nil
+			}
+			deck_ui.FooterBar.SetText(fmt.Sprintf("Copied %s", tview.Escape(link)))
+		}
+		return nil
+	} else {
+		return event
+	}
+	deck_ui.FooterBar.SetText(fmt.Sprintf("Link number: %s", deck_theme.Key(linkHintInput)))
+	return nil
+}
+
+func exitLinkHints() {
+	linkHintMode = false
+	linkHints = nil
+	linkHintInput = ""
+	renderDescription()
+}
+
+func moveCheckListSelection(step int) {
+	_, total, err := deck_markdown.CountCheckList(utils.FormatDescription(EditableCard.Description))
+	if err != nil {
+		deck_ui.FooterBar.SetText(err.Error())
+		return
+	}
+	if selectedCheckListItem < 0 && step < 0 {
+		selectedCheckListItem = total - 1
+	} else if selectedCheckListItem < 0 {
+		selectedCheckListItem = 0
+	} else {
+		selectedCheckListItem = (selectedCheckListItem + step + total) % total
+	}
+	DetailText.Highlight(deck_markdown.CheckListRegion(selectedCheckListItem))
+	DetailText.ScrollToHighlight()
+}
+
+func toggleCheckListItem() {
+	if selectedCheckListItem < 0 {
+		return
+	}
+	description, err := deck_markdown.ToggleCheckListItem(utils.FormatDescription(EditableCard.Description), selectedCheckListItem)
+	if err != nil {
+		deck_ui.FooterBar.SetText(err.Error())
+		return
+	}
+	previous := EditableCard
+	EditableCard.Description = description
+	go editCard(previous, EditableCard)
+	CardsMap[EditableCard.Id] = EditableCard
+	updateStacks()
+	BuildStacks()
+	renderDescription()
+	app.SetFocus(DetailText)
+}
+
+func moveFocusedCard(key tcell.Key) {
+	todoList, ok := app.GetFocus().(*tview.List)
+	if !ok || todoList.GetItemCount() == 0 {
+		return
+	}
+	moveStackModal(todoList, key)
+}
+
+func moveStackModal(todoList *tview.List, key tcell.Key) {
+	currentIndex := todoList.GetCurrentItem()
+	currentText, _ := todoList.GetItemText(currentIndex)
+	cardId := utils.GetId(currentText)
+
+	primitive := app.GetFocus()
+
+	Modal.ClearButtons()
+	moveText := "next"
+	if key == tcell.KeyLeft {
+		moveText = "prev"
+	}
+	Modal.SetText(fmt.Sprintf("Are you sure to move card #%d to %s stack??", cardId, moveText))
+	deck_theme.StyleModal(Modal)
+
+	Modal.AddButtons([]string{"Yes", "No"})
+
+	Modal.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyEsc {
+			deck_ui.MainFlex.RemoveItem(Modal)
+			app.SetFocus(todoList)
+		}
+		if event.Key() == tcell.KeyRight || event.Key() == tcell.KeyLeft || event.Key() == tcell.KeyEnter {
+			return event
+		}
+		return nil
+	})
+
+	Modal.SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+		if buttonLabel == "Yes" {
+			moveCardToStack(todoList, &primitive, key)
+			deck_ui.MainFlex.RemoveItem(Modal)
+		} else if buttonLabel == "No" {
+			deck_ui.MainFlex.RemoveItem(Modal)
+			app.SetFocus(todoList)
+		}
+	})
+	deck_ui.MainFlex.AddItem(Modal, 0, 0, false)
+	app.SetFocus(Modal)
+}
+
+func DeleteStack(index int) {
+	stack := deck_stack.Stacks[index]
+	go func() {
+		comments := make(map[int][]deck_structs.Comment)
+		commentsErrs := make([]string, 0)
+		for _, card := range stack.Cards {
+			var commentsErr error
+			comments[card.Id], commentsErr = getAllComments(card.Id)
+			if commentsErr != nil {
+				commentsErrs = append(commentsErrs, commentsErr.Error())
+			}
+		}
+		_, err := deck_http.DeleteStack(currentBoard.Id, stack.Id, configuration)
+		app.QueueUpdateDraw(func() {
+			if err != nil {
+				deck_ui.FooterBar.SetText(fmt.Sprintf("Error deleting stack: %s", err.Error()))
+				return
+			}
+			recordDeleteStack(stack, comments)
+			if len(commentsErrs) > 0 {
+				deck_ui.FooterBar.SetText(fmt.Sprintf("Error %s", strings.Join(commentsErrs, ", ")))
+			}
+		})
+	}()
+	for _, card := range stack.Cards {
+		delete(CardsMap, card.Id)
+	}
+	deck_stack.Stacks = append(deck_stack.Stacks[:index], deck_stack.Stacks[index+1:]...)
+}
+
+func recordAddCard(card deck_structs.Card) {
+	deck_undo.Record(deck_undo.Operation{
+		Title: fmt.Sprintf("Add card #%d %s", card.Id, card.Title),
+		Undo: func() error {
+			return removeCard(card.Id)
+		},
+		Redo: func() error {
+			return restoreCard(card, nil)
+		},
+	})
+}
+
+func recordDeleteCard(card deck_structs.Card, comments []deck_structs.Comment) {
+	card.Labels = append([]deck_structs.Label{}, card.Labels...)
+	card.AssignedUsers = append([]deck_structs.AssignedUser{}, card.AssignedUsers...)
+	deck_undo.Record(deck_undo.Operation{
+		Title: fmt.Sprintf("Delete card #%d %s", card.Id, card.Title),
+		Undo: func() error {
+			return restoreCard(card, comments)
+		},
+		Redo: func() error {
+			return removeCard(card.Id)
+		},
+	})
+}
+
+func recordEditCard(previous deck_structs.Card, card deck_structs.Card) {
+	if previous.Title == card.Title && previous.Description == card.Description && previous.DueDate == card.DueDate {
+		return
+	}
+	deck_undo.Record(deck_undo.Operation{
+		Title: fmt.Sprintf("Edit card #%d %s", card.Id, card.Title),
+		Undo: func() error {
+			return setCardFields(card.Id, previous)
+		},
+		Redo: func() error {
+			return setCardFields(card.Id, card)
+		},
+	})
+}
+
+func recordMoveCard(card deck_structs.Card, fromStackId int, toStackId int) {
+	deck_undo.Record(deck_undo.Operation{
+		Title: fmt.Sprintf("Move card #%d %s from %s to %s", card.Id, card.Title, getStackTitle(fromStackId), getStackTitle(toStackId)),
+		Undo: func() error {
+			return moveCard(card.Id, fromStackId)
+		},
+		Redo: func() error {
+			return moveCard(card.Id, toStackId)
+		},
+	})
+}
+
+func recordCardLabel(card deck_structs.Card, label deck_structs.Label, assign bool) {
+	title := fmt.Sprintf("Add label %s to card #%d", label.Title, card.Id)
+	if !assign {
+		title = fmt.Sprintf("Remove label %s from card #%d", label.Title, card.Id)
+	}
+	deck_undo.Record(deck_undo.Operation{
+		Title: title,
+		Undo: func() error {
+			return setCardLabel(card.Id, label, !assign)
+		},
+		Redo: func() error {
+			return setCardLabel(card.Id, label, assign)
+		},
+	})
+}
+
+func recordCardUser(card deck_structs.Card, user deck_structs.Owner, assign bool) {
+	title := fmt.Sprintf("Assign %s to card #%d", user.DisplayName, card.Id)
+	if !assign {
+		title = fmt.Sprintf("Unassign %s from card #%d", user.DisplayName, card.Id)
+	}
+	deck_undo.Record(deck_undo.Operation{
+		Title: title,
+		Undo: func() error {
+			return setCardUser(card.Id, user, !assign)
+		},
+		Redo: func() error {
+			return setCardUser(card.Id, user, assign)
+		},
+	})
+}
+
+func recordMoveLane(card deck_structs.Card, from lane, to lane) {
+	deck_undo.Record(deck_undo.Operation{
+		Title: fmt.Sprintf("Move card #%d %s from %s to %s", card.Id, card.Title, from.title, to.title),
+		Undo: func() error {
+			return setCardLane(card.Id, to, from)
+		},
+		Redo: func() error {
+			return setCardLane(card.Id, from, to)
+		},
+	})
+}
+
+func recordDeleteStack(stack deck_structs.Stack, comments map[int][]deck_structs.Comment) {
+	deck_undo.Record(deck_undo.Operation{
+		Title: fmt.Sprintf("Delete stack %s", stack.Title),
+		Undo: func() error {
+			return restoreStack(stack, comments)
+		},
+		Redo: func() error {
+			cardIds := make([]int, 0)
+			app.QueueUpdate(func() {
+				stackId := deck_stack.ResolveStackId(stack.Id)
+				for _, s := range deck_stack.Stacks {
+					if s.Id == stackId {
+						for _, card := range s.Cards {
+							cardIds = append(cardIds, card.Id)
+						}
+					}
+				}
+			})
+			err := deck_stack.RemoveStack(currentBoard.Id, stack.Id)
+			if err != nil {
+				return err
+			}
+			app.QueueUpdate(func() {
+				for _, cardId := range cardIds {
+					delete(CardsMap, cardId)
+				}
+			})
+			return nil
+		},
+	})
+}
+
+func resolveCardId(cardId int) int {
+	for {
+		restoredId, ok := restoredCards[cardId]
+		if !ok {
+			return cardId
+		}
+		cardId = restoredId
+	}
+}
+
+func getCard(cardId int) (deck_structs.Card, error) {
+	var card deck_structs.Card
+	var ok bool
+	app.QueueUpdate(func() {
+		card, ok = CardsMap[resolveCardId(cardId)]
+	})
+	if !ok {
+		return card, fmt.Errorf("card #%d not found in board %s", cardId, currentBoard.Title)
+	}
+	return card, nil
+}
+
+func getStackTitle(stackId int) string {
+	for _, s := range deck_stack.Stacks {
+		if s.Id == stackId {
+			return s.Title
+		}
+	}
+	return fmt.Sprintf("#%d", stackId)
+}
+
+func moveCard(cardId int, stackId int) error {
+	card, err := getCard(cardId)
+	if err != nil {
+		return err
+	}
+	app.QueueUpdate(func() {
+		stackId = deck_stack.ResolveStackId(stackId)
+	})
+	_, err = deck_http.UpdateCard(currentBoard.Id, card.StackId, card.Id, getMoveJson(card, stackId), configuration)
+	if err != nil {
+		return err
+	}
+	app.QueueUpdate(func() {
+		removeStackCard(card.Id)
+		card.StackId = stackId
+		CardsMap[card.Id] = card
+		addStackCard(card)
+	})
+	return nil
+}
+
+func removeCard(cardId int) error {
+	card, err := getCard(cardId)
+	if err != nil {
+		return err
+	}
+	_, err = deck_http.DeleteCard(currentBoard.Id, card.StackId, card.Id, configuration)
+	if err != nil {
+		return err
+	}
+	app.QueueUpdate(func() {
+		removeStackCard(card.Id)
+		delete(CardsMap, card.Id)
+	})
+	return nil
+}
+
+func restoreCard(card deck_structs.Card, comments []deck_structs.Comment) error {
+	var stackId int
+	app.QueueUpdate(func() {
+		stackId = deck_stack.ResolveStackId(card.StackId)
+	})
+	dueDateFormat := ""
+	if len(card.DueDate) > 0 {
+		dueDateFormat = fmt.Sprintf(`, "duedate": "%s"`, card.DueDate)
+	}
+	jsonBody := fmt.Sprintf(`{"title":"%s", "description": "%s", "type": "plain", "order": %d%s}`,
+		utils.CleanText(card.Title), utils.CleanText(card.Description), card.Order, dueDateFormat)
+	newCard, err := deck_http.AddCard(currentBoard.Id, stackId, jsonBody, configuration)
+	if err != nil {
+		return err
+	}
+	app.QueueUpdate(func() {
+		restoredCards[card.Id] = newCard.Id
+	})
+
+	errs := make([]string, 0)
+	newCard.Labels = make([]deck_structs.Label, 0)
+	for _, label := range card.Labels {
+		_, err = deck_http.AssignLabel(currentBoard.Id, stackId, newCard.Id, fmt.Sprintf(`{"labelId": %d}`, label.Id), configuration)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("label %s: %s", label.Title, err.Error()))
+			continue
+		}
+		newCard.Labels = append(newCard.Labels, label)
+	}
+	newCard.AssignedUsers = make([]deck_structs.AssignedUser, 0)
+	for _, user := range card.AssignedUsers {
+		_, err = deck_http.AssignUser(currentBoard.Id, stackId, newCard.Id, fmt.Sprintf(`{"userId": "%s"}`, user.Participant.Uid), configuration)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("user %s: %s", user.Participant.DisplayName, err.Error()))
+			continue
+		}
+		user.CardId = newCard.Id
+		newCard.AssignedUsers = append(newCard.AssignedUsers, user)
+	}
+	if len(comments) > 0 {
+		jsonBody = fmt.Sprintf(`{"message":"%s" }`, utils.CleanText(getCommentsNote(card.Id, comments)))
+		_, err = deck_http.AddComment(newCard.Id, jsonBody, configuration)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("comments: %s", err.Error()))
+		}
+	}
+
+	app.QueueUpdate(func() {
+		CardsMap[newCard.Id] = newCard
+		addStackCard(newCard)
+	})
+	if len(errs) > 0 {
+		return deck_undo.Applied(errors.New(strings.Join(errs, ", ")))
+	}
+	return nil
+}
+
+func restoreStack(stack deck_structs.Stack, comments map[int][]deck_structs.Comment) error {
+	_, err := deck_stack.RestoreStack(currentBoard.Id, stack)
+	if err != nil {
+		return err
+	}
+	errs := make([]string, 0)
+	for _, card := range stack.Cards {
+		err = restoreCard(card, comments[card.Id])
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("card #%d: %s", card.Id, err.Error()))
+		}
+	}
+	if len(errs) > 0 {
+		return deck_undo.Applied(errors.New(strings.Join(errs, ", ")))
+	}
+	return nil
+}
+
+func setCardFields(cardId int, fields deck_structs.Card) error {
+	card, err := getCard(cardId)
+	if err != nil {
+		return err
+	}
+	card.Title = fields.Title
+	card.Description = fields.Description
+	card.DueDate = fields.DueDate
+	_, err = deck_http.UpdateCard(currentBoard.Id, card.StackId, card.Id, getCardJson(card), configuration)
+	if err != nil {
+		return err
+	}
+	app.QueueUpdate(func() {
+		updateCardState(card)
+	})
+	return nil
+}
+
+func setCardLabel(cardId int, label deck_structs.Label, assign bool) error {
+	card, err := getCard(cardId)
+	if err != nil {
+		return err
+	}
+	jsonBody := fmt.Sprintf(`{"labelId": %d}`, label.Id)
+	labels := make([]deck_structs.Label, 0)
+	for _, l := range card.Labels {
+		if l.Id != label.Id {
+			labels = append(labels, l)
+		}
+	}
+	if assign {
+		_, err = deck_http.AssignLabel(currentBoard.Id, card.StackId, card.Id, jsonBody, configuration)
+		labels = append(labels, label)
+	} else {
+		_, err = deck_http.DeleteLabel(currentBoard.Id, card.StackId, card.Id, jsonBody, configuration)
+	}
+	if err != nil {
+		return err
+	}
+	card.Labels = labels
+	app.QueueUpdate(func() {
+		updateCardState(card)
+	})
+	return nil
+}
+
+func setCardUser(cardId int, user deck_structs.Owner, assign bool) error {
+	card, err := getCard(cardId)
+	if err != nil {
+		return err
+	}
+	jsonBody := fmt.Sprintf(`{"userId": "%s"}`, user.Uid)
+	users := make([]deck_structs.AssignedUser, 0)
+	for _, u := range card.AssignedUsers {
+		if u.Participant.Uid != user.Uid {
+			users = append(users, u)
+		}
+	}
+	if assign {
+		_, err = deck_http.AssignUser(currentBoard.Id, card.StackId, card.Id, jsonBody, configuration)
+		users = append(users, deck_structs.AssignedUser{CardId: card.Id, Participant: user})
+	} else {
+		_, err = deck_http.DeleteUser(currentBoard.Id, card.StackId, card.Id, jsonBody, configuration)
+	}
+	if err != nil {
+		return err
+	}
+	card.AssignedUsers = users
+	app.QueueUpdate(func() {
+		updateCardState(card)
+	})
+	return nil
+}
+
+func updateCardState(card deck_structs.Card) {
+	CardsMap[card.Id] = card
+	if EditableCard.Id == card.Id {
+		EditableCard = card
+	}
+	updateStackCard(card)
+}
+
+func getAllComments(cardId int) ([]deck_structs.Comment, error) {
+	comments := make([]deck_structs.Comment, 0)
+	for {
+		page, err := deck_http.GetComments(cardId, allCommentsPageSize, len(comments), configuration)
+		if err != nil {
+			return comments, fmt.Errorf("getting comments from card #%d: %s", cardId, err.Error())
+		}
+		comments = append(comments, page...)
+		if len(page) < allCommentsPageSize {
+			return comments, nil
+		}
+	}
+}
+
+func getCommentsNote(cardId int, comments []deck_structs.Comment) string {
+	note := fmt.Sprintf("Restored from deleted card #%d, original comments:\n", cardId)
+	for i := len(comments) - 1; i >= 0; i-- {
+		c := comments[i]
+		note = note + fmt.Sprintf("\n%s (%s): %s\n", c.ActorDisplayName, c.CreationDateTime, c.Message)
+	}
+	runes := []rune(note)
+	if len(runes) > maxCommentLength {
+		note = string(runes[:maxCommentLength-1]) + "…"
+	}
+	return note
+}
+
+func toggleMark() {
+	todoList, ok := app.GetFocus().(*tview.List)
+	if !ok || todoList.GetItemCount() == 0 {
+		return
+	}
+	index := todoList.GetCurrentItem()
+	name, secondName := todoList.GetItemText(index)
+	card, ok := CardsMap[utils.GetId(name)]
+	if !ok {
+		return
+	}
+	if markedCards[card.Id] {
+		delete(markedCards, card.Id)
+	} else {
+		markedCards[card.Id] = true
+	}
+	todoList.SetItemText(index, getCardItemText(card), secondName)
+	if index < todoList.GetItemCount()-1 {
+		todoList.SetCurrentItem(index + 1)
+	}
+	deck_ui.FooterBar.SetText(fmt.Sprintf("%d cards marked, press %s for bulk actions",
+		len(markedCards), deck_theme.Key(deck_keys.FirstKey("main.bulk"))))
+}
+
+func GetMarkedCards() []deck_structs.Card {
+	cards := make([]deck_structs.Card, 0)
+	for _, s := range deck_stack.Stacks {
+		for _, c := range s.Cards {
+			if markedCards[c.Id] {
+				cards = append(cards, CardsMap[c.Id])
+			}
+		}
+	}
+	return cards
+}
+
+func ClearMarks() {
+	markedCards = make(map[int]bool)
+	rebuildStacks()
+}
+
+func BulkMove(stackIndex int) {
+	stack := deck_stack.Stacks[stackIndex]
+	cards := make([]deck_structs.Card, 0)
+	for _, card := range GetMarkedCards() {
+		if card.StackId != stack.Id {
+			cards = append(cards, card)
+		}
+	}
+	runBulk(fmt.Sprintf("Move %d cards to %s", len(cards), stack.Title), cards,
+		func(i int, card deck_structs.Card) error {
+			_, err := deck_http.UpdateCard(currentBoard.Id, card.StackId, card.Id, getMoveJson(card, stack.Id), configuration)
+			return err
+		},
+		func(card deck_structs.Card) {
+			removeStackCard(card.Id)
+			card.StackId = stack.Id
+			CardsMap[card.Id] = card
+			addStackCard(card)
+		},
+		func(cards []deck_structs.Card) deck_undo.Operation {
+			return deck_undo.Operation{
+				Undo: func() error {
+					return applyAll(cards, func(card deck_structs.Card) error {
+						return moveCard(card.Id, card.StackId)
+					})
+				},
+				Redo: func() error {
+					return applyAll(cards, func(card deck_structs.Card) error {
+						return moveCard(card.Id, stack.Id)
+					})
+				},
+			}
+		})
+}
+
+func BulkLabel(label deck_structs.Label, assign bool) {
+	cards := make([]deck_structs.Card, 0)
+	for _, card := range GetMarkedCards() {
+		if hasLabel(card, label) != assign {
+			cards = append(cards, card)
+		}
+	}
+	title := fmt.Sprintf("Add label %s to %d cards", label.Title, len(cards))
+	if !assign {
+		title = fmt.Sprintf("Remove label %s from %d cards", label.Title, len(cards))
+	}
+	jsonBody := fmt.Sprintf(`{"labelId": %d}`, label.Id)
+	runBulk(title, cards,
+		func(i int, card deck_structs.Card) error {
+			var err error
+			if assign {
+				_, err = deck_http.AssignLabel(currentBoard.Id, card.StackId, card.Id, jsonBody, configuration)
+			} else {
+				_, err = deck_http.DeleteLabel(currentBoard.Id, card.StackId, card.Id, jsonBody, configuration)
+			}
+			return err
+		},
+		func(card deck_structs.Card) {
+			updateCardState(withLabel(card, label, assign))
+		},
+		func(cards []deck_structs.Card) deck_undo.Operation {
+			return deck_undo.Operation{
+				Undo: func() error {
+					return applyAll(cards, func(card deck_structs.Card) error {
+						return setCardLabel(card.Id, label, !assign)
+					})
+				},
+				Redo: func() error {
+					return applyAll(cards, func(card deck_structs.Card) error {
+						return setCardLabel(card.Id, label, assign)
+					})
+				},
+			}
+		})
+}
+
+func BulkUser(user deck_structs.Owner, assign bool) {
+	cards := make([]deck_structs.Card, 0)
+	for _, card := range GetMarkedCards() {
+		if hasUser(card, user) != assign {
+			cards = append(cards, card)
+		}
+	}
+	title := fmt.Sprintf("Assign %s to %d cards", user.DisplayName, len(cards))
+	if !assign {
+		title = fmt.Sprintf("Unassign %s from %d cards", user.DisplayName, len(cards))
+	}
+	jsonBody := fmt.Sprintf(`{"userId": "%s"}`, user.Uid)
+	runBulk(title, cards,
+		func(i int, card deck_structs.Card) error {
+			var err error
+			if assign {
+				_, err = deck_http.AssignUser(currentBoard.Id, card.StackId, card.Id, jsonBody, configuration)
+			} else {
+				_, err = deck_http.DeleteUser(currentBoard.Id, card.StackId, card.Id, jsonBody, configuration)
+			}
+			return err
+		},
+		func(card deck_structs.Card) {
+			updateCardState(withUser(card, user, assign))
+		},
+		func(cards []deck_structs.Card) deck_undo.Operation {
+			return deck_undo.Operation{
+				Undo: func() error {
+					return applyAll(cards, func(card deck_structs.Card) error {
+						return setCardUser(card.Id, user, !assign)
+					})
+				},
+				Redo: func() error {
+					return applyAll(cards, func(card deck_structs.Card) error {
+						return setCardUser(card.Id, user, assign)
+					})
+				},
+			}
+		})
+}
+
+func BulkDueDate(dueDate string) {
+	cards := GetMarkedCards()
+	title := fmt.Sprintf("Clear due date of %d cards", len(cards))
+	if len(dueDate) > 0 {
+		parse, _ := time.Parse("2006-01-02T15:04:05+00:00", dueDate)
+		title = fmt.Sprintf("Set due date of %d cards to %s", len(cards), parse.Format("02/01/2006 15:04"))
+	}
+	runBulk(title, cards,
+		func(i int, card deck_structs.Card) error {
+			card.DueDate = dueDate
+			updated, err := deck_http.UpdateCard(currentBoard.Id, card.StackId, card.Id, getCardJson(card), configuration)
+			if err != nil {
+				return err
+			}
+			if !sameDueDate(updated.DueDate, dueDate) {
+				return fmt.Errorf("due date not updated, server has %s", getDueDateText(updated.DueDate))
+			}
+			return nil
+		},
+		func(card deck_structs.Card) {
+			card.DueDate = dueDate
+			updateCardState(card)
+		},
+		func(cards []deck_structs.Card) deck_undo.Operation {
+			return deck_undo.Operation{
+				Undo: func() error {
+					return applyAll(cards, func(card deck_structs.Card) error {
+						return setCardDueDate(card.Id, card.DueDate)
+					})
+				},
+				Redo: func() error {
+					return applyAll(cards, func(card deck_structs.Card) error {
+						return setCardDueDate(card.Id, dueDate)
+					})
+				},
+			}
+		})
+}
+
+func sameDueDate(a string, b string) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return len(a) == len(b)
+	}
+	dateA, errA := time.Parse(time.RFC3339, a)
+	dateB, errB := time.Parse(time.RFC3339, b)
+	if errA != nil || errB != nil {
+		return a == b
+	}
+	return dateA.Equal(dateB)
+}
+
+func getDueDateText(dueDate string) string {
+	if len(dueDate) == 0 {
+		return "no due date"
+	}
+	parse, err := time.Parse(time.RFC3339, dueDate)
+	if err != nil {
+		return dueDate
+	}
+	return parse.Format("02/01/2006 15:04")
+}
+
+func BulkArchive() {
+	cards := GetMarkedCards()
+	runBulk(fmt.Sprintf("Archive %d cards", len(cards)), cards,
+		func(i int, card deck_structs.Card) error {
+			_, err := deck_http.ArchiveCard(currentBoard.Id, card.StackId, card.Id, configuration)
+			return err
+		},
+		func(card deck_structs.Card) {
+			removeStackCard(card.Id)
+			delete(CardsMap, card.Id)
+			delete(markedCards, card.Id)
+		},
+		func(cards []deck_structs.Card) deck_undo.Operation {
+			return deck_undo.Operation{
+				Undo: func() error {
+					return applyAll(cards, unarchiveCard)
+				},
+				Redo: func() error {
+					return applyAll(cards, func(card deck_structs.Card) error {
+						return archiveCard(card.Id)
+					})
+				},
+			}
+		})
+}
+
+func BulkDelete() {
+	cards := GetMarkedCards()
+	if len(cards) == 0 {
+		deck_ui.FooterBar.SetText("No cards marked")
+		return
+	}
+	focus := app.GetFocus()
+
+	Modal.ClearButtons()
+	Modal.SetText(fmt.Sprintf("Are you sure to delete %d cards?", len(cards)))
+	deck_theme.StyleModal(Modal)
+
+	Modal.AddButtons([]string{"Yes", "No"})
+
+	Modal.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyEsc {
+			deck_ui.MainFlex.RemoveItem(Modal)
+			app.SetFocus(focus)
+		}
+		if event.Key() == tcell.KeyRight || event.Key() == tcell.KeyLeft || event.Key() == tcell.KeyEnter {
+			return event
+		}
+		return nil
+	})
+
+	Modal.SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+		deck_ui.MainFlex.RemoveItem(Modal)
+		app.SetFocus(focus)
+		if buttonLabel != "Yes" {
+			return
+		}
+		comments := make([][]deck_structs.Comment, len(cards))
+		runBulk(fmt.Sprintf("Delete %d cards", len(cards)), cards,
+			func(i int, card deck_structs.Card) error {
+				var commentsErr error
+				comments[i], commentsErr = getAllComments(card.Id)
+				_, err := deck_http.DeleteCard(currentBoard.Id, card.StackId, card.Id, configuration)
+				if err != nil {
+					return err
+				}
+				return deck_undo.Applied(commentsErr)
+			},
+			func(card deck_structs.Card) {
+				removeStackCard(card.Id)
+				delete(CardsMap, card.Id)
+				delete(markedCards, card.Id)
+			},
+			func(deleted []deck_structs.Card) deck_undo.Operation {
+				deletedComments := make(map[int][]deck_structs.Comment)
+				for i, card := range cards {
+					deletedComments[card.Id] = comments[i]
+				}
+				return deck_undo.Operation{
+					Undo: func() error {
+						return applyAll(deleted, func(card deck_structs.Card) error {
+							return restoreCard(card, deletedComments[card.Id])
+						})
+					},
+					Redo: func() error {
+						return applyAll(deleted, func(card deck_structs.Card) error {
+							return removeCard(card.Id)
+						})
+					},
+				}
+			})
+	})
+
+	deck_ui.MainFlex.AddItem(Modal, 0, 0, false)
+	app.SetFocus(Modal)
+}
+
+func runBulk(title string, cards []deck_structs.Card, request func(i int, card deck_structs.Card) error,
+	update func(card deck_structs.Card), operation func(cards []deck_structs.Card) deck_undo.Operation) {
+	if len(cards) == 0 {
+		deck_ui.FooterBar.SetText("No marked cards to update")
+		return
+	}
+	deck_ui.FooterBar.SetText(fmt.Sprintf("%s...", title))
+
+	go func() {
+		errs := make([]error, len(cards))
+		semaphore := make(chan bool, bulkConcurrency)
+		var wg sync.WaitGroup
+		for i, card := range cards {
+			wg.Add(1)
+			semaphore <- true
+			go func(i int, card deck_structs.Card) {
+				defer wg.Done()
+				errs[i] = request(i, card)
+				<-semaphore
+			}(i, card)
+		}
+		wg.Wait()
+
+		app.QueueUpdateDraw(func() {
+			succeeded := make([]deck_structs.Card, 0)
+			failed := make([]string, 0)
+			warnings := make([]string, 0)
+			for i, card := range cards {
+				if errs[i] != nil && !deck_undo.IsApplied(errs[i]) {
+					failed = append(failed, fmt.Sprintf("#%d %s", card.Id, errs[i].Error()))
+					continue
+				}
+				if errs[i] != nil {
+					warnings = append(warnings, fmt.Sprintf("#%d %s", card.Id, errs[i].Error()))
+				}
+				current, ok := CardsMap[card.Id]
+				if !ok {
+					current = card
+				}
+				update(current)
+				succeeded = append(succeeded, card)
+			}
+			if len(succeeded) > 0 {
+				undoOperation := operation(succeeded)
+				undoOperation.Title = title
+				deck_undo.Record(undoOperation)
+			}
+			rebuildStacks()
+
+			report := fmt.Sprintf("%s: %d done, %d failed", title, len(succeeded), len(failed))
+			if len(failed) > 0 {
+				report = fmt.Sprintf("%s (%s)", report, tview.Escape(strings.Join(failed, ", ")))
+			}
+			if len(warnings) > 0 {
+				report = fmt.Sprintf("%s, warnings: %s", report, tview.Escape(strings.Join(warnings, ", ")))
+			}
+			deck_ui.FooterBar.SetText(report)
+		})
+	}()
+}
+
+func applyAll(cards []deck_structs.Card, apply func(card deck_structs.Card) error) error {
+	errs := make([]string, 0)
+	for _, card := range cards {
+		err := apply(card)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("#%d %s", card.Id, err.Error()))
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	err := errors.New(strings.Join(errs, ", "))
+	if len(errs) == len(cards) {
+		return err
+	}
+	return deck_undo.Applied(err)
+}
+
+func hasLabel(card deck_structs.Card, label deck_structs.Label) bool {
+	for _, l := range card.Labels {
+		if l.Id == label.Id {
+			return true
+		}
+	}
+	return false
+}
+
+func hasUser(card deck_structs.Card, user deck_structs.Owner) bool {
+	for _, u := range card.AssignedUsers {
+		if u.Participant.Uid == user.Uid {
+			return true
+		}
+	}
+	return false
+}
+
+func withLabel(card deck_structs.Card, label deck_structs.Label, assign bool) deck_structs.Card {
+	labels := make([]deck_structs.Label, 0)
+	for _, l := range card.Labels {
+		if l.Id != label.Id {
+			labels = append(labels, l)
+		}
+	}
+	if assign {
+		labels = append(labels, label)
+	}
+	card.Labels = labels
+	return card
+}
+
+func withUser(card deck_structs.Card, user deck_structs.Owner, assign bool) deck_structs.Card {
+	users := make([]deck_structs.AssignedUser, 0)
+	for _, u := range card.AssignedUsers {
+		if u.Participant.Uid != user.Uid {
+			users = append(users, u)
+		}
+	}
+	if assign {
+		users = append(users, deck_structs.AssignedUser{CardId: card.Id, Participant: user})
+	}
+	card.AssignedUsers = users
+	return card
+}
+
+func setCardDueDate(cardId int, dueDate string) error {
+	card, err := getCard(cardId)
+	if err != nil {
+		return err
+	}
+	card.DueDate = dueDate
+	_, err = deck_http.UpdateCard(currentBoard.Id, card.StackId, card.Id, getCardJson(card), configuration)
+	if err != nil {
+		return err
+	}
+	app.QueueUpdate(func() {
+		updateCardState(card)
+	})
+	return nil
+}
+
+func archiveCard(cardId int) error {
+	card, err := getCard(cardId)
+	if err != nil {
+		return err
+	}
+	_, err = deck_http.ArchiveCard(currentBoard.Id, card.StackId, card.Id, configuration)
+	if err != nil {
+		return err
+	}
+	app.QueueUpdate(func() {
+		removeStackCard(card.Id)
+		delete(CardsMap, card.Id)
+	})
+	return nil
+}
+
+func unarchiveCard(card deck_structs.Card) error {
+	app.QueueUpdate(func() {
+		card.Id = resolveCardId(card.Id)
+		card.StackId = deck_stack.ResolveStackId(card.StackId)
+	})
+	_, err := deck_http.UnarchiveCard(currentBoard.Id, card.StackId, card.Id, configuration)
+	if err != nil {
+		return err
+	}
+	app.QueueUpdate(func() {
+		CardsMap[card.Id] = card
+		addStackCard(card)
+	})
+	return nil
+}
+
+func BuildBulkDueDateForm() *tview.Form {
+	form := tview.NewForm()
+	form.SetTitle(fmt.Sprintf(" Due Date of %d Cards ", len(GetMarkedCards())))
+	form.SetBorder(true)
+	deck_theme.StyleForm(form)
+	form.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyEsc {
+			deck_ui.BuildFullFlex(deck_ui.MainFlex, nil)
+			return nil
+		}
+		return event
+	})
+	dueDate := ""
+	form.AddInputField("Due Date", "", 18, func(textToCheck string, lastChar rune) bool {
+		if (lastChar >= rune(47) && lastChar <= rune(58)) || lastChar == rune(32) {
+			return true
+		}
+		return false
+	}, func(date string) {
+		dueDate = date
+	})
+	form.AddButton("Save", func() {
+		if len(dueDate) > 0 {
+			parse, err := time.Parse("02/01/2006 15:04", dueDate)
+			if err != nil {
+				deck_ui.FooterBar.SetText(fmt.Sprintf("Not a valid date, format must be dd/MM/YYYY HH:mm: %s", err.Error()))
+				return
+			}
+			dueDate = parse.Format("2006-01-02T15:04:05+00:00")
+		}
+		deck_ui.BuildFullFlex(deck_ui.MainFlex, nil)
+		BulkDueDate(dueDate)
+	})
+	return form
 }
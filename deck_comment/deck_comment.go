@@ -4,12 +4,14 @@ import (
 	"fmt"
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
+	"regexp"
 	"sort"
 	"strings"
 	"time"
 	"tui-deck/deck_http"
 	"tui-deck/deck_markdown"
 	"tui-deck/deck_structs"
+	"tui-deck/deck_theme"
 	"tui-deck/deck_ui"
 	"tui-deck/utils"
 )
@@ -18,38 +20,167 @@ var Comments []deck_structs.Comment
 
 var CommentsMap = make(map[int]deck_structs.Comment)
 var CommentTree *tview.TreeView
+var CommentText *tview.TextView
+var CommentFlex *tview.Flex
 var app *tview.Application
 var Modal *tview.Modal
 var configuration utils.Configuration
 
+var boardUsers []deck_structs.Owner
+var currentCardId int
+
 var CommentTreeStructMap = make(map[int]*CommentStruct)
 
+const LoadMoreReference = -1
+const commentsPageSize = 20
+const autocompleteDelay = 250 * time.Millisecond
+
+var commentsCache = make(map[int]*commentPage)
+
+type commentPage struct {
+	Comments []deck_structs.Comment
+	HasMore  bool
+}
+
 type CommentStruct struct {
 	Comment deck_structs.Comment
 	Parent  *CommentStruct
 	Replies []*CommentStruct
 }
 
+type mentionCompleter struct {
+	area       *tview.TextArea
+	view       *tview.TextView
+	start      int
+	end        int
+	prefix     string
+	candidates []deck_structs.Owner
+	selected   int
+	timer      *time.Timer
+	cache      map[string][]deck_structs.Autocomplete
+}
+
 func Init(application *tview.Application, conf utils.Configuration) {
 	app = application
 	configuration = conf
 
 	CommentTree = tview.NewTreeView()
 	CommentTree.SetBorder(true)
-	CommentTree.SetBorderColor(utils.GetColor(configuration.Color))
+	CommentTree.SetSelectedFunc(func(node *tview.TreeNode) {
+		if node.GetReference() == LoadMoreReference {
+			LoadMoreComments()
+			CreateCommentsTree()
+		}
+	})
+	CommentTree.SetChangedFunc(func(node *tview.TreeNode) {
+		showComment(node)
+	})
+
+	CommentText = tview.NewTextView()
+	CommentText.SetBorder(true)
+	CommentText.SetDynamicColors(true)
+	CommentText.SetWordWrap(true)
+	CommentText.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyTab || event.Key() == tcell.KeyEscape {
+			// TAB, ESC -> back to comments tree
+			app.SetFocus(CommentTree)
+			return nil
+		}
+		return event
+	})
+
+	CommentFlex = tview.NewFlex()
+	CommentFlex.SetDirection(tview.FlexColumn)
+	CommentFlex.AddItem(CommentTree, 0, 1, true)
+	CommentFlex.AddItem(CommentText, 0, 2, false)
 
 	Modal = tview.NewModal()
+
+	applyTheme()
+	deck_theme.OnChange(applyTheme)
 }
 
-func GetComments(cardId int) {
+func applyTheme() {
+	deck_theme.StyleBox(CommentTree.Box)
+	CommentTree.SetGraphicsColor(deck_theme.Accent())
+	deck_theme.StyleBox(CommentText.Box)
+	CommentText.SetTextColor(deck_theme.GetColor(deck_theme.Current.Text))
+	deck_theme.StyleBox(CommentFlex.Box)
+	deck_theme.StyleModal(Modal)
+}
 
-	CommentTreeStructMap = make(map[int]*CommentStruct)
+func SetBoardUsers(users []deck_structs.Owner) {
+	boardUsers = users
+}
 
-	var err error
-	Comments, err = deck_http.GetComments(cardId, configuration)
+func GetComments(cardId int) {
+	currentCardId = cardId
+
+	page, ok := commentsCache[cardId]
+	if !ok {
+		page = &commentPage{}
+		err := loadNextPage(cardId, page)
+		if err != nil {
+			deck_ui.FooterBar.SetText(fmt.Sprintf("Error getting comments from card: %s", err.Error()))
+		} else {
+			commentsCache[cardId] = page
+		}
+	}
+	Comments = page.Comments
+	buildCommentStructs()
+}
+
+func LoadMoreComments() {
+	page, ok := commentsCache[currentCardId]
+	if !ok || !page.HasMore {
+		return
+	}
+	err := loadNextPage(currentCardId, page)
 	if err != nil {
 		deck_ui.FooterBar.SetText(fmt.Sprintf("Error getting comments from card: %s", err.Error()))
+		return
 	}
+	Comments = page.Comments
+	buildCommentStructs()
+}
+
+func ClearCache() {
+	commentsCache = make(map[int]*commentPage)
+}
+
+func GetSelectedCommentId() (int, bool) {
+	node := CommentTree.GetCurrentNode()
+	if node == nil {
+		return 0, false
+	}
+	commentId, ok := node.GetReference().(int)
+	if !ok || commentId == LoadMoreReference {
+		return 0, false
+	}
+	return commentId, true
+}
+
+func loadNextPage(cardId int, page *commentPage) error {
+	comments, err := deck_http.GetComments(cardId, commentsPageSize, len(page.Comments), configuration)
+	if err != nil {
+		return err
+	}
+	page.Comments = append(page.Comments, comments...)
+	page.HasMore = len(comments) == commentsPageSize
+	return nil
+}
+
+func updateCache(update func(page *commentPage)) {
+	page, ok := commentsCache[currentCardId]
+	if !ok {
+		return
+	}
+	update(page)
+	Comments = page.Comments
+}
+
+func buildCommentStructs() {
+	CommentTreeStructMap = make(map[int]*CommentStruct)
 
 	replies := make(map[int][]deck_structs.Comment)
 	for _, c := range Comments {
@@ -80,12 +211,22 @@ func GetComments(cardId int) {
 			}
 			CommentTreeStructMap[k] = cs
 		} else {
+			found := false
 			for k1, r1 := range CommentTreeStructMap {
+				if findById(r1, k) != nil {
+					found = true
+				}
 				for _, r2 := range r {
 					searchReplies(r1.Replies, k, r2)
 				}
 				CommentTreeStructMap[k1] = r1
 			}
+			if !found {
+				// the parent comment is on a page that has not been loaded yet
+				for _, r2 := range r {
+					CommentTreeStructMap[r2.Id] = &CommentStruct{Comment: r2}
+				}
+			}
 		}
 	}
 }
@@ -104,7 +245,7 @@ func searchReplies(replies []*CommentStruct, k int, r deck_structs.Comment) {
 
 func CreateCommentsTree() {
 	root := tview.NewTreeNode("COMMENTS").
-		SetColor(utils.GetColor(configuration.Color)).SetSelectable(false)
+		SetColor(deck_theme.Accent()).SetSelectable(false)
 	CommentTree.SetRoot(root).SetCurrentNode(root)
 
 	keySlice := make([]int, 0)
@@ -115,20 +256,57 @@ func CreateCommentsTree() {
 	for _, key := range keySlice {
 		l := CommentTreeStructMap[key]
 		comment := l.Comment
-		node := tview.NewTreeNode(fmt.Sprintf("[%s:-:-]#%d[-:-:-] - [%s:-:i]%s - [%s][-:-:-] - %s", configuration.Color,
-			comment.Id, configuration.Color, comment.ActorDisplayName, getCreationDate(comment),
-			deck_markdown.GetMarkDownDescription(comment.Message, configuration)))
+		node := tview.NewTreeNode(fmt.Sprintf("[%s:-:-]#%d[-:-:-] - [%s:-:i]%s - [%s][-:-:-] - %s", deck_theme.Current.Accent,
+			comment.Id, deck_theme.Current.Accent, comment.ActorDisplayName, getCreationDate(comment),
+			getSummary(comment)))
 		node.SetReference(l.Comment.Id)
 		buildTree(l.Replies, node)
 		root.AddChild(node)
 	}
+
+	if page, ok := commentsCache[currentCardId]; ok && page.HasMore {
+		node := tview.NewTreeNode("[-:-:i]load more comments...[-:-:-]")
+		node.SetReference(LoadMoreReference)
+		root.AddChild(node)
+	}
+	if len(root.GetChildren()) > 0 {
+		CommentTree.SetCurrentNode(root.GetChildren()[0])
+	}
+	showComment(CommentTree.GetCurrentNode())
+}
+
+func showComment(node *tview.TreeNode) {
+	CommentText.Clear()
+	CommentText.SetTitle("")
+	if node == nil {
+		return
+	}
+	commentId, ok := node.GetReference().(int)
+	if !ok || commentId == LoadMoreReference {
+		return
+	}
+	comment := CommentsMap[commentId]
+
+	CommentText.SetTitle(fmt.Sprintf(" #%d ", comment.Id))
+	text := fmt.Sprintf("[%s::b]%s[-:-:-] (@%s)\n[-:-:i]%s[-:-:-]\n\n", deck_theme.Current.Accent,
+		tview.Escape(comment.ActorDisplayName), tview.Escape(comment.ActorId), getCreationDate(comment))
+	if comment.ReplyTo != nil {
+		text = text + fmt.Sprintf("[-:-:i]In reply to #%d - %s:[-:-:-]\n", comment.ReplyTo.Id, tview.Escape(comment.ReplyTo.ActorDisplayName))
+		for _, line := range strings.Split(comment.ReplyTo.Message, "\n") {
+			text = text + fmt.Sprintf("│ %s\n", tview.Escape(line))
+		}
+		text = text + "\n"
+	}
+	text = text + formatMessage(comment)
+	CommentText.SetText(text)
+	CommentText.ScrollToBeginning()
 }
 
 func buildTree(replies []*CommentStruct, node *tview.TreeNode) {
 	if len(replies) > 0 {
 		for _, r := range replies {
 			node1 := tview.NewTreeNode(fmt.Sprintf("#%d - [-:-:i]%s - [%s][-:-:-] - %s", r.Comment.Id, r.Comment.ActorDisplayName,
-				getCreationDate(r.Comment), deck_markdown.GetMarkDownDescription(r.Comment.Message, configuration)))
+				getCreationDate(r.Comment), getSummary(r.Comment)))
 			node1.SetReference(r.Comment.Id)
 			node.AddChild(node1)
 			buildTree(r.Replies, node1)
@@ -136,6 +314,36 @@ func buildTree(replies []*CommentStruct, node *tview.TreeNode) {
 	}
 }
 
+func getSummary(comment deck_structs.Comment) string {
+	lines := strings.Split(strings.TrimSpace(comment.Message), "\n")
+	summary := lines[0]
+	if len(lines) > 1 {
+		summary = summary + " ..."
+	}
+	summary = tview.Escape(summary)
+	for _, m := range comment.Mentions {
+		re := getMentionRegexp(m)
+		mention := "@" + tview.Escape(m.MentionDisplayName)
+		summary = re.ReplaceAllStringFunc(summary, func(match string) string {
+			return mention + re.FindStringSubmatch(match)[1]
+		})
+	}
+	return summary
+}
+
+func formatMessage(comment deck_structs.Comment) string {
+	mentions := make(map[string]string)
+	for _, m := range comment.Mentions {
+		mentions[m.MentionId] = m.MentionDisplayName
+	}
+	return deck_markdown.GetMarkDownDescriptionWithMentions(comment.Message, configuration, mentions)
+}
+
+func getMentionRegexp(mention deck_structs.Mention) *regexp.Regexp {
+	id := regexp.QuoteMeta(mention.MentionId)
+	return regexp.MustCompile(`@(?:"` + id + `"|` + id + `)(\W|$)`)
+}
+
 func getCreationDate(comment deck_structs.Comment) string {
 	parse, _ := time.Parse("2006-01-02T15:04:05+00:00", comment.CreationDateTime)
 	return parse.Format("15:04:05 - 2006-01-02")
@@ -151,26 +359,167 @@ func BuildAddForm(c deck_structs.Comment) (*tview.Form, *deck_structs.Comment) {
 	}
 	addForm.SetTitle(title)
 	addForm.SetBorder(true)
-	addForm.SetBorderColor(utils.GetColor(configuration.Color))
-	addForm.SetButtonBackgroundColor(utils.GetColor(configuration.Color))
-	addForm.SetFieldBackgroundColor(tcell.ColorWhite)
-	addForm.SetFieldTextColor(tcell.ColorBlack)
-	addForm.SetLabelColor(utils.GetColor(configuration.Color))
+	deck_theme.StyleForm(addForm)
 	addForm.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
 		if event.Key() == tcell.KeyEsc {
-			deck_ui.BuildFullFlex(CommentTree, nil)
+			deck_ui.BuildFullFlex(CommentFlex, nil)
 			return nil
 		}
 		return event
 	})
-	addForm.AddTextArea("Message", c.Message, 60, 10, 300, func(message string) {
-		comment.Message = message
+	messageArea := tview.NewTextArea().
+		SetLabel("Message").
+		SetSize(10, 60).
+		SetMaxLength(300)
+	if c.Message != "" {
+		messageArea.SetText(c.Message, true)
+	}
+	mentionView := tview.NewTextView().
+		SetLabel("Mentions").
+		SetSize(5, 60).
+		SetDynamicColors(true).
+		SetScrollable(false)
+
+	completer := &mentionCompleter{area: messageArea, view: mentionView, start: -1,
+		cache: make(map[string][]deck_structs.Autocomplete)}
+	messageArea.SetChangedFunc(func() {
+		comment.Message = messageArea.GetText()
+		completer.update()
 	})
+	messageArea.SetMovedFunc(completer.update)
+	messageArea.SetInputCapture(completer.inputCapture)
+
+	addForm.AddFormItem(messageArea)
+	addForm.AddFormItem(mentionView)
 	return addForm, &comment
 }
 
+func (mc *mentionCompleter) update() {
+	text := mc.area.GetText()
+	_, cursor, _ := mc.area.GetSelection()
+	at := strings.LastIndex(text[:cursor], "@")
+	if at < 0 || strings.ContainsAny(text[at:cursor], " \t\n") || (at > 0 && !strings.ContainsAny(text[at-1:at], " \t\n")) {
+		mc.clear()
+		return
+	}
+	prefix := text[at+1 : cursor]
+	if mc.start == at && mc.prefix == prefix {
+		return
+	}
+	mc.start = at
+	mc.end = cursor
+	mc.prefix = prefix
+	mc.selected = 0
+	mc.candidates = filterUsers(boardUsers, prefix)
+
+	if mc.timer != nil {
+		mc.timer.Stop()
+	}
+	if results, ok := mc.cache[prefix]; ok {
+		mc.addResults(results)
+		mc.render()
+		return
+	}
+	mc.render()
+
+	cardId := currentCardId
+	mc.timer = time.AfterFunc(autocompleteDelay, func() {
+		results, err := deck_http.GetAutocomplete(prefix, cardId, configuration)
+		if err != nil {
+			return
+		}
+		app.QueueUpdateDraw(func() {
+			mc.cache[prefix] = results
+			if mc.prefix != prefix || mc.start != at {
+				return
+			}
+			mc.addResults(results)
+			mc.render()
+		})
+	})
+}
+
+func (mc *mentionCompleter) addResults(results []deck_structs.Autocomplete) {
+	for _, r := range results {
+		found := false
+		for _, c := range mc.candidates {
+			if c.Uid == r.Id {
+				found = true
+				break
+			}
+		}
+		if !found {
+			mc.candidates = append(mc.candidates, deck_structs.Owner{Uid: r.Id, DisplayName: r.Label})
+		}
+	}
+}
+
+func (mc *mentionCompleter) inputCapture(event *tcell.EventKey) *tcell.EventKey {
+	if len(mc.candidates) == 0 {
+		return event
+	}
+	switch event.Key() {
+	case tcell.KeyDown:
+		mc.selected = (mc.selected + 1) % len(mc.candidates)
+		mc.render()
+		return nil
+	case tcell.KeyUp:
+		mc.selected = (mc.selected - 1 + len(mc.candidates)) % len(mc.candidates)
+		mc.render()
+		return nil
+	case tcell.KeyEnter, tcell.KeyTab:
+		mc.accept()
+		return nil
+	}
+	return event
+}
+
+func (mc *mentionCompleter) accept() {
+	uid := mc.candidates[mc.selected].Uid
+	mention := fmt.Sprintf("@%s ", uid)
+	if strings.Contains(uid, " ") {
+		mention = fmt.Sprintf(`@"%s" `, uid)
+	}
+	start, end := mc.start, mc.end
+	mc.clear()
+	mc.area.Replace(start, end, mention)
+}
+
+func (mc *mentionCompleter) clear() {
+	if mc.timer != nil {
+		mc.timer.Stop()
+	}
+	mc.candidates = nil
+	mc.prefix = ""
+	mc.start = -1
+	mc.view.SetText("")
+}
+
+func (mc *mentionCompleter) render() {
+	text := ""
+	for i, c := range mc.candidates {
+		if i == mc.selected {
+			text = text + fmt.Sprintf("[%s:%s]%s (@%s)[-:-:-]\n", deck_theme.Current.SelectionText, deck_theme.Current.SelectionBackground, tview.Escape(c.DisplayName), tview.Escape(c.Uid))
+		} else {
+			text = text + fmt.Sprintf("[-]%s (@%s)[-:-:-]\n", tview.Escape(c.DisplayName), tview.Escape(c.Uid))
+		}
+	}
+	mc.view.SetText(text)
+}
+
+func filterUsers(users []deck_structs.Owner, prefix string) []deck_structs.Owner {
+	filtered := make([]deck_structs.Owner, 0)
+	prefix = strings.ToLower(prefix)
+	for _, u := range users {
+		if strings.HasPrefix(strings.ToLower(u.Uid), prefix) || strings.Contains(strings.ToLower(u.DisplayName), prefix) {
+			filtered = append(filtered, u)
+		}
+	}
+	return filtered
+}
+
 func AddComment(cardId int, comment deck_structs.Comment) error {
-	jsonBody := strings.ReplaceAll(fmt.Sprintf(`{"message":"%s" }`, comment.Message), "\n", `\n`)
+	jsonBody := fmt.Sprintf(`{"message":"%s" }`, utils.CleanText(comment.Message))
 	var newComment deck_structs.Comment
 	var err error
 	newComment, err = deck_http.AddComment(cardId, jsonBody, configuration)
@@ -180,11 +529,14 @@ func AddComment(cardId int, comment deck_structs.Comment) error {
 	}
 	CommentTreeStructMap[newComment.Id] = &CommentStruct{Comment: newComment}
 	CommentsMap[newComment.Id] = newComment
+	updateCache(func(page *commentPage) {
+		page.Comments = append([]deck_structs.Comment{newComment}, page.Comments...)
+	})
 	return nil
 }
 
 func EditComment(cardId int, comment deck_structs.Comment) error {
-	jsonBody := strings.ReplaceAll(fmt.Sprintf(`{"message":"%s" }`, comment.Message), "\n", `\n`)
+	jsonBody := fmt.Sprintf(`{"message":"%s" }`, utils.CleanText(comment.Message))
 	editComment, err := deck_http.EditComment(cardId, comment.Id, jsonBody, configuration)
 	if err != nil {
 		return err
@@ -196,12 +548,21 @@ func EditComment(cardId int, comment deck_structs.Comment) error {
 				break
 			}
 		}
+		CommentsMap[editComment.Id] = editComment
+		updateCache(func(page *commentPage) {
+			for i, c := range page.Comments {
+				if c.Id == editComment.Id {
+					page.Comments[i] = editComment
+					break
+				}
+			}
+		})
 	}
 	return nil
 }
 
 func ReplyComment(cardId int, parentId int, comment deck_structs.Comment) error {
-	jsonBody := strings.ReplaceAll(fmt.Sprintf(`{"message":"%s", "parentId": %d }`, comment.Message, parentId), "\n", `\n`)
+	jsonBody := fmt.Sprintf(`{"message":"%s", "parentId": %d }`, utils.CleanText(comment.Message), parentId)
 	//var newComment deck_structs.Comment
 	newComment, err := deck_http.AddComment(cardId, jsonBody, configuration)
 	if err != nil {
@@ -216,6 +577,9 @@ func ReplyComment(cardId int, parentId int, comment deck_structs.Comment) error
 				break
 			}
 		}
+		updateCache(func(page *commentPage) {
+			page.Comments = append([]deck_structs.Comment{newComment}, page.Comments...)
+		})
 	}
 	return nil
 }
@@ -224,7 +588,7 @@ func DeleteComment(cardId int, commentId int) {
 
 	Modal.ClearButtons()
 	Modal.SetText(fmt.Sprintf("Are you sure to delete comment #%d?", commentId))
-	Modal.SetBackgroundColor(utils.GetColor(configuration.Color))
+	deck_theme.StyleModal(Modal)
 
 	Modal.AddButtons([]string{"Yes", "No"})
 
@@ -265,6 +629,20 @@ func DeleteComment(cardId int, commentId int) {
 						}
 					}()
 
+					deleted := map[int]bool{commentId: true}
+					for _, c := range list {
+						deleted[c.Id] = true
+					}
+					updateCache(func(page *commentPage) {
+						comments := make([]deck_structs.Comment, 0)
+						for _, c := range page.Comments {
+							if !deleted[c.Id] {
+								comments = append(comments, c)
+							}
+						}
+						page.Comments = comments
+					})
+
 					node.remove()
 					break
 				}
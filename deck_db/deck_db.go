@@ -4,11 +4,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"time"
 	"tui-deck/deck_http"
 	"tui-deck/deck_structs"
 	"tui-deck/utils"
 )
 
+const maxSnapshots = 366
+
 func GetBoardDetails(boardId int, updateBoard bool, configuration utils.Configuration) (deck_structs.Board, error) {
 	currentBoard := deck_structs.Board{}
 	var fileName = fmt.Sprintf("%s/db/board-detail-%d.json", configuration.ConfigDir, boardId)
@@ -91,3 +94,70 @@ func GetStacks(boardId int, updateBoard bool, configuration utils.Configuration)
 	}
 	return stacks, nil
 }
+
+func GetSnapshots(boardId int, configuration utils.Configuration) ([]deck_structs.Snapshot, error) {
+	snapshots := make([]deck_structs.Snapshot, 0)
+	var fileName = fmt.Sprintf("%s/db/snapshots-%d.json", configuration.ConfigDir, boardId)
+	if !utils.Exists(fileName) {
+		return snapshots, nil
+	}
+	snapshotsFile, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer snapshotsFile.Close()
+	decoder := json.NewDecoder(snapshotsFile)
+	err = decoder.Decode(&snapshots)
+	if err != nil {
+		return nil, err
+	}
+	return snapshots, nil
+}
+
+// RecordSnapshot stores the card count of each stack in board order. The
+// burndown chart treats the last stack as "done", see getChartTotal in deck_stats.
+func RecordSnapshot(boardId int, stacks []deck_structs.Stack, configuration utils.Configuration) ([]deck_structs.Snapshot, error) {
+	snapshots, err := GetSnapshots(boardId, configuration)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	snapshot := deck_structs.Snapshot{Date: now.Format("2006-01-02")}
+	for _, s := range stacks {
+		snapshot.Stacks = append(snapshot.Stacks, deck_structs.StackCount{Title: s.Title, Count: len(s.Cards)})
+		for _, card := range s.Cards {
+			if len(card.DueDate) == 0 {
+				continue
+			}
+			due, err := time.Parse("2006-01-02T15:04:05+00:00", card.DueDate)
+			if err == nil && due.Before(now) {
+				snapshot.Overdue++
+			}
+		}
+	}
+	if len(snapshots) > 0 && snapshots[len(snapshots)-1].Date == snapshot.Date {
+		snapshots[len(snapshots)-1] = snapshot
+	} else {
+		snapshots = append(snapshots, snapshot)
+	}
+	if len(snapshots) > maxSnapshots {
+		snapshots = snapshots[len(snapshots)-maxSnapshots:]
+	}
+
+	var fileName = fmt.Sprintf("%s/db/snapshots-%d.json", configuration.ConfigDir, boardId)
+	snapshotsFile, err := utils.CreateFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer snapshotsFile.Close()
+	marshal, err := json.Marshal(snapshots)
+	if err != nil {
+		return nil, err
+	}
+	_, err = snapshotsFile.Write(marshal)
+	if err != nil {
+		return nil, err
+	}
+	return snapshots, nil
+}
@@ -0,0 +1,766 @@
+package deck_export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"tui-deck/deck_http"
+	"tui-deck/deck_structs"
+	"tui-deck/utils"
+)
+
+const exportVersion = 1
+const dateFormat = "2006-01-02T15:04:05+00:00"
+const commentsPageSize = 50
+const maxCommentLength = 1000
+const defaultColor = "0082c9"
+
+type BoardExport struct {
+	Version    int                            `json:"version"`
+	ExportedAt string                         `json:"exportedAt"`
+	Board      deck_structs.Board             `json:"board"`
+	Stacks     []deck_structs.Stack           `json:"stacks"`
+	Comments   map[int][]deck_structs.Comment `json:"comments"`
+}
+
+type trelloBoard struct {
+	Name   string `json:"name"`
+	Labels []struct {
+		Id    string `json:"id"`
+		Name  string `json:"name"`
+		Color string `json:"color"`
+	} `json:"labels"`
+	Lists []struct {
+		Id     string  `json:"id"`
+		Name   string  `json:"name"`
+		Closed bool    `json:"closed"`
+		Pos    float64 `json:"pos"`
+	} `json:"lists"`
+	Cards []struct {
+		Id        string   `json:"id"`
+		Name      string   `json:"name"`
+		Desc      string   `json:"desc"`
+		IdList    string   `json:"idList"`
+		IdLabels  []string `json:"idLabels"`
+		IdMembers []string `json:"idMembers"`
+		Due       string   `json:"due"`
+		Closed    bool     `json:"closed"`
+		Pos       float64  `json:"pos"`
+	} `json:"cards"`
+	Members []struct {
+		Id       string `json:"id"`
+		Username string `json:"username"`
+		FullName string `json:"fullName"`
+	} `json:"members"`
+	Checklists []struct {
+		Name       string  `json:"name"`
+		IdCard     string  `json:"idCard"`
+		Pos        float64 `json:"pos"`
+		CheckItems []struct {
+			Name  string  `json:"name"`
+			State string  `json:"state"`
+			Pos   float64 `json:"pos"`
+		} `json:"checkItems"`
+	} `json:"checklists"`
+	Actions []struct {
+		Type string `json:"type"`
+		Date string `json:"date"`
+		Data struct {
+			Text string `json:"text"`
+			Card struct {
+				Id string `json:"id"`
+			} `json:"card"`
+		} `json:"data"`
+		MemberCreator struct {
+			Username string `json:"username"`
+			FullName string `json:"fullName"`
+		} `json:"memberCreator"`
+	} `json:"actions"`
+}
+
+type wekanBoard struct {
+	Title  string `json:"title"`
+	Color  string `json:"color"`
+	Labels []struct {
+		Id    string `json:"_id"`
+		Name  string `json:"name"`
+		Color string `json:"color"`
+	} `json:"labels"`
+	Lists []struct {
+		Id       string  `json:"_id"`
+		Title    string  `json:"title"`
+		Archived bool    `json:"archived"`
+		Sort     float64 `json:"sort"`
+	} `json:"lists"`
+	Cards []struct {
+		Id          string   `json:"_id"`
+		Title       string   `json:"title"`
+		Description string   `json:"description"`
+		ListId      string   `json:"listId"`
+		LabelIds    []string `json:"labelIds"`
+		Members     []string `json:"members"`
+		Assignees   []string `json:"assignees"`
+		DueAt       string   `json:"dueAt"`
+		Archived    bool     `json:"archived"`
+		Sort        float64  `json:"sort"`
+	} `json:"cards"`
+	Users []struct {
+		Id       string `json:"_id"`
+		Username string `json:"username"`
+		Profile  struct {
+			Fullname string `json:"fullname"`
+		} `json:"profile"`
+	} `json:"users"`
+	Comments []struct {
+		CardId    string `json:"cardId"`
+		UserId    string `json:"userId"`
+		Text      string `json:"text"`
+		CreatedAt string `json:"createdAt"`
+	} `json:"comments"`
+}
+
+var labelColors = map[string]string{
+	"green":  "61bd4f",
+	"yellow": "f2d600",
+	"orange": "ff9f1a",
+	"red":    "eb5a46",
+	"purple": "c377e0",
+	"blue":   "0079bf",
+	"sky":    "00c2e0",
+	"lime":   "51e898",
+	"pink":   "ff78cb",
+	"black":  "344563",
+	"white":  "ffffff",
+	"silver": "c0c0c0",
+	"gray":   "808080",
+	"navy":   "000080",
+	"gold":   "ffd700",
+}
+
+var configuration utils.Configuration
+
+func Init(conf utils.Configuration) {
+	configuration = conf
+}
+
+func GetBoard(name string) (BoardExport, error) {
+	boards, err := deck_http.GetBoards(configuration)
+	if err != nil {
+		return BoardExport{}, err
+	}
+	boardId := 0
+	for _, b := range boards {
+		if strings.EqualFold(b.Title, name) || strconv.Itoa(b.Id) == name {
+			boardId = b.Id
+			break
+		}
+	}
+	if boardId == 0 {
+		return BoardExport{}, fmt.Errorf("unknown board %s", name)
+	}
+
+	board, err := deck_http.GetBoardDetail(boardId, configuration)
+	if err != nil {
+		return BoardExport{}, err
+	}
+	stacks, err := deck_http.GetStacks(boardId, configuration)
+	if err != nil {
+		return BoardExport{}, err
+	}
+	archived, err := deck_http.GetArchivedStacks(boardId, configuration)
+	if err != nil {
+		return BoardExport{}, err
+	}
+	for _, a := range archived {
+		found := false
+		for i, s := range stacks {
+			if s.Id == a.Id {
+				stacks[i].Cards = append(stacks[i].Cards, a.Cards...)
+				found = true
+				break
+			}
+		}
+		if !found {
+			stacks = append(stacks, a)
+		}
+	}
+	sortStacks(stacks)
+
+	comments := make(map[int][]deck_structs.Comment)
+	for _, s := range stacks {
+		for _, card := range s.Cards {
+			cardComments, err := getComments(card.Id)
+			if err != nil {
+				return BoardExport{}, fmt.Errorf("error getting comments of card #%d: %s", card.Id, err.Error())
+			}
+			if len(cardComments) > 0 {
+				comments[card.Id] = cardComments
+			}
+		}
+	}
+
+	return BoardExport{
+		Version:    exportVersion,
+		ExportedAt: time.Now().UTC().Format(dateFormat),
+		Board:      board,
+		Stacks:     stacks,
+		Comments:   comments,
+	}, nil
+}
+
+func getComments(cardId int) ([]deck_structs.Comment, error) {
+	comments := make([]deck_structs.Comment, 0)
+	for {
+		page, err := deck_http.GetComments(cardId, commentsPageSize, len(comments), configuration)
+		if err != nil {
+			return nil, err
+		}
+		comments = append(comments, page...)
+		if len(page) < commentsPageSize {
+			break
+		}
+	}
+	sort.SliceStable(comments, func(i, j int) bool {
+		return comments[i].CreationDateTime < comments[j].CreationDateTime
+	})
+	return comments, nil
+}
+
+func sortStacks(stacks []deck_structs.Stack) {
+	sort.SliceStable(stacks, func(i, j int) bool {
+		return stacks[i].Order < stacks[j].Order
+	})
+	for _, s := range stacks {
+		sort.SliceStable(s.Cards, func(i, j int) bool {
+			return s.Cards[i].Order < s.Cards[j].Order
+		})
+	}
+}
+
+func CheckFormat(format string) error {
+	switch format {
+	case "json", "md", "csv":
+		return nil
+	}
+	return fmt.Errorf("unknown format %s, use json, md or csv", format)
+}
+
+func Write(export BoardExport, format string, w io.Writer) error {
+	err := CheckFormat(format)
+	if err != nil {
+		return err
+	}
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(export)
+	case "md":
+		return writeMarkdown(export, w)
+	}
+	return writeCsv(export, w)
+}
+
+func writeMarkdown(export BoardExport, w io.Writer) error {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("# %s\n\n", export.Board.Title))
+	sb.WriteString(fmt.Sprintf("exported on %s\n", formatDate(export.ExportedAt)))
+	if len(export.Board.Labels) > 0 {
+		sb.WriteString(fmt.Sprintf("\nlabels: %s\n", strings.Join(getLabelTitles(export.Board.Labels), ", ")))
+	}
+	for _, s := range export.Stacks {
+		sb.WriteString(fmt.Sprintf("\n## %s\n", s.Title))
+		for _, card := range s.Cards {
+			sb.WriteString(fmt.Sprintf("\n### #%d %s\n\n", card.Id, card.Title))
+			if len(card.DueDate) > 0 {
+				sb.WriteString(fmt.Sprintf("* due date: %s\n", formatDate(card.DueDate)))
+			}
+			if len(card.Labels) > 0 {
+				sb.WriteString(fmt.Sprintf("* labels: %s\n", strings.Join(getLabelTitles(card.Labels), ", ")))
+			}
+			if len(card.AssignedUsers) > 0 {
+				sb.WriteString(fmt.Sprintf("* assignees: %s\n", strings.Join(getUserNames(card.AssignedUsers), ", ")))
+			}
+			if card.Archived {
+				sb.WriteString("* archived\n")
+			}
+			if len(strings.TrimSpace(card.Description)) > 0 {
+				sb.WriteString(fmt.Sprintf("\n%s\n", strings.TrimSpace(card.Description)))
+			}
+			comments := export.Comments[card.Id]
+			if len(comments) > 0 {
+				sb.WriteString("\n#### comments\n\n")
+				for _, comment := range comments {
+					sb.WriteString(fmt.Sprintf("* **%s** (%s): %s\n", comment.ActorDisplayName, formatDate(comment.CreationDateTime),
+						strings.ReplaceAll(comment.Message, "\n", "\n  ")))
+				}
+			}
+		}
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func writeCsv(export BoardExport, w io.Writer) error {
+	writer := csv.NewWriter(w)
+	err := writer.Write([]string{"board", "stack", "id", "title", "description", "labels", "assignees", "due date", "archived", "comments"})
+	if err != nil {
+		return err
+	}
+	for _, s := range export.Stacks {
+		for _, card := range s.Cards {
+			comments := make([]string, 0)
+			for _, comment := range export.Comments[card.Id] {
+				comments = append(comments, fmt.Sprintf("%s (%s): %s", comment.ActorDisplayName, formatDate(comment.CreationDateTime), comment.Message))
+			}
+			err = writer.Write([]string{
+				export.Board.Title,
+				s.Title,
+				strconv.Itoa(card.Id),
+				card.Title,
+				card.Description,
+				strings.Join(getLabelTitles(card.Labels), ", "),
+				strings.Join(getUserNames(card.AssignedUsers), ", "),
+				formatDate(card.DueDate),
+				strconv.FormatBool(card.Archived),
+				strings.Join(comments, "\n"),
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func formatDate(date string) string {
+	if len(date) == 0 {
+		return ""
+	}
+	parse, err := time.Parse(time.RFC3339, date)
+	if err != nil {
+		return date
+	}
+	return parse.Format("02/01/2006 15:04")
+}
+
+func getLabelTitles(labels []deck_structs.Label) []string {
+	titles := make([]string, 0)
+	for _, label := range labels {
+		titles = append(titles, label.Title)
+	}
+	return titles
+}
+
+func getUserNames(users []deck_structs.AssignedUser) []string {
+	names := make([]string, 0)
+	for _, user := range users {
+		names = append(names, user.Participant.DisplayName)
+	}
+	return names
+}
+
+func Read(file string, format string) (BoardExport, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return BoardExport{}, err
+	}
+	if format == "auto" {
+		format, err = detectFormat(data)
+		if err != nil {
+			return BoardExport{}, err
+		}
+	}
+
+	switch format {
+	case "json":
+		export := BoardExport{}
+		err = json.Unmarshal(data, &export)
+		if err != nil {
+			return BoardExport{}, fmt.Errorf("error reading %s: %s", file, err.Error())
+		}
+		return export, nil
+	case "trello":
+		trello := trelloBoard{}
+		err = json.Unmarshal(data, &trello)
+		if err != nil {
+			return BoardExport{}, fmt.Errorf("error reading %s: %s", file, err.Error())
+		}
+		return fromTrello(trello), nil
+	case "wekan":
+		wekan := wekanBoard{}
+		err = json.Unmarshal(data, &wekan)
+		if err != nil {
+			return BoardExport{}, fmt.Errorf("error reading %s: %s", file, err.Error())
+		}
+		return fromWekan(wekan), nil
+	}
+	return BoardExport{}, fmt.Errorf("unknown format %s, use auto, json, trello or wekan", format)
+}
+
+func detectFormat(data []byte) (string, error) {
+	keys := make(map[string]json.RawMessage)
+	err := json.Unmarshal(data, &keys)
+	if err != nil {
+		return "", err
+	}
+	if _, ok := keys["stacks"]; ok {
+		return "json", nil
+	}
+	if _, ok := keys["swimlanes"]; ok {
+		return "wekan", nil
+	}
+	if _, ok := keys["idOrganization"]; ok {
+		return "trello", nil
+	}
+	if _, ok := keys["actions"]; ok {
+		return "trello", nil
+	}
+	if _, ok := keys["lists"]; ok {
+		if _, ok := keys["name"]; ok {
+			return "trello", nil
+		}
+		return "wekan", nil
+	}
+	return "", fmt.Errorf("unknown file format, set it with --format")
+}
+
+func fromTrello(trello trelloBoard) BoardExport {
+	export := BoardExport{
+		Version:  exportVersion,
+		Board:    deck_structs.Board{Title: trello.Name, Color: defaultColor},
+		Comments: make(map[int][]deck_structs.Comment),
+	}
+
+	labels := make(map[string]deck_structs.Label)
+	for i, l := range trello.Labels {
+		label := deck_structs.Label{Id: i + 1, Title: l.Name, Color: getLabelColor(l.Color)}
+		if len(label.Title) == 0 {
+			label.Title = l.Color
+		}
+		labels[l.Id] = label
+		export.Board.Labels = append(export.Board.Labels, label)
+	}
+	members := make(map[string]deck_structs.Owner)
+	for _, m := range trello.Members {
+		members[m.Id] = deck_structs.Owner{Uid: m.Username, DisplayName: m.FullName}
+	}
+
+	sort.SliceStable(trello.Lists, func(i, j int) bool {
+		return trello.Lists[i].Pos < trello.Lists[j].Pos
+	})
+	sort.SliceStable(trello.Cards, func(i, j int) bool {
+		return trello.Cards[i].Pos < trello.Cards[j].Pos
+	})
+	sort.SliceStable(trello.Checklists, func(i, j int) bool {
+		return trello.Checklists[i].Pos < trello.Checklists[j].Pos
+	})
+	checklists := make(map[string]string)
+	for _, checklist := range trello.Checklists {
+		sort.SliceStable(checklist.CheckItems, func(i, j int) bool {
+			return checklist.CheckItems[i].Pos < checklist.CheckItems[j].Pos
+		})
+		var sb strings.Builder
+		sb.WriteString(fmt.Sprintf("\n\n### %s\n\n", checklist.Name))
+		for _, item := range checklist.CheckItems {
+			check := " "
+			if item.State == "complete" {
+				check = "x"
+			}
+			sb.WriteString(fmt.Sprintf("- [%s] %s\n", check, item.Name))
+		}
+		checklists[checklist.IdCard] += strings.TrimSuffix(sb.String(), "\n")
+	}
+	cardIds := make(map[string]int)
+	for i, l := range trello.Lists {
+		stack := deck_structs.Stack{Id: i + 1, Title: l.Name, Order: i}
+		for _, c := range trello.Cards {
+			if c.IdList != l.Id {
+				continue
+			}
+			card := deck_structs.Card{
+				Id:          len(cardIds) + 1,
+				Title:       c.Name,
+				Description: strings.TrimPrefix(c.Desc+checklists[c.Id], "\n\n"),
+				StackId:     stack.Id,
+				Order:       len(stack.Cards),
+				DueDate:     getDueDate(c.Due),
+				Archived:    c.Closed || l.Closed,
+			}
+			for _, id := range c.IdLabels {
+				if label, ok := labels[id]; ok {
+					card.Labels = append(card.Labels, label)
+				}
+			}
+			for _, id := range c.IdMembers {
+				if member, ok := members[id]; ok {
+					card.AssignedUsers = append(card.AssignedUsers, deck_structs.AssignedUser{Participant: member})
+				}
+			}
+			cardIds[c.Id] = card.Id
+			stack.Cards = append(stack.Cards, card)
+		}
+		export.Stacks = append(export.Stacks, stack)
+	}
+
+	for i := len(trello.Actions) - 1; i >= 0; i-- {
+		action := trello.Actions[i]
+		cardId, ok := cardIds[action.Data.Card.Id]
+		if action.Type != "commentCard" || !ok {
+			continue
+		}
+		export.Comments[cardId] = append(export.Comments[cardId], deck_structs.Comment{
+			Message:          action.Data.Text,
+			ActorId:          action.MemberCreator.Username,
+			ActorDisplayName: action.MemberCreator.FullName,
+			CreationDateTime: action.Date,
+		})
+	}
+	return export
+}
+
+func fromWekan(wekan wekanBoard) BoardExport {
+	export := BoardExport{
+		Version:  exportVersion,
+		Board:    deck_structs.Board{Title: wekan.Title, Color: getLabelColor(wekan.Color)},
+		Comments: make(map[int][]deck_structs.Comment),
+	}
+
+	labels := make(map[string]deck_structs.Label)
+	for i, l := range wekan.Labels {
+		label := deck_structs.Label{Id: i + 1, Title: l.Name, Color: getLabelColor(l.Color)}
+		if len(label.Title) == 0 {
+			label.Title = l.Color
+		}
+		labels[l.Id] = label
+		export.Board.Labels = append(export.Board.Labels, label)
+	}
+	users := make(map[string]deck_structs.Owner)
+	for _, u := range wekan.Users {
+		name := u.Profile.Fullname
+		if len(name) == 0 {
+			name = u.Username
+		}
+		users[u.Id] = deck_structs.Owner{Uid: u.Username, DisplayName: name}
+	}
+
+	sort.SliceStable(wekan.Lists, func(i, j int) bool {
+		return wekan.Lists[i].Sort < wekan.Lists[j].Sort
+	})
+	sort.SliceStable(wekan.Cards, func(i, j int) bool {
+		return wekan.Cards[i].Sort < wekan.Cards[j].Sort
+	})
+	cardIds := make(map[string]int)
+	for i, l := range wekan.Lists {
+		stack := deck_structs.Stack{Id: i + 1, Title: l.Title, Order: i}
+		for _, c := range wekan.Cards {
+			if c.ListId != l.Id {
+				continue
+			}
+			card := deck_structs.Card{
+				Id:          len(cardIds) + 1,
+				Title:       c.Title,
+				Description: c.Description,
+				StackId:     stack.Id,
+				Order:       len(stack.Cards),
+				DueDate:     getDueDate(c.DueAt),
+				Archived:    c.Archived || l.Archived,
+			}
+			for _, id := range c.LabelIds {
+				if label, ok := labels[id]; ok {
+					card.Labels = append(card.Labels, label)
+				}
+			}
+			assignees := c.Assignees
+			if len(assignees) == 0 {
+				assignees = c.Members
+			}
+			for _, id := range assignees {
+				if user, ok := users[id]; ok {
+					card.AssignedUsers = append(card.AssignedUsers, deck_structs.AssignedUser{Participant: user})
+				}
+			}
+			cardIds[c.Id] = card.Id
+			stack.Cards = append(stack.Cards, card)
+		}
+		export.Stacks = append(export.Stacks, stack)
+	}
+
+	sort.SliceStable(wekan.Comments, func(i, j int) bool {
+		return wekan.Comments[i].CreatedAt < wekan.Comments[j].CreatedAt
+	})
+	for _, c := range wekan.Comments {
+		cardId, ok := cardIds[c.CardId]
+		if !ok {
+			continue
+		}
+		user := users[c.UserId]
+		export.Comments[cardId] = append(export.Comments[cardId], deck_structs.Comment{
+			Message:          c.Text,
+			ActorId:          user.Uid,
+			ActorDisplayName: user.DisplayName,
+			CreationDateTime: c.CreatedAt,
+		})
+	}
+	return export
+}
+
+func getDueDate(date string) string {
+	if len(date) == 0 {
+		return ""
+	}
+	parse, err := time.Parse(time.RFC3339, date)
+	if err != nil {
+		return ""
+	}
+	return parse.UTC().Format(dateFormat)
+}
+
+func getLabelColor(color string) string {
+	color = strings.TrimPrefix(strings.ToLower(color), "#")
+	if hex, ok := labelColors[color]; ok {
+		return hex
+	}
+	if regexp.MustCompile(`^[0-9a-f]{6}$`).MatchString(color) {
+		return color
+	}
+	return defaultColor
+}
+
+func Import(export BoardExport, title string) (deck_structs.Board, []string, error) {
+	if len(title) == 0 {
+		title = export.Board.Title
+	}
+	if len(title) == 0 {
+		return deck_structs.Board{}, nil, fmt.Errorf("missing board title, set it with --title")
+	}
+	color := getLabelColor(export.Board.Color)
+
+	newBoard, err := deck_http.AddBoard(fmt.Sprintf(`{"title":"%s", "color": "%s"}`, utils.CleanText(title), color), configuration)
+	if err != nil {
+		return deck_structs.Board{}, nil, fmt.Errorf("error creating board: %s", err.Error())
+	}
+	board, err := deck_http.GetBoardDetail(newBoard.Id, configuration)
+	if err != nil {
+		return newBoard, nil, err
+	}
+
+	warnings := make([]string, 0)
+	labels := make(map[string]deck_structs.Label)
+	for _, l := range board.Labels {
+		labels[strings.ToLower(l.Title)] = l
+	}
+	for _, l := range export.Board.Labels {
+		if _, ok := labels[strings.ToLower(l.Title)]; ok {
+			continue
+		}
+		jsonBody := fmt.Sprintf(`{"title":"%s", "color": "%s"}`, utils.CleanText(l.Title), getLabelColor(l.Color))
+		newLabel, err := deck_http.AddBoardLabel(board.Id, jsonBody, configuration)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("error creating label %s: %s", l.Title, err.Error()))
+			continue
+		}
+		labels[strings.ToLower(l.Title)] = newLabel
+	}
+
+	sortStacks(export.Stacks)
+	for i, s := range export.Stacks {
+		jsonBody := fmt.Sprintf(`{"title":"%s", "order": %d}`, utils.CleanText(s.Title), i)
+		stack, err := deck_http.AddStack(board.Id, jsonBody, configuration)
+		if err != nil {
+			return board, warnings, fmt.Errorf("error creating stack %s: %s", s.Title, err.Error())
+		}
+		for _, card := range s.Cards {
+			err = importCard(board, stack, card, labels, export.Comments[card.Id])
+			if err != nil {
+				warnings = append(warnings, fmt.Sprintf("card %s: %s", card.Title, err.Error()))
+			}
+		}
+	}
+	return board, warnings, nil
+}
+
+func importCard(board deck_structs.Board, stack deck_structs.Stack, card deck_structs.Card, labels map[string]deck_structs.Label, comments []deck_structs.Comment) error {
+	dueDate := ""
+	if len(card.DueDate) > 0 {
+		dueDate = fmt.Sprintf(`, "duedate": "%s"`, card.DueDate)
+	}
+	jsonBody := fmt.Sprintf(`{"title":"%s", "description": "%s", "type": "plain", "order": %d%s}`,
+		utils.CleanText(card.Title), utils.CleanText(card.Description), card.Order, dueDate)
+	newCard, err := deck_http.AddCard(board.Id, stack.Id, jsonBody, configuration)
+	if err != nil {
+		return err
+	}
+
+	errs := make([]string, 0)
+	for _, l := range card.Labels {
+		label, ok := labels[strings.ToLower(l.Title)]
+		if !ok {
+			errs = append(errs, fmt.Sprintf("unknown label %s", l.Title))
+			continue
+		}
+		_, err = deck_http.AssignLabel(board.Id, stack.Id, newCard.Id, fmt.Sprintf(`{"labelId": %d}`, label.Id), configuration)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("error assigning label %s: %s", l.Title, err.Error()))
+		}
+	}
+	for _, u := range card.AssignedUsers {
+		uid := ""
+		for _, user := range board.Users {
+			if user.Uid == u.Participant.Uid || strings.EqualFold(user.DisplayName, u.Participant.DisplayName) {
+				uid = user.Uid
+				break
+			}
+		}
+		if len(uid) == 0 {
+			errs = append(errs, fmt.Sprintf("unknown user %s", u.Participant.DisplayName))
+			continue
+		}
+		_, err = deck_http.AssignUser(board.Id, stack.Id, newCard.Id, fmt.Sprintf(`{"userId": "%s"}`, uid), configuration)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("error assigning user %s: %s", u.Participant.DisplayName, err.Error()))
+		}
+	}
+	for _, comment := range comments {
+		jsonBody = fmt.Sprintf(`{"message":"%s" }`, utils.CleanText(getCommentMessage(comment)))
+		_, err = deck_http.AddComment(newCard.Id, jsonBody, configuration)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("error adding comment: %s", err.Error()))
+		}
+	}
+	if card.Archived {
+		_, err = deck_http.ArchiveCard(board.Id, stack.Id, newCard.Id, configuration)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("error archiving: %s", err.Error()))
+		}
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+func getCommentMessage(comment deck_structs.Comment) string {
+	author := comment.ActorDisplayName
+	if len(author) == 0 {
+		author = comment.ActorId
+	}
+	message := comment.Message
+	if len(author) > 0 {
+		message = fmt.Sprintf("%s (%s): %s", author, formatDate(comment.CreationDateTime), comment.Message)
+	}
+	runes := []rune(message)
+	if len(runes) > maxCommentLength {
+		message = string(runes[:maxCommentLength-1]) + "…"
+	}
+	return message
+}
@@ -0,0 +1,210 @@
+package deck_export
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"tui-deck/deck_structs"
+)
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    string
+		wantErr bool
+	}{
+		{name: "tui-deck", data: `{"version": 1, "board": {}, "stacks": []}`, want: "json"},
+		{name: "wekan swimlanes", data: `{"title": "b", "swimlanes": [], "lists": []}`, want: "wekan"},
+		{name: "wekan lists", data: `{"title": "b", "lists": []}`, want: "wekan"},
+		{name: "trello organization", data: `{"idOrganization": "o"}`, want: "trello"},
+		{name: "trello actions", data: `{"actions": []}`, want: "trello"},
+		{name: "trello lists", data: `{"name": "b", "lists": []}`, want: "trello"},
+		{name: "unknown", data: `{"foo": 1}`, wantErr: true},
+		{name: "not json", data: `board`, wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := detectFormat([]byte(test.data))
+			if test.wantErr {
+				if err == nil {
+					t.Errorf("expected an error, got %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestFromTrello(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		stacks   []deck_structs.Stack
+		comments map[int][]deck_structs.Comment
+	}{
+		{
+			name: "lists and cards by position",
+			data: `{"name": "Board",
+				"labels": [{"id": "l1", "name": "", "color": "red"}],
+				"members": [{"id": "m1", "username": "alice", "fullName": "Alice"}],
+				"lists": [{"id": "b", "name": "Done", "pos": 2}, {"id": "a", "name": "To do", "pos": 1, "closed": true}],
+				"cards": [
+					{"id": "c2", "name": "second", "idList": "a", "pos": 2, "due": "2024-01-02T10:00:00.000Z", "idLabels": ["l1"], "idMembers": ["m1"]},
+					{"id": "c1", "name": "first", "idList": "a", "pos": 1},
+					{"id": "c3", "name": "done", "idList": "b", "pos": 1, "closed": true}
+				]}`,
+			stacks: []deck_structs.Stack{
+				{Id: 1, Title: "To do", Order: 0, Cards: []deck_structs.Card{
+					{Id: 1, Title: "first", StackId: 1, Order: 0, Archived: true},
+					{Id: 2, Title: "second", StackId: 1, Order: 1, DueDate: "2024-01-02T10:00:00+00:00", Archived: true,
+						Labels:        []deck_structs.Label{{Id: 1, Title: "red", Color: "eb5a46"}},
+						AssignedUsers: []deck_structs.AssignedUser{{Participant: deck_structs.Owner{Uid: "alice", DisplayName: "Alice"}}}},
+				}},
+				{Id: 2, Title: "Done", Order: 1, Cards: []deck_structs.Card{
+					{Id: 3, Title: "done", StackId: 2, Order: 0, Archived: true},
+				}},
+			},
+			comments: map[int][]deck_structs.Comment{},
+		},
+		{
+			name: "checklists",
+			data: `{"name": "Board",
+				"lists": [{"id": "a", "name": "To do", "pos": 1}],
+				"cards": [{"id": "c1", "name": "card", "desc": "Intro", "idList": "a", "pos": 1}, {"id": "c2", "name": "empty", "idList": "a", "pos": 2}],
+				"checklists": [
+					{"name": "Later", "idCard": "c1", "pos": 2, "checkItems": [{"name": "three", "state": "incomplete", "pos": 1}]},
+					{"name": "Steps", "idCard": "c1", "pos": 1, "checkItems": [
+						{"name": "two", "state": "incomplete", "pos": 2},
+						{"name": "one", "state": "complete", "pos": 1}
+					]},
+					{"name": "Only", "idCard": "c2", "pos": 1, "checkItems": [{"name": "item", "state": "complete", "pos": 1}]}
+				]}`,
+			stacks: []deck_structs.Stack{
+				{Id: 1, Title: "To do", Order: 0, Cards: []deck_structs.Card{
+					{Id: 1, Title: "card", StackId: 1, Order: 0,
+						Description: "Intro\n\n### Steps\n\n- [x] one\n- [ ] two\n\n### Later\n\n- [ ] three"},
+					{Id: 2, Title: "empty", StackId: 1, Order: 1, Description: "### Only\n\n- [x] item"},
+				}},
+			},
+			comments: map[int][]deck_structs.Comment{},
+		},
+		{
+			name: "comments oldest first",
+			data: `{"name": "Board",
+				"lists": [{"id": "a", "name": "To do", "pos": 1}],
+				"cards": [{"id": "c1", "name": "card", "idList": "a", "pos": 1}],
+				"actions": [
+					{"type": "commentCard", "date": "2024-01-02T00:00:00.000Z", "data": {"text": "newer", "card": {"id": "c1"}}, "memberCreator": {"username": "bob", "fullName": "Bob"}},
+					{"type": "updateCard", "date": "2024-01-01T12:00:00.000Z", "data": {"card": {"id": "c1"}}},
+					{"type": "commentCard", "date": "2024-01-01T00:00:00.000Z", "data": {"text": "older", "card": {"id": "c1"}}, "memberCreator": {"username": "alice", "fullName": "Alice"}},
+					{"type": "commentCard", "date": "2024-01-01T00:00:00.000Z", "data": {"text": "unknown", "card": {"id": "c9"}}}
+				]}`,
+			stacks: []deck_structs.Stack{
+				{Id: 1, Title: "To do", Order: 0, Cards: []deck_structs.Card{{Id: 1, Title: "card", StackId: 1, Order: 0}}},
+			},
+			comments: map[int][]deck_structs.Comment{1: {
+				{Message: "older", ActorId: "alice", ActorDisplayName: "Alice", CreationDateTime: "2024-01-01T00:00:00.000Z"},
+				{Message: "newer", ActorId: "bob", ActorDisplayName: "Bob", CreationDateTime: "2024-01-02T00:00:00.000Z"},
+			}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			trello := trelloBoard{}
+			err := json.Unmarshal([]byte(test.data), &trello)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := fromTrello(trello)
+			if got.Board.Title != "Board" {
+				t.Errorf("board title %q, want %q", got.Board.Title, "Board")
+			}
+			if !reflect.DeepEqual(got.Stacks, test.stacks) {
+				t.Errorf("stacks\ngot:  %+v\nwant: %+v", got.Stacks, test.stacks)
+			}
+			if !reflect.DeepEqual(got.Comments, test.comments) {
+				t.Errorf("comments\ngot:  %+v\nwant: %+v", got.Comments, test.comments)
+			}
+		})
+	}
+}
+
+func TestFromWekan(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		stacks   []deck_structs.Stack
+		comments map[int][]deck_structs.Comment
+	}{
+		{
+			name: "lists and cards by sort",
+			data: `{"title": "Board", "color": "belize",
+				"labels": [{"_id": "l1", "name": "urgent", "color": "#EB5A46"}],
+				"users": [{"_id": "u1", "username": "alice", "profile": {"fullname": "Alice"}}, {"_id": "u2", "username": "bob"}],
+				"lists": [{"_id": "b", "title": "Done", "sort": 1, "archived": true}, {"_id": "a", "title": "To do", "sort": 0}],
+				"cards": [
+					{"_id": "c2", "title": "second", "listId": "a", "sort": 2, "dueAt": "2024-01-02T10:00:00.000Z", "labelIds": ["l1"], "members": ["u1"], "assignees": ["u2"]},
+					{"_id": "c1", "title": "first", "description": "text", "listId": "a", "sort": 1, "members": ["u1"], "archived": true},
+					{"_id": "c3", "title": "done", "listId": "b", "sort": 0}
+				]}`,
+			stacks: []deck_structs.Stack{
+				{Id: 1, Title: "To do", Order: 0, Cards: []deck_structs.Card{
+					{Id: 1, Title: "first", Description: "text", StackId: 1, Order: 0, Archived: true,
+						AssignedUsers: []deck_structs.AssignedUser{{Participant: deck_structs.Owner{Uid: "alice", DisplayName: "Alice"}}}},
+					{Id: 2, Title: "second", StackId: 1, Order: 1, DueDate: "2024-01-02T10:00:00+00:00",
+						Labels:        []deck_structs.Label{{Id: 1, Title: "urgent", Color: "eb5a46"}},
+						AssignedUsers: []deck_structs.AssignedUser{{Participant: deck_structs.Owner{Uid: "bob", DisplayName: "bob"}}}},
+				}},
+				{Id: 2, Title: "Done", Order: 1, Cards: []deck_structs.Card{
+					{Id: 3, Title: "done", StackId: 2, Order: 0, Archived: true},
+				}},
+			},
+			comments: map[int][]deck_structs.Comment{},
+		},
+		{
+			name: "comments sorted by date",
+			data: `{"title": "Board",
+				"users": [{"_id": "u1", "username": "alice", "profile": {"fullname": "Alice"}}],
+				"lists": [{"_id": "a", "title": "To do", "sort": 0}],
+				"cards": [{"_id": "c1", "title": "card", "listId": "a", "sort": 0}],
+				"comments": [
+					{"cardId": "c1", "userId": "u1", "text": "newer", "createdAt": "2024-01-02T00:00:00.000Z"},
+					{"cardId": "c1", "userId": "u9", "text": "older", "createdAt": "2024-01-01T00:00:00.000Z"},
+					{"cardId": "c9", "userId": "u1", "text": "unknown", "createdAt": "2024-01-01T00:00:00.000Z"}
+				]}`,
+			stacks: []deck_structs.Stack{
+				{Id: 1, Title: "To do", Order: 0, Cards: []deck_structs.Card{{Id: 1, Title: "card", StackId: 1, Order: 0}}},
+			},
+			comments: map[int][]deck_structs.Comment{1: {
+				{Message: "older", CreationDateTime: "2024-01-01T00:00:00.000Z"},
+				{Message: "newer", ActorId: "alice", ActorDisplayName: "Alice", CreationDateTime: "2024-01-02T00:00:00.000Z"},
+			}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			wekan := wekanBoard{}
+			err := json.Unmarshal([]byte(test.data), &wekan)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := fromWekan(wekan)
+			if got.Board.Title != "Board" {
+				t.Errorf("board title %q, want %q", got.Board.Title, "Board")
+			}
+			if !reflect.DeepEqual(got.Stacks, test.stacks) {
+				t.Errorf("stacks\ngot:  %+v\nwant: %+v", got.Stacks, test.stacks)
+			}
+			if !reflect.DeepEqual(got.Comments, test.comments) {
+				t.Errorf("comments\ngot:  %+v\nwant: %+v", got.Comments, test.comments)
+			}
+		})
+	}
+}
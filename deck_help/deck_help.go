@@ -1,145 +1,55 @@
 package deck_help
 
 import (
+	"fmt"
 	"github.com/rivo/tview"
+	"tui-deck/deck_keys"
+	"tui-deck/deck_theme"
 )
 
-var HelpMain = tview.NewTextView()
-var HelpView = tview.NewTextView()
-var HelpEdit = tview.NewTextView()
-var HelpLabels = tview.NewTextView()
-var HelpUsers = tview.NewTextView()
-var HelpBoards = tview.NewTextView()
-var HelpComments = tview.NewTextView()
+var HelpViews = make(map[string]*tview.TextView)
 
-func InitHelp() {
-	HelpMain = getHelp()
-	HelpView = getHelp2()
-	HelpEdit = getHelp3()
-	HelpLabels = getHelp4()
-	HelpBoards = getHelp5()
-	HelpComments = getHelp6()
-	HelpUsers = getHelp7()
+var notes = map[string]string{
+	deck_keys.Edit:        "Type to enter text.",
+	deck_keys.Labels:      "ENTER: If card label has been selected, delete it. If available label has been selected, add it to card.",
+	deck_keys.Users:       "ENTER: If card user has been selected, delete it. If available user has been selected, add it to card.",
+	deck_keys.Comments:    "Up/Down arrow: Move between comments.\nENTER: Load more comments when \"load more\" is selected.\n\nWhile writing a comment, type @ to mention a user:\nUp/Down arrow: Choose user.\nENTER/TAB: Insert mention.",
+	deck_keys.BoardLabels: "ENTER: Delete selected label.",
 }
 
-func getHelp() *tview.TextView {
-	HelpMain = tview.NewTextView().
-		SetDynamicColors(true).
-		SetText(`[green]Main[white]
-
-[yellow]TAB[white]: Switch stack.
-[yellow]Down arrow[white]: Move down.
-[yellow]Up arrow[white]: Move up.
-[yellow]Right arrow[white]: Move card to next stack.
-[yellow]Left arrow[white]: Move card to previous stack.
-[yellow]ENTER[white]: Select card.
-[yellow]s[white]: Switch board.
-[yellow]r[white]: Reload board.
-[yellow]a[white]: Add card to current stack.
-[yellow]d[white]: Delete selected card in current stack.
-[yellow]ctrl+a[white]: Add stack.
-[yellow]ctrl+d[white]: Delete current stack.
-[yellow]ctrl+e[white]: Edit current stack.
-[yellow]q[white]: Quit app.
-[yellow]?[white]: Help.
-
-[blue]Press Enter for more help, press Escape to return.`)
-	HelpMain.SetTitle(" HELP - Main ")
-	return HelpMain
-}
-
-func getHelp2() *tview.TextView {
-	HelpView = tview.NewTextView().
-		SetDynamicColors(true).
-		SetText(`[green]View Card[white]
-
-[yellow]e[white]: Edit card Description.
-[yellow]l[white]: Edit card labels.
-[yellow]u[white]: Edit card users.
-[yellow]t[white]: Edit card title.
-[yellow]c[white]: View comments.
-[yellow]ESC[white]: Back to main view.
-
-[blue]Press Enter for more help, press Escape to return.`)
-	HelpView.SetTitle(" HELP - View Card ")
-	return HelpView
-}
-func getHelp3() *tview.TextView {
-	HelpEdit = tview.NewTextView().
-		SetDynamicColors(true).
-		SetText(`[green]Edit Card[white]
-
-Type to enter text.
-[yellow]F2[white]: Save card.
-[yellow]ESC[white]: Back to card view.
-
-[blue]Press Enter for more help, press Escape to return.`)
-	HelpEdit.SetTitle(" HELP - Edit Card ")
-	return HelpEdit
-}
-
-func getHelp4() *tview.TextView {
-	HelpLabels = tview.NewTextView().
-		SetDynamicColors(true).
-		SetText(`[green]Edit Card Labels[white]
-
-[yellow]Up arrow[white]: Move up.
-[yellow]Down arrow[white]: Move down.
-[yellow]TAB[white]: Switch between card labels and available labels list.
-[yellow]ENTER[white]: If card label has been selected, delete it. If available label has been selected, add it to card
-[yellow]ESC[white]: Back to card view.
-
-[blue]Press Enter for more help, press Escape to return.`)
-	HelpLabels.SetTitle(" HELP - Edit Card Labels ")
-	return HelpLabels
-}
-func getHelp5() *tview.TextView {
-	HelpBoards = tview.NewTextView().
-		SetDynamicColors(true).
-		SetText(`[green]Switch Boards[white]
-
-[yellow]Up arrow[white]: Move up.
-[yellow]Down arrow[white]: Move down.
-[yellow]ENTER[white]: Select board.
-[yellow]a[white]: Add board.
-[yellow]e[white]: Edit board.
-[yellow]d[white]: Delete board.
-[yellow]ESC[white]: Back to main view.
-
-[blue]Press Enter for more help, press Escape to return.`)
-	HelpBoards.SetTitle(" HELP - Switch Boards ")
-	return HelpBoards
-}
-func getHelp6() *tview.TextView {
-	HelpComments = tview.NewTextView().
-		SetDynamicColors(true).
-		SetText(`[green]View Comments[white]
-
-[yellow]Up arrow[white]: Move up.
-[yellow]Down arrow[white]: Move down.
-[yellow]a[white]: Add comment.
-[yellow]r[white]: Reply comment.
-[yellow]e[white]: Edit comment.
-[yellow]d[white]: Delete comment.
-[yellow]ESC[white]: Back to card view.
-
-[blue]Press Enter for more help, press Escape to return.`)
-	HelpComments.SetTitle(" HELP - View Comments ")
-	return HelpComments
-}
-
-func getHelp7() *tview.TextView {
-	HelpUsers = tview.NewTextView().
-		SetDynamicColors(true).
-		SetText(`[green]Edit Card Users[white]
-
-[yellow]Up arrow[white]: Move up.
-[yellow]Down arrow[white]: Move down.
-[yellow]TAB[white]: Switch between card users and available users list.
-[yellow]ENTER[white]: If card user has been selected, delete it. If available user has been selected, add it to card
-[yellow]ESC[white]: Back to card view.
-
-[blue]Press Enter for more help, press Escape to return.`)
-	HelpUsers.SetTitle(" HELP - Edit Card Users ")
-	return HelpUsers
+func InitHelp() {
+	for _, context := range deck_keys.Contexts {
+		view, ok := HelpViews[context.Name]
+		if !ok {
+			view = tview.NewTextView()
+			view.SetDynamicColors(true)
+			HelpViews[context.Name] = view
+		}
+		view.SetTitle(fmt.Sprintf(" HELP - %s ", context.Title))
+		view.SetText(getHelp(context))
+	}
+}
+
+func NextHelp(context string) string {
+	for i, c := range deck_keys.Contexts {
+		if c.Name == context {
+			return deck_keys.Contexts[(i+1)%len(deck_keys.Contexts)].Name
+		}
+	}
+	return deck_keys.Main
+}
+
+func getHelp(context deck_keys.Context) string {
+	text := fmt.Sprintf("[%s]%s[-]\n\n", deck_theme.Current.Accent, context.Title)
+	for _, action := range deck_keys.GetActions(context.Name) {
+		if len(action.Keys) == 0 {
+			continue
+		}
+		text = text + fmt.Sprintf("[%s]%s[-]: %s\n", deck_theme.Current.Warning, tview.Escape(action.FormatKeys()), tview.Escape(action.Description))
+	}
+	if note, ok := notes[context.Name]; ok {
+		text = text + "\n" + tview.Escape(note) + "\n"
+	}
+	text = text + fmt.Sprintf("\n[%s]Press Enter for more help, press Escape to return.", deck_theme.Current.Muted)
+	return text
 }
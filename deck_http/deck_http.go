@@ -2,17 +2,108 @@ package deck_http
 
 import (
 	"bytes"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"net/url"
+	"os"
 	"time"
 	"tui-deck/deck_structs"
 	"tui-deck/utils"
 )
 
+const defaultTimeout = 30
+const defaultConnectTimeout = 10
+
+var client = &http.Client{Timeout: defaultTimeout * time.Second}
+
+func Init(configuration utils.Configuration) error {
+	timeout := configuration.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	connectTimeout := configuration.ConnectTimeout
+	if connectTimeout <= 0 {
+		connectTimeout = defaultConnectTimeout
+	}
+
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   time.Duration(connectTimeout) * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		TLSHandshakeTimeout: time.Duration(connectTimeout) * time.Second,
+		MaxIdleConns:        10,
+		MaxIdleConnsPerHost: 10,
+		IdleConnTimeout:     90 * time.Second,
+		ForceAttemptHTTP2:   true,
+	}
+	client = &http.Client{Transport: transport, Timeout: time.Duration(timeout) * time.Second}
+
+	var errs []error
+	tlsConfig, err := getTlsConfig(configuration)
+	if err != nil {
+		errs = append(errs, err)
+	} else {
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	if len(configuration.Proxy) > 0 {
+		proxy, err := url.Parse(configuration.Proxy)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid proxy %s: %s", configuration.Proxy, err.Error()))
+		} else if proxy.Scheme != "http" && proxy.Scheme != "https" && proxy.Scheme != "socks5" {
+			errs = append(errs, fmt.Errorf("invalid proxy %s: scheme must be http, https or socks5", configuration.Proxy))
+		} else {
+			transport.Proxy = http.ProxyURL(proxy)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func GetClient() *http.Client {
+	return client
+}
+
+func getTlsConfig(configuration utils.Configuration) (*tls.Config, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: configuration.Insecure}
+
+	if len(configuration.CaFile) > 0 {
+		pem, err := os.ReadFile(configuration.CaFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading CA file: %s", err.Error())
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA file %s", configuration.CaFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if len(configuration.ClientCert) > 0 {
+		key := configuration.ClientKey
+		if len(key) == 0 {
+			key = configuration.ClientCert
+		}
+		certificate, err := tls.LoadX509KeyPair(configuration.ClientCert, key)
+		if err != nil {
+			return nil, fmt.Errorf("error loading client certificate: %s", err.Error())
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+	return tlsConfig, nil
+}
+
 func httpCall(jsonBody []byte, method string, url string, user string, password string, ocs bool) (*http.Response, error) {
 	bodyReader := bytes.NewReader(jsonBody)
 
@@ -26,14 +117,16 @@ func httpCall(jsonBody []byte, method string, url string, user string, password
 	if ocs {
 		req.Header.Add("OCS-APIRequest", "true")
 	}
-	client := http.Client{
-		Timeout: 30 * time.Second,
-	}
-
 	res, err := client.Do(req)
 	if err != nil {
 		return nil, err
 	}
+	body, err := io.ReadAll(res.Body)
+	_ = res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	res.Body = io.NopCloser(bytes.NewReader(body))
 	if res.StatusCode != 200 {
 		return res, errors.New(res.Status)
 	}
@@ -88,6 +181,25 @@ func GetStacks(boardId int, configuration utils.Configuration) ([]deck_structs.S
 	return stacks, nil
 }
 
+func GetArchivedStacks(boardId int, configuration utils.Configuration) ([]deck_structs.Stack, error) {
+	call, err := httpCall(nil, http.MethodGet,
+		fmt.Sprintf("%s/index.php/apps/deck/api/v1.1/boards/%d/stacks/archived", configuration.Url, boardId),
+		configuration.User, configuration.Password, false)
+	if err != nil {
+		return nil, err
+
+	}
+
+	decoder := json.NewDecoder(call.Body)
+	var stacks []deck_structs.Stack
+
+	err = decoder.Decode(&stacks)
+	if err != nil {
+		panic(err)
+	}
+	return stacks, nil
+}
+
 func AddCard(boardId int, stackId int, jsonBody string, configuration utils.Configuration) (deck_structs.Card, error) {
 	body := []byte(jsonBody)
 
@@ -147,6 +259,31 @@ func DeleteCard(boardId int, stackId int, cardId int, configuration utils.Config
 	return card, nil
 }
 
+func ArchiveCard(boardId int, stackId int, cardId int, configuration utils.Configuration) (deck_structs.Card, error) {
+	return setCardArchived(boardId, stackId, cardId, "archive", configuration)
+}
+
+func UnarchiveCard(boardId int, stackId int, cardId int, configuration utils.Configuration) (deck_structs.Card, error) {
+	return setCardArchived(boardId, stackId, cardId, "unarchive", configuration)
+}
+
+func setCardArchived(boardId int, stackId int, cardId int, action string, configuration utils.Configuration) (deck_structs.Card, error) {
+	call, err := httpCall(nil, http.MethodPut,
+		fmt.Sprintf("%s/index.php/apps/deck/api/v1.1/boards/%d/stacks/%d/cards/%d/%s", configuration.Url, boardId, stackId, cardId, action),
+		configuration.User, configuration.Password, false)
+	if err != nil {
+		return deck_structs.Card{}, err
+	}
+	decoder := json.NewDecoder(call.Body)
+	var card deck_structs.Card
+
+	err = decoder.Decode(&card)
+	if err != nil {
+		panic(err)
+	}
+	return card, nil
+}
+
 func GetBoardDetail(boardId int, configuration utils.Configuration) (deck_structs.Board, error) {
 	call, err := httpCall(nil, http.MethodGet,
 		fmt.Sprintf("%s/index.php/apps/deck/api/v1.1/boards/%d", configuration.Url, boardId),
@@ -366,10 +503,10 @@ func EditStack(boardId int, stackId int, jsonBody string, configuration utils.Co
 	return stack, nil
 }
 
-func GetComments(cardId int, configuration utils.Configuration) ([]deck_structs.Comment, error) {
+func GetComments(cardId int, limit int, offset int, configuration utils.Configuration) ([]deck_structs.Comment, error) {
 
 	call, err := httpCall(nil, http.MethodGet,
-		fmt.Sprintf("%s/ocs/v2.php/apps/deck/api/v1.0/cards/%d/comments", configuration.Url, cardId),
+		fmt.Sprintf("%s/ocs/v2.php/apps/deck/api/v1.0/cards/%d/comments?limit=%d&offset=%d", configuration.Url, cardId, limit, offset),
 		configuration.User, configuration.Password, true)
 
 	if err != nil {
@@ -388,6 +525,25 @@ func GetComments(cardId int, configuration utils.Configuration) ([]deck_structs.
 	return ocs.Ocs.Data, nil
 }
 
+func GetAutocomplete(search string, cardId int, configuration utils.Configuration) ([]deck_structs.Autocomplete, error) {
+	call, err := httpCall(nil, http.MethodGet,
+		fmt.Sprintf("%s/ocs/v2.php/core/autocomplete/get?search=%s&itemType=deck-card&itemId=%d&shareTypes[]=0&limit=10",
+			configuration.Url, url.QueryEscape(search), cardId),
+		configuration.User, configuration.Password, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var ocs deck_structs.OcsResponseAutocomplete
+
+	decoder := json.NewDecoder(call.Body)
+	err = decoder.Decode(&ocs)
+	if err != nil {
+		return nil, err
+	}
+	return ocs.Ocs.Data, nil
+}
+
 func AddComment(cardId int, jsonBody string, configuration utils.Configuration) (deck_structs.Comment, error) {
 	body := []byte(jsonBody)
 
@@ -476,3 +632,63 @@ func AssignUser(boardId int, stackId int, cardId int, jsonBody string, configura
 	}
 	return assingedUser, nil
 }
+
+func GetNotifications(configuration utils.Configuration) ([]deck_structs.Notification, error) {
+	call, err := httpCall(nil, http.MethodGet,
+		fmt.Sprintf("%s/ocs/v2.php/apps/notifications/api/v2/notifications", configuration.Url),
+		configuration.User, configuration.Password, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var ocs deck_structs.OcsResponseNotifications
+
+	decoder := json.NewDecoder(call.Body)
+	err = decoder.Decode(&ocs)
+	if err != nil {
+		panic(err)
+	}
+	return ocs.Ocs.Data, nil
+}
+
+func GetCardActivities(cardId int, since int, limit int, configuration utils.Configuration) ([]deck_structs.Activity, error) {
+	return getActivities(fmt.Sprintf("%s/ocs/v2.php/apps/activity/api/v2/activity/filter?format=json&object_type=deck_card&object_id=%d&since=%d&limit=%d",
+		configuration.Url, cardId, since, limit), configuration)
+}
+
+func GetDeckActivities(since int, limit int, configuration utils.Configuration) ([]deck_structs.Activity, error) {
+	return getActivities(fmt.Sprintf("%s/ocs/v2.php/apps/activity/api/v2/activity/deck?format=json&since=%d&limit=%d",
+		configuration.Url, since, limit), configuration)
+}
+
+func getActivities(url string, configuration utils.Configuration) ([]deck_structs.Activity, error) {
+	call, err := httpCall(nil, http.MethodGet, url, configuration.User, configuration.Password, true)
+	if call != nil && call.StatusCode == http.StatusNotModified {
+		return []deck_structs.Activity{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var ocs deck_structs.OcsResponseActivities
+
+	decoder := json.NewDecoder(call.Body)
+	err = decoder.Decode(&ocs)
+	if err != nil {
+		return nil, err
+	}
+	return ocs.Ocs.Data, nil
+}
+
+func DeleteNotification(notificationId int, configuration utils.Configuration) (int, error) {
+	call, err := httpCall(nil, http.MethodDelete,
+		fmt.Sprintf("%s/ocs/v2.php/apps/notifications/api/v2/notifications/%d", configuration.Url, notificationId),
+		configuration.User, configuration.Password, true)
+	if call == nil {
+		return 0, err
+	}
+	if err != nil {
+		return call.StatusCode, err
+	}
+	return call.StatusCode, nil
+}
@@ -0,0 +1,434 @@
+package deck_ical
+
+import (
+	"errors"
+	"fmt"
+	"github.com/emersion/go-ical"
+	"github.com/emersion/go-webdav"
+	"github.com/emersion/go-webdav/caldav"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"tui-deck/deck_http"
+	"tui-deck/deck_structs"
+	"tui-deck/utils"
+)
+
+const dateFormat = "2006-01-02T15:04:05+00:00"
+const uidPrefix = "deck-card-"
+
+var configuration utils.Configuration
+
+func Init(conf utils.Configuration) {
+	configuration = conf
+}
+
+func Export(names []string, file string, todo bool) (int, error) {
+	boards, err := getBoards(names)
+	if err != nil {
+		return 0, err
+	}
+
+	calendar := ical.NewCalendar()
+	calendar.Props.SetText(ical.PropProductID, "-//tui-deck//tui-deck//EN")
+	calendar.Props.SetText(ical.PropVersion, "2.0")
+
+	now := time.Now()
+	for _, board := range boards {
+		cards, err := getCards(board)
+		if err != nil {
+			return 0, fmt.Errorf("error getting cards of board %s: %s", board.Title, err.Error())
+		}
+		for _, card := range cards {
+			if len(card.DueDate) == 0 {
+				continue
+			}
+			component, err := getComponent(board, card, todo, now)
+			if err != nil {
+				return 0, err
+			}
+			calendar.Children = append(calendar.Children, component)
+		}
+	}
+	if len(calendar.Children) == 0 {
+		return 0, fmt.Errorf("no cards with due date")
+	}
+
+	f, err := utils.CreateFile(file)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	err = ical.NewEncoder(f).Encode(calendar)
+	if err != nil {
+		return 0, err
+	}
+	return len(calendar.Children), nil
+}
+
+func getBoards(names []string) ([]deck_structs.Board, error) {
+	boards, err := deck_http.GetBoards(configuration)
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		return boards, nil
+	}
+
+	selected := make([]deck_structs.Board, 0)
+	for _, name := range names {
+		found := false
+		for _, b := range boards {
+			if strings.EqualFold(b.Title, name) || strconv.Itoa(b.Id) == name {
+				selected = append(selected, b)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown board %s", name)
+		}
+	}
+	return selected, nil
+}
+
+func getCards(board deck_structs.Board) ([]deck_structs.Card, error) {
+	stacks, err := deck_http.GetStacks(board.Id, configuration)
+	if err != nil {
+		return nil, err
+	}
+	archived, err := deck_http.GetArchivedStacks(board.Id, configuration)
+	if err != nil {
+		return nil, err
+	}
+	cards := make([]deck_structs.Card, 0)
+	for _, s := range append(stacks, archived...) {
+		cards = append(cards, s.Cards...)
+	}
+	return cards, nil
+}
+
+func getComponent(board deck_structs.Board, card deck_structs.Card, todo bool, now time.Time) (*ical.Component, error) {
+	due, err := time.Parse(dateFormat, card.DueDate)
+	if err != nil {
+		return nil, fmt.Errorf("card #%d: not a valid due date %s", card.Id, card.DueDate)
+	}
+
+	name := ical.CompEvent
+	if todo {
+		name = ical.CompToDo
+	}
+	component := ical.NewComponent(name)
+	component.Props.SetText(ical.PropUID, fmt.Sprintf("%s%d", uidPrefix, card.Id))
+	component.Props.SetDateTime(ical.PropDateTimeStamp, now.UTC())
+	component.Props.SetText(ical.PropSummary, card.Title)
+	if len(card.Description) > 0 {
+		component.Props.SetText(ical.PropDescription, card.Description)
+	}
+	if card.LastModified > 0 {
+		component.Props.SetDateTime(ical.PropLastModified, time.Unix(card.LastModified, 0).UTC())
+	}
+	cardUrl, err := url.Parse(utils.GetCardUrl(configuration.Url, board.Id, card.Id))
+	if err == nil {
+		component.Props.SetURI(ical.PropURL, cardUrl)
+	}
+	if len(card.Labels) > 0 {
+		labels := make([]string, 0)
+		for _, label := range card.Labels {
+			labels = append(labels, label.Title)
+		}
+		categories := ical.NewProp(ical.PropCategories)
+		categories.SetTextList(labels)
+		component.Props.Set(categories)
+	}
+
+	if todo {
+		component.Props.SetDateTime(ical.PropDue, due)
+		setCompleted(component, card.Archived)
+	} else {
+		component.Props.SetDateTime(ical.PropDateTimeStart, due)
+		component.Props.SetDateTime(ical.PropDateTimeEnd, due)
+	}
+	return component, nil
+}
+
+func setCompleted(component *ical.Component, completed bool) {
+	if completed {
+		component.Props.SetText(ical.PropStatus, "COMPLETED")
+	} else {
+		component.Props.SetText(ical.PropStatus, "NEEDS-ACTION")
+		component.Props.Del(ical.PropCompleted)
+	}
+}
+
+func Sync(names []string) ([]string, error) {
+	boards, err := getBoards(names)
+	if err != nil {
+		return nil, err
+	}
+	client, err := getClient()
+	if err != nil {
+		return nil, err
+	}
+	calendars, err := getCalendars(client)
+	if err != nil {
+		return nil, err
+	}
+
+	report := make([]string, 0)
+	errs := make([]string, 0)
+	for _, board := range boards {
+		calendar, found := findCalendar(calendars, board)
+		if !found {
+			errs = append(errs, fmt.Sprintf("%s: no calendar found", board.Title))
+			continue
+		}
+		boardReport, err := syncBoard(client, board, calendar)
+		for _, line := range boardReport {
+			report = append(report, fmt.Sprintf("%s: %s", board.Title, line))
+		}
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %s", board.Title, err.Error()))
+		}
+	}
+	if len(errs) > 0 {
+		return report, fmt.Errorf("error syncing calendars: %s", strings.Join(errs, ", "))
+	}
+	return report, nil
+}
+
+func getClient() (*caldav.Client, error) {
+	httpClient := webdav.HTTPClientWithBasicAuth(deck_http.GetClient(), configuration.User, configuration.Password)
+	return caldav.NewClient(httpClient, strings.TrimRight(configuration.Url, "/")+"/remote.php/dav")
+}
+
+func getCalendars(client *caldav.Client) ([]caldav.Calendar, error) {
+	homeSet := fmt.Sprintf("%s/remote.php/dav/calendars/%s/", getUrlPath(), configuration.User)
+	principal, err := client.FindCurrentUserPrincipal()
+	if err == nil {
+		found, err := client.FindCalendarHomeSet(principal)
+		if err == nil {
+			homeSet = found
+		}
+	}
+	calendars, err := client.FindCalendars(homeSet)
+	if err != nil {
+		return nil, fmt.Errorf("error finding calendars in %s: %s", homeSet, err.Error())
+	}
+	return calendars, nil
+}
+
+func getUrlPath() string {
+	u, err := url.Parse(configuration.Url)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimRight(u.Path, "/")
+}
+
+func findCalendar(calendars []caldav.Calendar, board deck_structs.Board) (caldav.Calendar, bool) {
+	suffix := fmt.Sprintf("deck--board-%d", board.Id)
+	for _, c := range calendars {
+		if strings.HasSuffix(strings.TrimRight(c.Path, "/"), suffix) {
+			return c, true
+		}
+	}
+	for _, c := range calendars {
+		if strings.EqualFold(c.Name, board.Title) {
+			return c, true
+		}
+	}
+	return caldav.Calendar{}, false
+}
+
+func syncBoard(client *caldav.Client, board deck_structs.Board, calendar caldav.Calendar) ([]string, error) {
+	cards, err := getCards(board)
+	if err != nil {
+		return nil, err
+	}
+	objects, err := client.QueryCalendar(calendar.Path, &caldav.CalendarQuery{
+		CompRequest: caldav.CalendarCompRequest{
+			Name:     ical.CompCalendar,
+			AllProps: true,
+			AllComps: true,
+		},
+		CompFilter: caldav.CompFilter{
+			Name:  ical.CompCalendar,
+			Comps: []caldav.CompFilter{{Name: ical.CompToDo}},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error reading calendar %s: %s", calendar.Path, err.Error())
+	}
+
+	todos := make(map[int]caldav.CalendarObject)
+	for _, object := range objects {
+		todo := getTodo(object)
+		if todo == nil {
+			continue
+		}
+		uid, _ := todo.Props.Text(ical.PropUID)
+		if !strings.HasPrefix(uid, uidPrefix) {
+			continue
+		}
+		id, err := strconv.Atoi(strings.TrimPrefix(uid, uidPrefix))
+		if err != nil {
+			continue
+		}
+		todos[id] = object
+	}
+
+	report := make([]string, 0)
+	errs := make([]string, 0)
+	now := time.Now()
+	for _, card := range cards {
+		object, found := todos[card.Id]
+		if !found {
+			if len(card.DueDate) == 0 {
+				continue
+			}
+			line, err := createTodo(client, board, calendar, card, now)
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("card #%d: %s", card.Id, err.Error()))
+				continue
+			}
+			report = append(report, line)
+			continue
+		}
+		line, err := syncCard(client, board, card, object, now)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("card #%d: %s", card.Id, err.Error()))
+			continue
+		}
+		if len(line) > 0 {
+			report = append(report, line)
+		}
+	}
+	if len(errs) > 0 {
+		return report, errors.New(strings.Join(errs, ", "))
+	}
+	return report, nil
+}
+
+func getTodo(object caldav.CalendarObject) *ical.Component {
+	if object.Data == nil {
+		return nil
+	}
+	for _, child := range object.Data.Children {
+		if child.Name == ical.CompToDo {
+			return child
+		}
+	}
+	return nil
+}
+
+func createTodo(client *caldav.Client, board deck_structs.Board, calendar caldav.Calendar, card deck_structs.Card, now time.Time) (string, error) {
+	component, err := getComponent(board, card, true, now)
+	if err != nil {
+		return "", err
+	}
+	data := ical.NewCalendar()
+	data.Props.SetText(ical.PropProductID, "-//tui-deck//tui-deck//EN")
+	data.Props.SetText(ical.PropVersion, "2.0")
+	data.Children = append(data.Children, component)
+
+	path := fmt.Sprintf("%s/%s%d.ics", strings.TrimRight(calendar.Path, "/"), uidPrefix, card.Id)
+	_, err = client.PutCalendarObject(path, data)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("card #%d %s added to calendar", card.Id, card.Title), nil
+}
+
+func syncCard(client *caldav.Client, board deck_structs.Board, card deck_structs.Card, object caldav.CalendarObject, now time.Time) (string, error) {
+	todo := getTodo(object)
+
+	var todoDue time.Time
+	if prop := todo.Props.Get(ical.PropDue); prop != nil {
+		due, err := prop.DateTime(time.Local)
+		if err != nil {
+			return "", err
+		}
+		todoDue = due.UTC().Truncate(time.Minute)
+	}
+	status, _ := todo.Props.Text(ical.PropStatus)
+	todoCompleted := strings.EqualFold(status, "COMPLETED") || todo.Props.Get(ical.PropCompleted) != nil
+
+	var cardDue time.Time
+	if len(card.DueDate) > 0 {
+		due, err := time.Parse(dateFormat, card.DueDate)
+		if err != nil {
+			return "", fmt.Errorf("not a valid due date %s", card.DueDate)
+		}
+		cardDue = due.UTC().Truncate(time.Minute)
+	}
+
+	if todoDue.Equal(cardDue) && todoCompleted == card.Archived {
+		return "", nil
+	}
+
+	todoModified := object.ModTime
+	if prop := todo.Props.Get(ical.PropLastModified); prop != nil {
+		modified, err := prop.DateTime(time.UTC)
+		if err == nil {
+			todoModified = modified
+		}
+	}
+	cardModified := time.Unix(card.LastModified, 0)
+
+	if todoModified.After(cardModified) {
+		return pullCard(board, card, todoDue, cardDue, todoCompleted)
+	}
+	return pushTodo(client, card, object, todo, cardDue, now)
+}
+
+func pullCard(board deck_structs.Board, card deck_structs.Card, todoDue time.Time, cardDue time.Time, completed bool) (string, error) {
+	changes := make([]string, 0)
+	if !todoDue.Equal(cardDue) {
+		dueDate := "null"
+		if !todoDue.IsZero() {
+			dueDate = fmt.Sprintf(`"%s"`, todoDue.Format(dateFormat))
+		}
+		jsonBody := fmt.Sprintf(`{"description": "%s", "title": "%s", "type": "plain", "owner":"%s", "duedate": %s}`,
+			utils.CleanText(card.Description), utils.CleanText(card.Title), configuration.User, dueDate)
+		_, err := deck_http.UpdateCard(board.Id, card.StackId, card.Id, jsonBody, configuration)
+		if err != nil {
+			return "", err
+		}
+		if todoDue.IsZero() {
+			changes = append(changes, "due date removed")
+		} else {
+			changes = append(changes, fmt.Sprintf("due date set to %s", todoDue.Format("02/01/2006 15:04")))
+		}
+	}
+	if completed && !card.Archived {
+		_, err := deck_http.ArchiveCard(board.Id, card.StackId, card.Id, configuration)
+		if err != nil {
+			return "", err
+		}
+		changes = append(changes, "archived")
+	}
+	if len(changes) == 0 {
+		return "", nil
+	}
+	return fmt.Sprintf("card #%d %s from calendar: %s", card.Id, card.Title, strings.Join(changes, ", ")), nil
+}
+
+func pushTodo(client *caldav.Client, card deck_structs.Card, object caldav.CalendarObject, todo *ical.Component, cardDue time.Time, now time.Time) (string, error) {
+	if cardDue.IsZero() {
+		todo.Props.Del(ical.PropDue)
+	} else {
+		todo.Props.SetDateTime(ical.PropDue, cardDue)
+	}
+	setCompleted(todo, card.Archived)
+	todo.Props.SetText(ical.PropSummary, card.Title)
+	todo.Props.SetDateTime(ical.PropDateTimeStamp, now.UTC())
+	todo.Props.SetDateTime(ical.PropLastModified, now.UTC())
+
+	_, err := client.PutCalendarObject(object.Path, object.Data)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("card #%d %s updated in calendar", card.Id, card.Title), nil
+}
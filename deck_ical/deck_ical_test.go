@@ -0,0 +1,253 @@
+package deck_ical
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/emersion/go-ical"
+	"github.com/emersion/go-webdav"
+	"github.com/emersion/go-webdav/caldav"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"tui-deck/deck_structs"
+	"tui-deck/utils"
+)
+
+const calendarPath = "/remote.php/dav/calendars/alice/deck--board-1/"
+
+type calendarBackend struct {
+	mutex   sync.Mutex
+	objects map[string]caldav.CalendarObject
+}
+
+func (b *calendarBackend) CurrentUserPrincipal(ctx context.Context) (string, error) {
+	return "/remote.php/dav/alice/", nil
+}
+
+func (b *calendarBackend) CalendarHomeSetPath(ctx context.Context) (string, error) {
+	return "/remote.php/dav/calendars/alice/", nil
+}
+
+func (b *calendarBackend) Calendar(ctx context.Context) (*caldav.Calendar, error) {
+	return &caldav.Calendar{Path: calendarPath, Name: "Team"}, nil
+}
+
+func (b *calendarBackend) GetCalendarObject(ctx context.Context, path string, req *caldav.CalendarCompRequest) (*caldav.CalendarObject, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	object, ok := b.objects[path]
+	if !ok {
+		return nil, webdav.NewHTTPError(http.StatusNotFound, fmt.Errorf("%s not found", path))
+	}
+	return &object, nil
+}
+
+func (b *calendarBackend) ListCalendarObjects(ctx context.Context, req *caldav.CalendarCompRequest) ([]caldav.CalendarObject, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	objects := make([]caldav.CalendarObject, 0)
+	for _, object := range b.objects {
+		objects = append(objects, object)
+	}
+	return objects, nil
+}
+
+func (b *calendarBackend) QueryCalendarObjects(ctx context.Context, query *caldav.CalendarQuery) ([]caldav.CalendarObject, error) {
+	objects, err := b.ListCalendarObjects(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	return caldav.Filter(query, objects)
+}
+
+func (b *calendarBackend) PutCalendarObject(ctx context.Context, path string, calendar *ical.Calendar, opts *caldav.PutCalendarObjectOptions) (string, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.objects[path] = caldav.CalendarObject{
+		Path:    path,
+		ModTime: time.Now(),
+		ETag:    strconv.FormatInt(time.Now().UnixNano(), 10),
+		Data:    calendar,
+	}
+	return path, nil
+}
+
+func (b *calendarBackend) DeleteCalendarObject(ctx context.Context, path string) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	delete(b.objects, path)
+	return nil
+}
+
+func (b *calendarBackend) getTodo(t *testing.T, cardId int) *ical.Component {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	object, ok := b.objects[fmt.Sprintf("%s%s%d.ics", calendarPath, uidPrefix, cardId)]
+	if !ok {
+		t.Fatalf("no todo for card #%d in calendar", cardId)
+	}
+	return getTodo(object)
+}
+
+func (b *calendarBackend) putTodo(cardId int, due time.Time, completed bool, modified time.Time) {
+	todo := ical.NewComponent(ical.CompToDo)
+	todo.Props.SetText(ical.PropUID, fmt.Sprintf("%s%d", uidPrefix, cardId))
+	todo.Props.SetDateTime(ical.PropDateTimeStamp, modified)
+	todo.Props.SetText(ical.PropSummary, fmt.Sprintf("card %d", cardId))
+	todo.Props.SetDateTime(ical.PropDue, due)
+	todo.Props.SetDateTime(ical.PropLastModified, modified)
+	setCompleted(todo, completed)
+
+	calendar := ical.NewCalendar()
+	calendar.Props.SetText(ical.PropProductID, "-//tui-deck//test//EN")
+	calendar.Props.SetText(ical.PropVersion, "2.0")
+	calendar.Children = append(calendar.Children, todo)
+	_, _ = b.PutCalendarObject(context.Background(), fmt.Sprintf("%s%s%d.ics", calendarPath, uidPrefix, cardId), calendar, nil)
+}
+
+type deckServer struct {
+	mutex         sync.Mutex
+	cards         []deck_structs.Card
+	archivedCards []deck_structs.Card
+	updates       map[int]string
+	archived      map[int]bool
+}
+
+func (d *deckServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	path := strings.TrimPrefix(r.URL.Path, "/index.php/apps/deck/api/v1.1")
+	switch {
+	case r.Method == http.MethodGet && path == "/boards":
+		_ = json.NewEncoder(w).Encode([]deck_structs.Board{{Id: 1, Title: "Team"}})
+	case r.Method == http.MethodGet && path == "/boards/1/stacks":
+		_ = json.NewEncoder(w).Encode([]deck_structs.Stack{{Id: 10, Title: "To do", Cards: d.cards}})
+	case r.Method == http.MethodGet && path == "/boards/1/stacks/archived":
+		_ = json.NewEncoder(w).Encode([]deck_structs.Stack{{Id: 10, Title: "To do", Cards: d.archivedCards}})
+	case r.Method == http.MethodPut && strings.HasSuffix(path, "/archive"):
+		var cardId int
+		_, _ = fmt.Sscanf(path, "/boards/1/stacks/10/cards/%d/archive", &cardId)
+		d.archived[cardId] = true
+		_ = json.NewEncoder(w).Encode(deck_structs.Card{Id: cardId, Archived: true})
+	case r.Method == http.MethodPut:
+		var cardId int
+		_, _ = fmt.Sscanf(path, "/boards/1/stacks/10/cards/%d", &cardId)
+		body, _ := io.ReadAll(r.Body)
+		d.updates[cardId] = string(body)
+		_ = json.NewEncoder(w).Encode(deck_structs.Card{Id: cardId})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestSync(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Minute)
+	due := now.Add(24 * time.Hour)
+	moved := now.Add(48 * time.Hour)
+
+	backend := &calendarBackend{objects: make(map[string]caldav.CalendarObject)}
+	deck := &deckServer{
+		cards: []deck_structs.Card{
+			{Id: 1, Title: "create", StackId: 10, DueDate: due.Format(dateFormat), LastModified: now.Add(-time.Hour).Unix()},
+			{Id: 2, Title: "pull", StackId: 10, DueDate: due.Format(dateFormat), LastModified: now.Add(-time.Hour).Unix()},
+			{Id: 3, Title: "push", StackId: 10, DueDate: moved.Format(dateFormat), LastModified: now.Add(-time.Hour).Unix()},
+			{Id: 4, Title: "complete", StackId: 10, DueDate: due.Format(dateFormat), LastModified: now.Add(-time.Hour).Unix()},
+			{Id: 5, Title: "no due date", StackId: 10, LastModified: now.Add(-time.Hour).Unix()},
+			{Id: 6, Title: "unchanged", StackId: 10, DueDate: due.Format(dateFormat), LastModified: now.Add(-time.Hour).Unix()},
+		},
+		archivedCards: []deck_structs.Card{
+			{Id: 7, Title: "archived", StackId: 10, DueDate: due.Format(dateFormat), Archived: true, LastModified: now.Unix()},
+		},
+		updates:  make(map[int]string),
+		archived: make(map[int]bool),
+	}
+	backend.putTodo(2, moved, false, now)
+	backend.putTodo(3, due, false, now.Add(-2*time.Hour))
+	backend.putTodo(4, due, true, now)
+	backend.putTodo(6, due, false, now)
+	backend.putTodo(7, due, false, now.Add(-time.Hour))
+
+	mux := http.NewServeMux()
+	mux.Handle("/remote.php/dav/", &caldav.Handler{Backend: backend, Prefix: "/remote.php/dav"})
+	mux.Handle("/index.php/apps/deck/", deck)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	Init(utils.Configuration{Url: server.URL, User: "alice", Password: "secret"})
+
+	report, err := Sync(nil)
+	if err != nil {
+		t.Fatalf("sync failed: %s", err)
+	}
+	if len(report) != 5 {
+		t.Errorf("expected 5 report lines, got %d: %v", len(report), report)
+	}
+
+	t.Run("create", func(t *testing.T) {
+		todo := backend.getTodo(t, 1)
+		summary, _ := todo.Props.Text(ical.PropSummary)
+		if summary != "create" {
+			t.Errorf("summary %q, want %q", summary, "create")
+		}
+		todoDue, err := todo.Props.DateTime(ical.PropDue, time.UTC)
+		if err != nil || !todoDue.Equal(due) {
+			t.Errorf("due %s, want %s", todoDue, due)
+		}
+	})
+
+	t.Run("pull", func(t *testing.T) {
+		body, ok := deck.updates[2]
+		if !ok {
+			t.Fatalf("card #2 not updated")
+		}
+		if !strings.Contains(body, moved.Format(dateFormat)) {
+			t.Errorf("card #2 updated with %s, want due date %s", body, moved.Format(dateFormat))
+		}
+	})
+
+	t.Run("push", func(t *testing.T) {
+		if _, ok := deck.updates[3]; ok {
+			t.Errorf("card #3 updated, want calendar updated")
+		}
+		todoDue, err := backend.getTodo(t, 3).Props.DateTime(ical.PropDue, time.UTC)
+		if err != nil || !todoDue.Equal(moved) {
+			t.Errorf("due %s, want %s", todoDue, moved)
+		}
+	})
+
+	t.Run("complete", func(t *testing.T) {
+		if !deck.archived[4] {
+			t.Errorf("card #4 not archived")
+		}
+		if _, ok := deck.updates[4]; ok {
+			t.Errorf("card #4 updated, want only archived")
+		}
+	})
+
+	t.Run("archived", func(t *testing.T) {
+		if _, ok := deck.updates[7]; ok {
+			t.Errorf("card #7 updated, want calendar updated")
+		}
+		status, _ := backend.getTodo(t, 7).Props.Text(ical.PropStatus)
+		if status != "COMPLETED" {
+			t.Errorf("status %q, want COMPLETED", status)
+		}
+	})
+
+	t.Run("skip", func(t *testing.T) {
+		for _, cardId := range []int{5, 6} {
+			if _, ok := deck.updates[cardId]; ok || deck.archived[cardId] {
+				t.Errorf("card #%d changed", cardId)
+			}
+		}
+		if _, ok := backend.objects[fmt.Sprintf("%s%s5.ics", calendarPath, uidPrefix)]; ok {
+			t.Errorf("todo created for card #5 without due date")
+		}
+	})
+}
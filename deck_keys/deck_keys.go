@@ -0,0 +1,525 @@
+package deck_keys
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"os"
+	"strings"
+	"time"
+	"tui-deck/utils"
+)
+
+const (
+	Main          = "main"
+	Card          = "card"
+	Edit          = "edit"
+	Labels        = "labels"
+	Users         = "users"
+	Comments      = "comments"
+	Boards        = "boards"
+	BoardLabels   = "board-labels"
+	Notifications = "notifications"
+	History       = "history"
+	Stats         = "stats"
+	Table         = "table"
+	Activity      = "activity"
+)
+
+const sequenceTimeout = time.Second
+
+type Context struct {
+	Name  string
+	Title string
+}
+
+type Action struct {
+	Name        string
+	Context     string
+	Description string
+	Keys        []string
+	handler     func()
+}
+
+type keymap struct {
+	Preset   string              `json:"preset"`
+	Bindings map[string][]string `json:"bindings"`
+}
+
+var Contexts = []Context{
+	{Main, "Main"},
+	{Card, "View Card"},
+	{Edit, "Edit Card"},
+	{Labels, "Edit Card Labels"},
+	{Users, "Edit Card Users"},
+	{Comments, "View Comments"},
+	{Boards, "Switch Boards"},
+	{BoardLabels, "Edit Board Labels"},
+	{Notifications, "Notifications"},
+	{History, "History"},
+	{Stats, "Stats"},
+	{Table, "Card Table"},
+	{Activity, "Activity"},
+}
+
+var defaults = []Action{
+	{Name: "main.down", Context: Main, Description: "Move down.", Keys: []string{"down"}},
+	{Name: "main.up", Context: Main, Description: "Move up.", Keys: []string{"up"}},
+	{Name: "main.top", Context: Main, Description: "Go to first card.", Keys: []string{"home"}},
+	{Name: "main.bottom", Context: Main, Description: "Go to last card.", Keys: []string{"end"}},
+	{Name: "main.next-stack", Context: Main, Description: "Switch to next stack.", Keys: []string{"tab", "right"}},
+	{Name: "main.previous-stack", Context: Main, Description: "Switch to previous stack.", Keys: []string{"backtab", "left"}},
+	{Name: "main.move-card-next", Context: Main, Description: "Move card to next stack.", Keys: []string{"shift+right", ">"}},
+	{Name: "main.move-card-previous", Context: Main, Description: "Move card to previous stack.", Keys: []string{"shift+left", "<"}},
+	{Name: "main.next-lane", Context: Main, Description: "Switch to next swimlane.", Keys: []string{"ctrl+down"}},
+	{Name: "main.previous-lane", Context: Main, Description: "Switch to previous swimlane.", Keys: []string{"ctrl+up"}},
+	{Name: "main.move-card-next-lane", Context: Main, Description: "Move card to next swimlane.", Keys: []string{"shift+down"}},
+	{Name: "main.move-card-previous-lane", Context: Main, Description: "Move card to previous swimlane.", Keys: []string{"shift+up"}},
+	{Name: "main.open-card", Context: Main, Description: "Select card.", Keys: []string{"enter"}},
+	{Name: "main.switch-board", Context: Main, Description: "Switch board.", Keys: []string{"s"}},
+	{Name: "main.notifications", Context: Main, Description: "View notifications.", Keys: []string{"n"}},
+	{Name: "main.reload", Context: Main, Description: "Reload board.", Keys: []string{"r"}},
+	{Name: "main.add-card", Context: Main, Description: "Add card to current stack.", Keys: []string{"a"}},
+	{Name: "main.add-card-template", Context: Main, Description: "Add card from template.", Keys: []string{"A"}},
+	{Name: "main.delete-card", Context: Main, Description: "Delete selected card in current stack.", Keys: []string{"d"}},
+	{Name: "main.add-stack", Context: Main, Description: "Add stack.", Keys: []string{"ctrl+a"}},
+	{Name: "main.delete-stack", Context: Main, Description: "Delete current stack.", Keys: []string{"ctrl+d"}},
+	{Name: "main.edit-stack", Context: Main, Description: "Edit current stack.", Keys: []string{"ctrl+e"}},
+	{Name: "main.mark", Context: Main, Description: "Mark or unmark card for bulk actions.", Keys: []string{"space"}},
+	{Name: "main.clear-marks", Context: Main, Description: "Unmark all cards.", Keys: []string{"esc"}},
+	{Name: "main.bulk", Context: Main, Description: "Bulk actions on marked cards.", Keys: []string{"b"}},
+	{Name: "main.undo", Context: Main, Description: "Undo last change.", Keys: []string{"u"}},
+	{Name: "main.redo", Context: Main, Description: "Redo last undone change.", Keys: []string{"ctrl+r"}},
+	{Name: "main.history", Context: Main, Description: "View undo history.", Keys: []string{"U"}},
+	{Name: "main.stats", Context: Main, Description: "View board statistics.", Keys: []string{"S"}},
+	{Name: "main.table", Context: Main, Description: "View all cards in a table.", Keys: []string{"t"}},
+	{Name: "main.activity", Context: Main, Description: "View board activity.", Keys: []string{"y"}},
+	{Name: "main.layout", Context: Main, Description: "Switch between stack and swimlane layouts.", Keys: []string{"v"}},
+	{Name: "main.quit", Context: Main, Description: "Quit app.", Keys: []string{"q"}},
+	{Name: "main.help", Context: Main, Description: "Help.", Keys: []string{"?"}},
+	{Name: "main.palette", Context: Main, Description: "Command palette.", Keys: []string{":", "ctrl+p"}},
+
+	{Name: "card.edit-description", Context: Card, Description: "Edit card description.", Keys: []string{"e"}},
+	{Name: "card.edit-description-external", Context: Card, Description: "Edit card description in $EDITOR.", Keys: []string{"E"}},
+	{Name: "card.labels", Context: Card, Description: "Edit card labels.", Keys: []string{"l"}},
+	{Name: "card.users", Context: Card, Description: "Edit card users.", Keys: []string{"u"}},
+	{Name: "card.edit-details", Context: Card, Description: "Edit card title, due date and order.", Keys: []string{"t"}},
+	{Name: "card.comments", Context: Card, Description: "View comments.", Keys: []string{"c"}},
+	{Name: "card.next-checklist-item", Context: Card, Description: "Select next checklist item.", Keys: []string{"tab"}},
+	{Name: "card.previous-checklist-item", Context: Card, Description: "Select previous checklist item.", Keys: []string{"backtab"}},
+	{Name: "card.toggle-checklist-item", Context: Card, Description: "Toggle selected checklist item.", Keys: []string{"space"}},
+	{Name: "card.link-hints", Context: Card, Description: "Number links in description and comments, then type a number and ENTER to open it or y to copy it.", Keys: []string{"f"}},
+	{Name: "card.activity", Context: Card, Description: "View card activity.", Keys: []string{"y"}},
+	{Name: "card.open-browser", Context: Card, Description: "Open card in the browser.", Keys: []string{"o"}},
+	{Name: "card.back", Context: Card, Description: "Back to main view.", Keys: []string{"esc"}},
+	{Name: "card.help", Context: Card, Description: "Help.", Keys: []string{"?"}},
+	{Name: "card.palette", Context: Card, Description: "Command palette.", Keys: []string{":", "ctrl+p"}},
+
+	{Name: "edit.save", Context: Edit, Description: "Save card.", Keys: []string{"f2"}},
+	{Name: "edit.toggle-preview", Context: Edit, Description: "Toggle Markdown preview.", Keys: []string{"f3"}},
+	{Name: "edit.back", Context: Edit, Description: "Back to card view.", Keys: []string{"esc"}},
+	{Name: "edit.palette", Context: Edit, Description: "Command palette.", Keys: []string{"ctrl+p"}},
+
+	{Name: "labels.down", Context: Labels, Description: "Move down.", Keys: []string{"down"}},
+	{Name: "labels.up", Context: Labels, Description: "Move up.", Keys: []string{"up"}},
+	{Name: "labels.switch-list", Context: Labels, Description: "Switch between card labels and available labels list.", Keys: []string{"tab"}},
+	{Name: "labels.back", Context: Labels, Description: "Back to card view.", Keys: []string{"esc"}},
+	{Name: "labels.help", Context: Labels, Description: "Help.", Keys: []string{"?"}},
+	{Name: "labels.palette", Context: Labels, Description: "Command palette.", Keys: []string{":", "ctrl+p"}},
+
+	{Name: "users.down", Context: Users, Description: "Move down.", Keys: []string{"down"}},
+	{Name: "users.up", Context: Users, Description: "Move up.", Keys: []string{"up"}},
+	{Name: "users.switch-list", Context: Users, Description: "Switch between card users and available users list.", Keys: []string{"tab"}},
+	{Name: "users.back", Context: Users, Description: "Back to card view.", Keys: []string{"esc"}},
+	{Name: "users.help", Context: Users, Description: "Help.", Keys: []string{"?"}},
+	{Name: "users.palette", Context: Users, Description: "Command palette.", Keys: []string{":", "ctrl+p"}},
+
+	{Name: "comments.focus-message", Context: Comments, Description: "Switch between comments tree and selected comment.", Keys: []string{"tab"}},
+	{Name: "comments.add", Context: Comments, Description: "Add comment.", Keys: []string{"a"}},
+	{Name: "comments.add-external", Context: Comments, Description: "Add comment in $EDITOR.", Keys: []string{"A"}},
+	{Name: "comments.reply", Context: Comments, Description: "Reply comment.", Keys: []string{"r"}},
+	{Name: "comments.edit", Context: Comments, Description: "Edit comment.", Keys: []string{"e"}},
+	{Name: "comments.delete", Context: Comments, Description: "Delete comment.", Keys: []string{"d"}},
+	{Name: "comments.back", Context: Comments, Description: "Back to card view.", Keys: []string{"esc"}},
+	{Name: "comments.help", Context: Comments, Description: "Help.", Keys: []string{"?"}},
+	{Name: "comments.palette", Context: Comments, Description: "Command palette.", Keys: []string{":", "ctrl+p"}},
+
+	{Name: "boards.down", Context: Boards, Description: "Move down.", Keys: []string{"down"}},
+	{Name: "boards.up", Context: Boards, Description: "Move up.", Keys: []string{"up"}},
+	{Name: "boards.select", Context: Boards, Description: "Select board.", Keys: []string{"enter"}},
+	{Name: "boards.add", Context: Boards, Description: "Add board.", Keys: []string{"a"}},
+	{Name: "boards.edit", Context: Boards, Description: "Edit board.", Keys: []string{"e"}},
+	{Name: "boards.delete", Context: Boards, Description: "Delete board.", Keys: []string{"d"}},
+	{Name: "boards.labels", Context: Boards, Description: "Edit board labels.", Keys: []string{"t"}},
+	{Name: "boards.back", Context: Boards, Description: "Back to main view.", Keys: []string{"esc"}},
+	{Name: "boards.help", Context: Boards, Description: "Help.", Keys: []string{"?"}},
+	{Name: "boards.palette", Context: Boards, Description: "Command palette.", Keys: []string{":", "ctrl+p"}},
+
+	{Name: "board-labels.down", Context: BoardLabels, Description: "Move down.", Keys: []string{"down"}},
+	{Name: "board-labels.up", Context: BoardLabels, Description: "Move up.", Keys: []string{"up"}},
+	{Name: "board-labels.add", Context: BoardLabels, Description: "Add label.", Keys: []string{"a"}},
+	{Name: "board-labels.edit", Context: BoardLabels, Description: "Edit label.", Keys: []string{"e"}},
+	{Name: "board-labels.back", Context: BoardLabels, Description: "Back to boards.", Keys: []string{"esc"}},
+	{Name: "board-labels.help", Context: BoardLabels, Description: "Help.", Keys: []string{"?"}},
+	{Name: "board-labels.palette", Context: BoardLabels, Description: "Command palette.", Keys: []string{":", "ctrl+p"}},
+
+	{Name: "notifications.down", Context: Notifications, Description: "Move down.", Keys: []string{"down"}},
+	{Name: "notifications.up", Context: Notifications, Description: "Move up.", Keys: []string{"up"}},
+	{Name: "notifications.open", Context: Notifications, Description: "Jump to the referenced card.", Keys: []string{"enter"}},
+	{Name: "notifications.dismiss", Context: Notifications, Description: "Dismiss notification.", Keys: []string{"d"}},
+	{Name: "notifications.dismiss-all", Context: Notifications, Description: "Dismiss all notifications.", Keys: []string{"ctrl+d"}},
+	{Name: "notifications.reload", Context: Notifications, Description: "Reload notifications.", Keys: []string{"r"}},
+	{Name: "notifications.back", Context: Notifications, Description: "Back to main view.", Keys: []string{"esc"}},
+	{Name: "notifications.help", Context: Notifications, Description: "Help.", Keys: []string{"?"}},
+	{Name: "notifications.palette", Context: Notifications, Description: "Command palette.", Keys: []string{":", "ctrl+p"}},
+
+	{Name: "history.down", Context: History, Description: "Move down.", Keys: []string{"down"}},
+	{Name: "history.up", Context: History, Description: "Move up.", Keys: []string{"up"}},
+	{Name: "history.undo", Context: History, Description: "Undo last change.", Keys: []string{"u"}},
+	{Name: "history.redo", Context: History, Description: "Redo last undone change.", Keys: []string{"ctrl+r"}},
+	{Name: "history.back", Context: History, Description: "Back to main view.", Keys: []string{"esc"}},
+	{Name: "history.help", Context: History, Description: "Help.", Keys: []string{"?"}},
+	{Name: "history.palette", Context: History, Description: "Command palette.", Keys: []string{":", "ctrl+p"}},
+
+	{Name: "stats.chart", Context: Stats, Description: "Switch between burndown and cumulative flow chart.", Keys: []string{"c"}},
+	{Name: "stats.back", Context: Stats, Description: "Back to main view.", Keys: []string{"esc"}},
+	{Name: "stats.help", Context: Stats, Description: "Help.", Keys: []string{"?"}},
+	{Name: "stats.palette", Context: Stats, Description: "Command palette.", Keys: []string{":", "ctrl+p"}},
+
+	{Name: "table.open", Context: Table, Description: "Open selected card.", Keys: []string{"enter"}},
+	{Name: "table.filter", Context: Table, Description: "Edit filter expression.", Keys: []string{"/"}},
+	{Name: "table.clear-filter", Context: Table, Description: "Clear filter expression.", Keys: []string{"ctrl+l"}},
+	{Name: "table.sort", Context: Table, Description: "Sort by next column.", Keys: []string{"s"}},
+	{Name: "table.reverse", Context: Table, Description: "Reverse sort order.", Keys: []string{"r"}},
+	{Name: "table.back", Context: Table, Description: "Back to main view.", Keys: []string{"esc"}},
+	{Name: "table.help", Context: Table, Description: "Help.", Keys: []string{"?"}},
+	{Name: "table.palette", Context: Table, Description: "Command palette.", Keys: []string{":", "ctrl+p"}},
+
+	{Name: "activity.down", Context: Activity, Description: "Move down.", Keys: []string{"down"}},
+	{Name: "activity.up", Context: Activity, Description: "Move up.", Keys: []string{"up"}},
+	{Name: "activity.open", Context: Activity, Description: "Jump to the referenced card.", Keys: []string{"enter"}},
+	{Name: "activity.more", Context: Activity, Description: "Load older activity.", Keys: []string{"m"}},
+	{Name: "activity.reload", Context: Activity, Description: "Reload activity.", Keys: []string{"r"}},
+	{Name: "activity.back", Context: Activity, Description: "Back to previous view.", Keys: []string{"esc"}},
+	{Name: "activity.help", Context: Activity, Description: "Help.", Keys: []string{"?"}},
+	{Name: "activity.palette", Context: Activity, Description: "Command palette.", Keys: []string{":", "ctrl+p"}},
+}
+
+var presets = map[string]map[string][]string{
+	"default": {},
+	"vim": {
+		"main.down":                    {"j", "down"},
+		"main.up":                      {"k", "up"},
+		"main.top":                     {"g g", "home"},
+		"main.bottom":                  {"G", "end"},
+		"main.next-stack":              {"l", "tab", "right"},
+		"main.previous-stack":          {"h", "backtab", "left"},
+		"main.move-card-next":          {"L", "shift+right"},
+		"main.move-card-previous":      {"H", "shift+left"},
+		"main.move-card-next-lane":     {"J", "shift+down"},
+		"main.move-card-previous-lane": {"K", "shift+up"},
+		"main.delete-card":             {"d d"},
+		"card.back":                    {"esc", "q"},
+		"labels.down":                  {"j", "down"},
+		"labels.up":                    {"k", "up"},
+		"users.down":                   {"j", "down"},
+		"users.up":                     {"k", "up"},
+		"comments.delete":              {"d d"},
+		"boards.down":                  {"j", "down"},
+		"boards.up":                    {"k", "up"},
+		"boards.delete":                {"d d"},
+		"board-labels.down":            {"j", "down"},
+		"board-labels.up":              {"k", "up"},
+		"notifications.down":           {"j", "down"},
+		"notifications.up":             {"k", "up"},
+		"notifications.dismiss":        {"d d"},
+		"notifications.back":           {"esc", "q"},
+		"notifications.dismiss-all":    {"D"},
+		"history.down":                 {"j", "down"},
+		"history.up":                   {"k", "up"},
+		"history.back":                 {"esc", "q"},
+		"stats.back":                   {"esc", "q"},
+		"table.back":                   {"esc", "q"},
+		"activity.down":                {"j", "down"},
+		"activity.up":                  {"k", "up"},
+		"activity.back":                {"esc", "q"},
+	},
+}
+
+var actions = make([]*Action, 0)
+var actionsMap = make(map[string]*Action)
+
+var pending = make([]string, 0)
+var pendingContext = ""
+var timer *time.Timer
+
+var app *tview.Application
+var configuration utils.Configuration
+
+func Init(application *tview.Application, conf utils.Configuration) error {
+	app = application
+	configuration = conf
+
+	keys := keymap{Preset: "default", Bindings: map[string][]string{}}
+	var err error
+	keymapFile := configuration.ConfigDir + "/keymap.json"
+	if utils.Exists(keymapFile) {
+		keys, err = readKeymap(keymapFile)
+	}
+
+	preset, ok := presets[keys.Preset]
+	if !ok {
+		preset = presets["default"]
+		err = fmt.Errorf("unknown keymap preset %s, using default", keys.Preset)
+	}
+
+	actions = make([]*Action, 0)
+	actionsMap = make(map[string]*Action)
+	for _, d := range defaults {
+		action := d
+		if presetKeys, ok := preset[action.Name]; ok {
+			action.Keys = presetKeys
+		}
+		if userKeys, ok := keys.Bindings[action.Name]; ok {
+			action.Keys = userKeys
+		}
+		action.Keys = normalizeKeys(action.Keys)
+		actions = append(actions, &action)
+		actionsMap[action.Name] = &action
+	}
+	for name := range keys.Bindings {
+		if _, ok := actionsMap[name]; !ok {
+			err = fmt.Errorf("unknown action %s in keymap", name)
+		}
+	}
+
+	for _, context := range Contexts {
+		setListNavigation(context.Name)
+	}
+	return err
+}
+
+func SetHandler(name string, handler func()) {
+	action, ok := actionsMap[name]
+	if !ok {
+		panic(fmt.Sprintf("unknown action %s", name))
+	}
+	action.handler = handler
+}
+
+func GetActions(context string) []*Action {
+	contextActions := make([]*Action, 0)
+	for _, action := range actions {
+		if action.Context == context {
+			contextActions = append(contextActions, action)
+		}
+	}
+	return contextActions
+}
+
+func GetAction(name string) *Action {
+	return actionsMap[name]
+}
+
+func (action *Action) Run() bool {
+	if action.handler == nil {
+		return false
+	}
+	action.handler()
+	return true
+}
+
+func FirstKey(name string) string {
+	action, ok := actionsMap[name]
+	if !ok || len(action.Keys) == 0 {
+		return ""
+	}
+	return action.Keys[0]
+}
+
+func (action *Action) Available() bool {
+	return action.handler != nil
+}
+
+func (action *Action) FormatKeys() string {
+	return strings.Join(action.Keys, ", ")
+}
+
+func Capture(context string) func(event *tcell.EventKey) *tcell.EventKey {
+	return func(event *tcell.EventKey) *tcell.EventKey {
+		return Handle(context, event)
+	}
+}
+
+func Handle(context string, event *tcell.EventKey) *tcell.EventKey {
+	if timer != nil {
+		timer.Stop()
+		timer = nil
+	}
+	if pendingContext != context {
+		pending = make([]string, 0)
+		pendingContext = context
+	}
+
+	sequence := append(pending, KeyName(event))
+	exact, prefix := match(context, sequence)
+	if prefix {
+		pending = sequence
+		if exact != nil {
+			timer = time.AfterFunc(sequenceTimeout, func() {
+				app.QueueUpdateDraw(func() {
+					if len(pending) == len(sequence) && pendingContext == context {
+						pending = make([]string, 0)
+						exact.Run()
+					}
+				})
+			})
+		}
+		return nil
+	}
+
+	pending = make([]string, 0)
+	if exact != nil && exact.Run() {
+		return nil
+	}
+	if len(sequence) > 1 {
+		return Handle(context, event)
+	}
+	return event
+}
+
+func KeyName(event *tcell.EventKey) string {
+	if event.Key() == tcell.KeyRune {
+		name := string(event.Rune())
+		if event.Rune() == ' ' {
+			name = "space"
+		}
+		if event.Modifiers()&tcell.ModAlt != 0 {
+			name = "alt+" + name
+		}
+		return name
+	}
+
+	name, ok := tcell.KeyNames[event.Key()]
+	if !ok {
+		return fmt.Sprintf("key%d", event.Key())
+	}
+	name = strings.ToLower(name)
+	if strings.HasPrefix(name, "ctrl-") {
+		return "ctrl+" + strings.TrimPrefix(name, "ctrl-")
+	}
+	if event.Modifiers()&tcell.ModCtrl != 0 {
+		name = "ctrl+" + name
+	}
+	if event.Modifiers()&tcell.ModAlt != 0 {
+		name = "alt+" + name
+	}
+	if event.Modifiers()&tcell.ModShift != 0 {
+		name = "shift+" + name
+	}
+	return name
+}
+
+func match(context string, sequence []string) (*Action, bool) {
+	joined := strings.Join(sequence, " ")
+	var exact *Action
+	prefix := false
+	for _, action := range actions {
+		if action.Context != context {
+			continue
+		}
+		for _, key := range action.Keys {
+			if key == joined {
+				exact = action
+			} else if strings.HasPrefix(key, joined+" ") {
+				prefix = true
+			}
+		}
+	}
+	return exact, prefix
+}
+
+func setListNavigation(context string) {
+	if action, ok := actionsMap[context+".down"]; ok {
+		action.handler = func() { moveList(1) }
+	}
+	if action, ok := actionsMap[context+".up"]; ok {
+		action.handler = func() { moveList(-1) }
+	}
+	if action, ok := actionsMap[context+".top"]; ok {
+		action.handler = func() { setListItem(0) }
+	}
+	if action, ok := actionsMap[context+".bottom"]; ok {
+		action.handler = func() { setListItem(-1) }
+	}
+}
+
+func moveList(delta int) {
+	list, ok := app.GetFocus().(*tview.List)
+	if !ok || list.GetItemCount() == 0 {
+		return
+	}
+	index := list.GetCurrentItem() + delta
+	if index < 0 {
+		index = list.GetItemCount() - 1
+	} else if index >= list.GetItemCount() {
+		index = 0
+	}
+	list.SetCurrentItem(index)
+}
+
+func setListItem(index int) {
+	list, ok := app.GetFocus().(*tview.List)
+	if !ok || list.GetItemCount() == 0 {
+		return
+	}
+	list.SetCurrentItem(index)
+}
+
+func normalizeKeys(keys []string) []string {
+	normalized := make([]string, 0)
+	for _, key := range keys {
+		tokens := make([]string, 0)
+		for _, token := range strings.Fields(key) {
+			tokens = append(tokens, normalizeToken(token))
+		}
+		if len(tokens) > 0 {
+			normalized = append(normalized, strings.Join(tokens, " "))
+		}
+	}
+	return normalized
+}
+
+func normalizeToken(token string) string {
+	if len([]rune(token)) == 1 {
+		return token
+	}
+	token = strings.ToLower(token)
+	switch token {
+	case "escape":
+		return "esc"
+	case "return":
+		return "enter"
+	case "shift+tab":
+		return "backtab"
+	}
+	return token
+}
+
+func readKeymap(keymapFile string) (keymap, error) {
+	keys := keymap{Preset: "default", Bindings: map[string][]string{}}
+	file, err := os.Open(keymapFile)
+	if err != nil {
+		return keys, err
+	}
+	defer file.Close()
+	err = json.NewDecoder(file).Decode(&keys)
+	if err != nil {
+		return keymap{Preset: "default", Bindings: map[string][]string{}}, fmt.Errorf("error reading keymap %s: %s", keymapFile, err.Error())
+	}
+	if len(keys.Preset) == 0 {
+		keys.Preset = "default"
+	}
+	return keys, nil
+}
@@ -2,120 +2,516 @@ package deck_markdown
 
 import (
 	"fmt"
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
 	"github.com/rivo/tview"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	east "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
 	"regexp"
+	"sort"
 	"strings"
+	"tui-deck/deck_theme"
 	"tui-deck/utils"
 )
 
-func GetMarkDownDescription(text string, configuration utils.Configuration) string {
-	var lineContainer = ""
-	var isCodeBlock = false
-	for _, line := range strings.Split(text, "\n") {
-		if strings.HasPrefix(line, "#") || strings.HasPrefix(line, "##") || strings.HasPrefix(line, "###") {
-			// # heading
-			line = fmt.Sprintf("[:%s:b]%s[-:-:-]\n", configuration.Color, strings.ReplaceAll(line, "#", "")[1:])
-		} else if strings.Contains(line, "- [ ]") {
-			// task list unselected
-			line = fmt.Sprintf("%s\n", strings.ReplaceAll(line, "- [ ]", "[ ]"))
-		} else if strings.Contains(line, "- [x]") {
-			// task list selected
-			line = fmt.Sprintf("%s\n", strings.ReplaceAll(line, "- [x]", "[✓]"))
-		} else if strings.HasPrefix(line, "- ") {
-			// unordered list
-			line = fmt.Sprintf("%s\n", strings.ReplaceAll(line, "- ", "• "))
-		} else if strings.HasPrefix(line, "> ") {
-			// Blockquotes
-			line = fmt.Sprintf("%s\n", strings.Replace(line, "> ", "│ ", 1))
-		} else if strings.HasPrefix(line, "```") {
-			//codeBlock
-			if !isCodeBlock {
-				line = fmt.Sprintf("[#af0000:#4e4e4e:-]%s\n", strings.ReplaceAll(line, "```", ""))
-				isCodeBlock = true
+var markdown = goldmark.New(goldmark.WithExtensions(extension.GFM))
+
+type style struct {
+	fg    string
+	bg    string
+	attrs string
+}
+
+type renderer struct {
+	source        []byte
+	configuration utils.Configuration
+	styles        []style
+	regions       bool
+	taskIndex     int
+	hints         bool
+	links         []string
+	mentions      map[string]string
+	mentionRegexp *regexp.Regexp
+}
+
+func GetMarkDownDescription(description string, configuration utils.Configuration) string {
+	result, _ := render(description, configuration, false, false)
+	return result
+}
+
+func GetMarkDownDescriptionWithCheckList(description string, configuration utils.Configuration) string {
+	result, _ := render(description, configuration, true, false)
+	return result
+}
+
+func GetMarkDownDescriptionWithLinkHints(description string, configuration utils.Configuration) (string, []string) {
+	return render(description, configuration, false, true)
+}
+
+func GetMarkDownDescriptionWithMentions(description string, configuration utils.Configuration, mentions map[string]string) string {
+	r := newRenderer(description, configuration, false, false)
+	if len(mentions) > 0 {
+		ids := make([]string, 0)
+		for id := range mentions {
+			ids = append(ids, regexp.QuoteMeta(id))
+		}
+		// longer ids first so that a user id never shadows another one it prefixes
+		sort.Slice(ids, func(i, j int) bool {
+			return len(ids[i]) > len(ids[j])
+		})
+		alternatives := strings.Join(ids, "|")
+		r.mentions = mentions
+		r.mentionRegexp = regexp.MustCompile(`@(?:"(` + alternatives + `)"|(` + alternatives + `))(\W|$)`)
+	}
+	return r.render()
+}
+
+func CheckListRegion(index int) string {
+	return fmt.Sprintf("task-%d", index)
+}
+
+func LinkHint(index int) string {
+	return fmt.Sprintf("[%s:%s:b] %d [-:-:-]", deck_theme.Current.Background, deck_theme.Current.Warning, index+1)
+}
+
+func GetLinks(description string) []string {
+	links := make([]string, 0)
+	source := []byte(description)
+	document := markdown.Parser().Parse(text.NewReader(source))
+	_ = ast.Walk(document, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := node.(type) {
+		case *ast.Link:
+			links = append(links, string(n.Destination))
+		case *ast.AutoLink:
+			links = append(links, string(n.URL(source)))
+		case *ast.Image:
+			links = append(links, string(n.Destination))
+		}
+		return ast.WalkContinue, nil
+	})
+	return links
+}
+
+func render(description string, configuration utils.Configuration, regions bool, hints bool) (string, []string) {
+	r := newRenderer(description, configuration, regions, hints)
+	return r.render(), r.links
+}
+
+func newRenderer(description string, configuration utils.Configuration, regions bool, hints bool) *renderer {
+	return &renderer{
+		source:        []byte(description),
+		configuration: configuration,
+		styles:        []style{{fg: "-", bg: "-", attrs: "-"}},
+		regions:       regions,
+		hints:         hints,
+		links:         make([]string, 0),
+	}
+}
+
+func (r *renderer) render() string {
+	document := markdown.Parser().Parse(text.NewReader(r.source))
+	return strings.TrimRight(r.renderBlocks(document, false), "\n")
+}
+
+func (s style) tag() string {
+	return fmt.Sprintf("[%s:%s:%s]", s.fg, s.bg, s.attrs)
+}
+
+func (r *renderer) push(s style) string {
+	current := r.styles[len(r.styles)-1]
+	if s.fg == "" {
+		s.fg = current.fg
+	}
+	if s.bg == "" {
+		s.bg = current.bg
+	}
+	if s.attrs == "" {
+		s.attrs = current.attrs
+	} else if current.attrs != "-" {
+		s.attrs = current.attrs + s.attrs
+	}
+	r.styles = append(r.styles, s)
+	return s.tag()
+}
+
+func (r *renderer) pop() string {
+	r.styles = r.styles[:len(r.styles)-1]
+	return r.styles[len(r.styles)-1].tag()
+}
+
+func (r *renderer) renderBlocks(node ast.Node, tight bool) string {
+	separator := "\n\n"
+	if tight {
+		separator = "\n"
+	}
+	blocks := make([]string, 0)
+	for child := node.FirstChild(); child != nil; child = child.NextSibling() {
+		blocks = append(blocks, r.renderBlock(child))
+	}
+	return strings.Join(blocks, separator)
+}
+
+func (r *renderer) renderBlock(node ast.Node) string {
+	switch n := node.(type) {
+	case *ast.Heading:
+		return r.push(style{bg: deck_theme.Current.Accent, attrs: "b"}) + r.renderInlines(n) + r.pop()
+	case *ast.Paragraph, *ast.TextBlock:
+		return r.renderInlines(n)
+	case *ast.ThematicBreak:
+		return strings.Repeat("─", 40)
+	case *ast.Blockquote:
+		return prefixLines(r.renderBlocks(n, false), "│ ", "│ ")
+	case *ast.List:
+		return r.renderList(n)
+	case *ast.FencedCodeBlock:
+		return r.renderCodeBlock(n)
+	case *ast.CodeBlock:
+		return r.renderCodeBlock(n)
+	case *ast.HTMLBlock:
+		return r.renderCodeBlock(n)
+	case *east.Table:
+		return r.renderTable(n)
+	}
+	return r.renderBlocks(node, false)
+}
+
+func (r *renderer) renderList(list *ast.List) string {
+	items := make([]string, 0)
+	index := list.Start
+	for item := list.FirstChild(); item != nil; item = item.NextSibling() {
+		marker := "• "
+		if list.IsOrdered() {
+			marker = fmt.Sprintf("%d. ", index)
+			index++
+		}
+		region := ""
+		if checkBox := getTaskCheckBox(item); checkBox != nil {
+			marker = tview.Escape("[ ] ")
+			if checkBox.IsChecked {
+				marker = "[✓] "
+			}
+			region = CheckListRegion(r.taskIndex)
+			r.taskIndex++
+		}
+		indent := strings.Repeat(" ", tview.TaggedStringWidth(marker))
+		content := prefixLines(r.renderBlocks(item, list.IsTight), marker, indent)
+		if r.regions && len(region) > 0 {
+			lines := strings.SplitN(content, "\n", 2)
+			lines[0] = fmt.Sprintf(`["%s"]%s[""]`, region, lines[0])
+			content = strings.Join(lines, "\n")
+		}
+		items = append(items, content)
+	}
+	separator := "\n"
+	if !list.IsTight {
+		separator = "\n\n"
+	}
+	return strings.Join(items, separator)
+}
+
+func (r *renderer) renderCodeBlock(node ast.Node) string {
+	lines := node.Lines()
+	code := make([]string, 0)
+	for i := 0; i < lines.Len(); i++ {
+		line := lines.At(i)
+		code = append(code, strings.TrimRight(string(line.Value(r.source)), "\n"))
+	}
+	if fenced, ok := node.(*ast.FencedCodeBlock); ok {
+		highlighted, err := r.highlight(strings.Join(code, "\n"), string(fenced.Language(r.source)))
+		if err == nil {
+			return highlighted
+		}
+	}
+	for i, line := range code {
+		code[i] = r.push(style{fg: deck_theme.Current.CodeText, bg: deck_theme.Current.CodeBackground}) + tview.Escape(line) + r.pop()
+	}
+	return strings.Join(code, "\n")
+}
+
+func (r *renderer) highlight(code string, language string) (string, error) {
+	if len(language) == 0 {
+		return "", fmt.Errorf("no language")
+	}
+	if len(deck_theme.Current.CodeStyle) == 0 {
+		return "", fmt.Errorf("no code style")
+	}
+	lexer := lexers.Get(language)
+	if lexer == nil {
+		return "", fmt.Errorf("unknown language %s", language)
+	}
+	codeStyle := styles.Get(deck_theme.Current.CodeStyle)
+	iterator, err := chroma.Coalesce(lexer).Tokenise(nil, code)
+	if err != nil {
+		return "", err
+	}
+
+	background := deck_theme.Current.CodeBackground
+	if entry := codeStyle.Get(chroma.Background); entry.Background.IsSet() {
+		background = entry.Background.String()
+	}
+
+	lines := []string{""}
+	for _, token := range iterator.Tokens() {
+		entry := codeStyle.Get(token.Type)
+		foreground := "-"
+		if entry.Colour.IsSet() {
+			foreground = entry.Colour.String()
+		}
+		attrs := ""
+		if entry.Bold == chroma.Yes {
+			attrs = attrs + "b"
+		}
+		if entry.Italic == chroma.Yes {
+			attrs = attrs + "i"
+		}
+		if entry.Underline == chroma.Yes {
+			attrs = attrs + "u"
+		}
+		if len(attrs) == 0 {
+			attrs = "-"
+		}
+		tag := fmt.Sprintf("[%s:%s:%s]", foreground, background, attrs)
+		for i, part := range strings.Split(token.Value, "\n") {
+			if i > 0 {
+				lines = append(lines, "")
+			}
+			if len(part) > 0 {
+				lines[len(lines)-1] = lines[len(lines)-1] + tag + tview.Escape(part)
+			}
+		}
+	}
+	// lexers terminate the code with a new line
+	if len(lines) > 1 && len(lines[len(lines)-1]) == 0 {
+		lines = lines[:len(lines)-1]
+	}
+	for i, line := range lines {
+		lines[i] = fmt.Sprintf("[-:%s:-]%s[-:-:-]", background, line)
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func (r *renderer) renderTable(table *east.Table) string {
+	rows := make([][]string, 0)
+	widths := make([]int, 0)
+	for row := table.FirstChild(); row != nil; row = row.NextSibling() {
+		cells := make([]string, 0)
+		for cell := row.FirstChild(); cell != nil; cell = cell.NextSibling() {
+			content := ""
+			if _, ok := row.(*east.TableHeader); ok {
+				content = r.push(style{attrs: "b"}) + r.renderInlines(cell) + r.pop()
 			} else {
-				line = fmt.Sprintf("[-:-:-]%s\n", strings.ReplaceAll(line, "```", ""))
+				content = r.renderInlines(cell)
 			}
-		} else if len(checkLink(line)) > 0 {
-			result := checkLink(line)
-			for _, r := range result {
-				rTmp := strings.ReplaceAll(r[1], "[", "")
-				r[1] = strings.ReplaceAll(rTmp, "]", "")
-				line = fmt.Sprintf("%s", strings.ReplaceAll(line, r[0], fmt.Sprintf("[#00ffaf]%s [#5f5fff:-:u]%s[-:-:-]", strings.ReplaceAll(r[1], "[", ""), r[2])))
+			column := len(cells)
+			if column == len(widths) {
+				widths = append(widths, 0)
 			}
-		} else {
-			line = fmt.Sprintf("%s\n", tview.Escape(line))
+			if width := tview.TaggedStringWidth(content); width > widths[column] {
+				widths[column] = width
+			}
+			cells = append(cells, content)
 		}
+		rows = append(rows, cells)
+	}
 
-		// bold + italic
-		result := getStringInBetween(line, "\\*\\*\\*", "\\*\\*\\*")
-		if len(result) > 0 {
-			for _, r := range result {
-				line = fmt.Sprintf("%s", strings.ReplaceAll(line, r[1], fmt.Sprintf("[::bi]%s[::-]", r[2])))
+	lines := make([]string, 0)
+	for i, cells := range rows {
+		padded := make([]string, 0)
+		for column, content := range cells {
+			alignment := east.AlignNone
+			if column < len(table.Alignments) {
+				alignment = table.Alignments[column]
 			}
-			line = fmt.Sprintf("%s", strings.ReplaceAll(line, "***", ""))
+			padded = append(padded, pad(content, widths[column], alignment))
 		}
-		// bold
-		result = getStringInBetween(line, "\\*\\*", "\\*\\*")
-		if len(result) > 0 {
-			for _, r := range result {
-				line = fmt.Sprintf("%s", strings.ReplaceAll(line, r[1], fmt.Sprintf("[::b]%s[::-]", r[2])))
+		lines = append(lines, strings.Join(padded, " │ "))
+		if i == 0 {
+			separators := make([]string, 0)
+			for _, width := range widths {
+				separators = append(separators, strings.Repeat("─", width))
 			}
-			line = fmt.Sprintf("%s", strings.ReplaceAll(line, "**", ""))
+			lines = append(lines, strings.Join(separators, "─┼─"))
 		}
-		// italic
-		result = getStringInBetween(line, "\\*", "\\*")
-		if len(result) > 0 {
+	}
+	return strings.Join(lines, "\n")
+}
 
-			for _, r := range result {
-				line = fmt.Sprintf("%s", strings.ReplaceAll(line, r[1], fmt.Sprintf("[::i]%s[::-]", r[2])))
-			}
-			line = fmt.Sprintf("%s", strings.ReplaceAll(line, "*", ""))
+func (r *renderer) renderInlines(node ast.Node) string {
+	result := ""
+	for child := node.FirstChild(); child != nil; child = child.NextSibling() {
+		result = result + r.renderInline(child)
+	}
+	return result
+}
+
+func (r *renderer) renderInline(node ast.Node) string {
+	switch n := node.(type) {
+	case *ast.Text:
+		value := n.Segment.Value(r.source)
+		if !n.IsRaw() {
+			value = util.ResolveEntityNames(util.ResolveNumericReferences(util.UnescapePunctuations(value)))
+		}
+		result := r.renderText(string(value))
+		if n.SoftLineBreak() || n.HardLineBreak() {
+			result = result + "\n"
 		}
-		// code inline
-		result = getStringInBetween(line, "\\`", "\\`")
-		if len(result) > 0 {
-			for _, r := range result {
-				line = fmt.Sprintf("%s", strings.ReplaceAll(line, r[1], fmt.Sprintf("[#af0000:#4e4e4e:-] %s [::-]", r[2])))
+		return result
+	case *ast.String:
+		return tview.Escape(string(n.Value))
+	case *ast.CodeSpan:
+		code := ""
+		for child := n.FirstChild(); child != nil; child = child.NextSibling() {
+			if t, ok := child.(*ast.Text); ok {
+				code = code + string(t.Segment.Value(r.source))
 			}
-			line = fmt.Sprintf("%s", strings.ReplaceAll(line, "`", ""))
 		}
+		return r.push(style{fg: deck_theme.Current.CodeText, bg: deck_theme.Current.CodeBackground}) + " " + tview.Escape(code) + " " + r.pop()
+	case *ast.Emphasis:
+		attrs := "i"
+		if n.Level == 2 {
+			attrs = "b"
+		}
+		return r.push(style{attrs: attrs}) + r.renderInlines(n) + r.pop()
+	case *east.Strikethrough:
+		return r.push(style{attrs: "s"}) + r.renderInlines(n) + r.pop()
+	case *ast.Link:
+		return r.renderLink(r.renderInlines(n), string(n.Destination))
+	case *ast.AutoLink:
+		return r.renderLink("", string(n.URL(r.source)))
+	case *ast.Image:
+		return r.renderLink("!"+r.renderInlines(n), string(n.Destination))
+	case *ast.RawHTML:
+		raw := ""
+		for i := 0; i < n.Segments.Len(); i++ {
+			segment := n.Segments.At(i)
+			raw = raw + string(segment.Value(r.source))
+		}
+		return tview.Escape(raw)
+	case *east.TaskCheckBox:
+		return ""
+	}
+	return r.renderInlines(node)
+}
 
-		lineContainer = lineContainer + fmt.Sprintf("%s", line)
+func (r *renderer) renderText(value string) string {
+	if r.mentionRegexp == nil {
+		return tview.Escape(value)
 	}
+	result := ""
+	last := 0
+	for _, match := range r.mentionRegexp.FindAllStringSubmatchIndex(value, -1) {
+		id := ""
+		if match[2] >= 0 {
+			id = value[match[2]:match[3]]
+		} else {
+			id = value[match[4]:match[5]]
+		}
+		result = result + tview.Escape(value[last:match[0]]) +
+			r.push(style{fg: deck_theme.Current.Accent, attrs: "b"}) + "@" + tview.Escape(r.mentions[id]) + r.pop()
+		last = match[6]
+	}
+	return result + tview.Escape(value[last:])
+}
 
-	return lineContainer
+func (r *renderer) renderLink(label string, url string) string {
+	link := ""
+	if r.hints {
+		link = r.push(style{}) + LinkHint(len(r.links)) + r.pop() + " "
+		r.links = append(r.links, url)
+	}
+	if len(label) > 0 && label != tview.Escape(url) {
+		link += r.push(style{fg: deck_theme.Current.LinkText}) + label + r.pop() + " "
+	}
+	return link + r.push(style{fg: deck_theme.Current.LinkUrl, attrs: "u"}) + tview.Escape(url) + r.pop()
 }
 
-func getStringInBetween(str string, start string, end string) (result [][]string) {
-	re := regexp.MustCompile("(" + start + `(.*?)` + end + ")")
-	match := re.FindAllStringSubmatch(str, -1)
-	if len(match) > 0 {
-		return match
-	} else {
-		return
+func getTaskCheckBox(item ast.Node) *east.TaskCheckBox {
+	block := item.FirstChild()
+	if block == nil {
+		return nil
+	}
+	checkBox, ok := block.FirstChild().(*east.TaskCheckBox)
+	if !ok {
+		return nil
 	}
+	return checkBox
 }
 
-func checkLink(str string) (result [][]string) {
-	re := regexp.MustCompile(`(\[[^][]+])\((https?://[^()]+)\)`)
-	match := re.FindAllStringSubmatch(str, -1)
-	if len(match) > 0 {
-		return match
-	} else {
-		return
+func prefixLines(text string, first string, rest string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if i == 0 {
+			lines[i] = first + line
+		} else if len(line) > 0 {
+			lines[i] = rest + line
+		}
 	}
+	return strings.Join(lines, "\n")
 }
 
-func CountCheckList(description string) (int, int, error) {
-	re := regexp.MustCompile("- \\[ \\]")
-	matchUncheckd := re.FindAllStringSubmatch(description, -1)
+func pad(content string, width int, alignment east.Alignment) string {
+	padding := width - tview.TaggedStringWidth(content)
+	switch alignment {
+	case east.AlignRight:
+		return strings.Repeat(" ", padding) + content
+	case east.AlignCenter:
+		return strings.Repeat(" ", padding/2) + content + strings.Repeat(" ", padding-padding/2)
+	}
+	return content + strings.Repeat(" ", padding)
+}
 
-	re = regexp.MustCompile("- \\[x\\]")
-	matchChecked := re.FindAllStringSubmatch(description, -1)
+func CountCheckList(description string) (int, int, error) {
+	checked := 0
+	checkBoxes := getTaskCheckBoxes([]byte(description))
+	for _, checkBox := range checkBoxes {
+		if checkBox.IsChecked {
+			checked++
+		}
+	}
 
-	if len(matchChecked) > 0 || len(matchUncheckd) > 0 {
-		return len(matchChecked), len(matchUncheckd) + len(matchChecked), nil
+	if len(checkBoxes) > 0 {
+		return checked, len(checkBoxes), nil
 	}
 	return 0, 0, fmt.Errorf("no check list found")
 
 }
+
+func ToggleCheckListItem(description string, index int) (string, error) {
+	source := []byte(description)
+	checkBoxes := getTaskCheckBoxes(source)
+	if index < 0 || index >= len(checkBoxes) {
+		return description, fmt.Errorf("check list item %d not found", index)
+	}
+	block := checkBoxes[index].Parent()
+	if block.Lines().Len() == 0 {
+		return description, fmt.Errorf("check list item %d not found", index)
+	}
+	// block lines start at the opening bracket of the check box
+	position := block.Lines().At(0).Start + 1
+	if checkBoxes[index].IsChecked {
+		source[position] = ' '
+	} else {
+		source[position] = 'x'
+	}
+	return string(source), nil
+}
+
+func getTaskCheckBoxes(source []byte) []*east.TaskCheckBox {
+	checkBoxes := make([]*east.TaskCheckBox, 0)
+	document := markdown.Parser().Parse(text.NewReader(source))
+	_ = ast.Walk(document, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if checkBox, ok := node.(*east.TaskCheckBox); ok && entering {
+			checkBoxes = append(checkBoxes, checkBox)
+		}
+		return ast.WalkContinue, nil
+	})
+	return checkBoxes
+}
@@ -0,0 +1,191 @@
+package deck_markdown
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"tui-deck/deck_theme"
+	"tui-deck/utils"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+func TestGetMarkDownDescription(t *testing.T) {
+	tests := []string{
+		"table",
+		"ordered_list",
+		"nested_list",
+		"strikethrough",
+		"autolink",
+		"horizontal_rule",
+		"code_block_heading",
+		"escaped_asterisk",
+		"inline_code_asterisk",
+	}
+	for _, name := range tests {
+		t.Run(name, func(t *testing.T) {
+			input, err := os.ReadFile(filepath.Join("testdata", name+".md"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := GetMarkDownDescription(string(input), utils.Configuration{})
+
+			golden := filepath.Join("testdata", name+".golden")
+			if *update {
+				if err := os.WriteFile(golden, []byte(got), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != string(want) {
+				t.Errorf("rendered output mismatch\ngot:\n%s\nwant:\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestGetMarkDownDescriptionWithLinkHints(t *testing.T) {
+	got, links := GetMarkDownDescriptionWithLinkHints("[docs](https://example.com/docs) and https://example.com", utils.Configuration{})
+	if len(links) != 2 || links[0] != "https://example.com/docs" || links[1] != "https://example.com" {
+		t.Fatalf("unexpected links %v", links)
+	}
+	first := strings.Index(got, LinkHint(0))
+	if first < 0 || first > strings.Index(got, "docs") {
+		t.Errorf("hint 1 missing before the link label in %q", got)
+	}
+	if !strings.Contains(got, LinkHint(1)) {
+		t.Errorf("hint 2 missing from %q", got)
+	}
+}
+
+func TestToggleCheckListItem(t *testing.T) {
+	tests := []struct {
+		name        string
+		description string
+		index       int
+		want        string
+	}{
+		{
+			name:        "check",
+			description: "- [ ] one\n- [ ] two\n",
+			index:       1,
+			want:        "- [ ] one\n- [x] two\n",
+		},
+		{
+			name:        "uncheck",
+			description: "- [x] one\n- [x] two\n",
+			index:       0,
+			want:        "- [ ] one\n- [x] two\n",
+		},
+		{
+			name:        "nested",
+			description: "- [ ] parent\n  - [ ] child\n  - [x] other\n- [ ] next\n",
+			index:       1,
+			want:        "- [ ] parent\n  - [x] child\n  - [x] other\n- [ ] next\n",
+		},
+		{
+			name:        "ordered",
+			description: "1. [ ] first\n2. [ ] second\n10. [x] tenth\n",
+			index:       2,
+			want:        "1. [ ] first\n2. [ ] second\n10. [ ] tenth\n",
+		},
+		{
+			name:        "blockquote",
+			description: "> - [ ] quoted\n> - [ ] other\n\n- [ ] outside\n",
+			index:       1,
+			want:        "> - [ ] quoted\n> - [x] other\n\n- [ ] outside\n",
+		},
+		{
+			name:        "crlf",
+			description: "- [ ] one\r\n- [ ] two\r\n- [ ] three\r\n",
+			index:       2,
+			want:        "- [ ] one\r\n- [ ] two\r\n- [x] three\r\n",
+		},
+		{
+			name:        "brackets in text",
+			description: "- [ ] see [ ] and [x]\n- [ ] two\n",
+			index:       0,
+			want:        "- [x] see [ ] and [x]\n- [ ] two\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := ToggleCheckListItem(test.description, test.index)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestToggleCheckListItemOutOfRange(t *testing.T) {
+	description := "- [ ] one\n"
+	got, err := ToggleCheckListItem(description, 1)
+	if err == nil {
+		t.Errorf("expected an error for a missing item")
+	}
+	if got != description {
+		t.Errorf("description changed: %q", got)
+	}
+}
+
+func TestGetMarkDownDescriptionWithMentions(t *testing.T) {
+	accent := deck_theme.Current.Accent
+	deck_theme.Current.Accent = "yellow"
+	defer func() {
+		deck_theme.Current.Accent = accent
+	}()
+	mentions := map[string]string{"alice": "Alice A", "al": "Al", "bob smith": "Bob"}
+	tests := []struct {
+		name        string
+		description string
+		want        string
+	}{
+		{
+			name:        "plain",
+			description: "hi @alice.",
+			want:        "hi [yellow:-:b]@Alice A[-:-:-].",
+		},
+		{
+			name:        "inside bold",
+			description: "**hi @alice there**",
+			want:        "[-:-:b]hi [yellow:-:bb]@Alice A[-:-:b] there[-:-:-]",
+		},
+		{
+			name:        "inside italic",
+			description: "_see @al_",
+			want:        "[-:-:i]see [yellow:-:ib]@Al[-:-:i][-:-:-]",
+		},
+		{
+			name:        "quoted",
+			description: `ping @"bob smith" now`,
+			want:        "ping [yellow:-:b]@Bob[-:-:-] now",
+		},
+		{
+			name:        "unknown and partial",
+			description: "@carol @alicex",
+			want:        "@carol @alicex",
+		},
+		{
+			name:        "code span",
+			description: "`@alice`",
+			want:        "[" + deck_theme.Current.CodeText + ":" + deck_theme.Current.CodeBackground + ":-] @alice [-:-:-]",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := GetMarkDownDescriptionWithMentions(test.description, utils.Configuration{}, mentions)
+			if got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,252 @@
+package deck_notification
+
+import (
+	"fmt"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"regexp"
+	"strconv"
+	"time"
+	"tui-deck/deck_board"
+	"tui-deck/deck_card"
+	"tui-deck/deck_http"
+	"tui-deck/deck_keys"
+	"tui-deck/deck_structs"
+	"tui-deck/deck_theme"
+	"tui-deck/deck_ui"
+	"tui-deck/utils"
+)
+
+var NotificationFlex *tview.Flex
+var NotificationList *tview.List
+var Modal *tview.Modal
+
+var Notifications []deck_structs.Notification
+
+var app *tview.Application
+var configuration utils.Configuration
+
+func Init(application *tview.Application, conf utils.Configuration) {
+	app = application
+	configuration = conf
+
+	NotificationFlex = tview.NewFlex()
+	NotificationList = tview.NewList()
+	Modal = tview.NewModal()
+
+	NotificationList.SetBorder(true)
+	NotificationList.SetTitle(" Notifications ")
+
+	NotificationFlex.AddItem(NotificationList, 0, 1, true)
+
+	applyTheme()
+	deck_theme.OnChange(func() {
+		applyTheme()
+		buildNotificationList()
+	})
+}
+
+func applyTheme() {
+	deck_theme.StyleBox(NotificationFlex.Box)
+	deck_theme.StyleBox(NotificationList.Box)
+	deck_theme.StyleList(NotificationList)
+	deck_theme.StyleModal(Modal)
+}
+
+func GetNotifications() error {
+	notifications, err := deck_http.GetNotifications(configuration)
+	if err != nil {
+		return err
+	}
+	Notifications = make([]deck_structs.Notification, 0)
+	for _, n := range notifications {
+		if n.App == "deck" {
+			Notifications = append(Notifications, n)
+		}
+	}
+	return nil
+}
+
+func BuildNotifications() {
+	err := GetNotifications()
+	if err != nil {
+		deck_ui.FooterBar.SetText(fmt.Sprintf("Error getting notifications: %s", err.Error()))
+		return
+	}
+	buildNotificationList()
+
+	NotificationList.SetInputCapture(deck_keys.Capture(deck_keys.Notifications))
+	deck_keys.SetHandler("notifications.back", func() {
+		deck_ui.BuildFullFlex(deck_ui.MainFlex, nil)
+	})
+	deck_keys.SetHandler("notifications.open", func() {
+		if len(Notifications) == 0 {
+			return
+		}
+		jumpToCard(Notifications[NotificationList.GetCurrentItem()])
+	})
+	deck_keys.SetHandler("notifications.dismiss-all", func() {
+		if len(Notifications) == 0 {
+			return
+		}
+		dismissAll()
+	})
+	deck_keys.SetHandler("notifications.dismiss", func() {
+		if len(Notifications) == 0 {
+			return
+		}
+		dismiss(NotificationList.GetCurrentItem())
+	})
+	deck_keys.SetHandler("notifications.reload", func() {
+		err := GetNotifications()
+		if err != nil {
+			deck_ui.FooterBar.SetText(fmt.Sprintf("Error getting notifications: %s", err.Error()))
+			return
+		}
+		buildNotificationList()
+	})
+	deck_keys.SetHandler("notifications.help", func() {
+		deck_ui.BuildHelp(NotificationFlex, deck_keys.Notifications)
+	})
+
+	NotificationList.SetSelectedFunc(func(index int, name string, secondName string, shortcut rune) {
+		jumpToCard(Notifications[index])
+	})
+
+	deck_ui.BuildFullFlex(NotificationFlex, nil)
+}
+
+func buildNotificationList() {
+	NotificationList.Clear()
+	NotificationList.SetTitle(fmt.Sprintf(" Notifications (%d) ", len(Notifications)))
+	for _, n := range Notifications {
+		NotificationList.AddItem(fmt.Sprintf("[%s]#%d[-] - %s", deck_theme.Current.Accent, n.NotificationId, tview.Escape(n.Subject)),
+			fmt.Sprintf("[-:-:i]%s[-:-:-] %s", getDate(n), tview.Escape(n.Message)), rune(0), nil)
+	}
+}
+
+func dismiss(index int) {
+	notification := Notifications[index]
+	go func() {
+		_, err := deck_http.DeleteNotification(notification.NotificationId, configuration)
+		app.QueueUpdateDraw(func() {
+			if err != nil {
+				deck_ui.FooterBar.SetText(fmt.Sprintf("Error dismissing notification #%d: %s", notification.NotificationId, err.Error()))
+				return
+			}
+			removeNotifications(map[int]bool{notification.NotificationId: true})
+		})
+	}()
+}
+
+func removeNotifications(dismissed map[int]bool) {
+	current := NotificationList.GetCurrentItem()
+	remaining := make([]deck_structs.Notification, 0)
+	for _, n := range Notifications {
+		if !dismissed[n.NotificationId] {
+			remaining = append(remaining, n)
+		}
+	}
+	Notifications = remaining
+	buildNotificationList()
+	if current >= len(Notifications) {
+		current = len(Notifications) - 1
+	}
+	if current >= 0 {
+		NotificationList.SetCurrentItem(current)
+	}
+}
+
+func dismissAll() {
+	Modal.ClearButtons()
+	Modal.SetText(fmt.Sprintf("Are you sure to dismiss %d notifications?", len(Notifications)))
+	deck_theme.StyleModal(Modal)
+
+	Modal.AddButtons([]string{"Yes", "No"})
+
+	Modal.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyEsc {
+			NotificationFlex.RemoveItem(Modal)
+			app.SetFocus(NotificationList)
+		}
+		if event.Key() == tcell.KeyRight || event.Key() == tcell.KeyLeft || event.Key() == tcell.KeyEnter {
+			return event
+		}
+		return nil
+	})
+
+	Modal.SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+		if buttonLabel == "Yes" {
+			pending := Notifications
+			go func() {
+				dismissed := make(map[int]bool)
+				failed := 0
+				var lastErr error
+				for _, n := range pending {
+					_, err := deck_http.DeleteNotification(n.NotificationId, configuration)
+					if err != nil {
+						failed++
+						lastErr = err
+						continue
+					}
+					dismissed[n.NotificationId] = true
+				}
+				app.QueueUpdateDraw(func() {
+					removeNotifications(dismissed)
+					if failed > 0 {
+						deck_ui.FooterBar.SetText(fmt.Sprintf("Error dismissing %d of %d notifications: %s", failed, len(pending), lastErr.Error()))
+					}
+				})
+			}()
+			NotificationFlex.RemoveItem(Modal)
+			app.SetFocus(NotificationList)
+		} else if buttonLabel == "No" {
+			NotificationFlex.RemoveItem(Modal)
+			app.SetFocus(NotificationList)
+		}
+	})
+
+	NotificationFlex.AddItem(Modal, 0, 0, false)
+	app.SetFocus(Modal)
+}
+
+func jumpToCard(notification deck_structs.Notification) {
+	if notification.ObjectType != "card" {
+		deck_ui.FooterBar.SetText(fmt.Sprintf("Notification #%d does not reference a card", notification.NotificationId))
+		return
+	}
+	cardId, err := strconv.Atoi(notification.ObjectId)
+	if err != nil {
+		deck_ui.FooterBar.SetText(fmt.Sprintf("Not a valid card id: %s", notification.ObjectId))
+		return
+	}
+
+	boardId := getBoardId(notification.Link)
+	if boardId != 0 && boardId != deck_board.CurrentBoard.Id {
+		err = deck_board.SwitchBoard(boardId)
+		if err != nil {
+			deck_ui.FooterBar.SetText(fmt.Sprintf("Error switching board: %s", err.Error()))
+			return
+		}
+	}
+
+	err = deck_card.OpenCard(cardId)
+	if err != nil {
+		deck_ui.FooterBar.SetText(err.Error())
+	}
+}
+
+func getBoardId(link string) int {
+	re := regexp.MustCompile(`/board/(\d+)`)
+	match := re.FindStringSubmatch(link)
+	if len(match) < 2 {
+		return 0
+	}
+	boardId, _ := strconv.Atoi(match[1])
+	return boardId
+}
+
+func getDate(notification deck_structs.Notification) string {
+	parse, _ := time.Parse("2006-01-02T15:04:05+00:00", notification.Datetime)
+	return parse.Format("15:04:05 - 2006-01-02")
+}
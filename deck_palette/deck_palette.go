@@ -0,0 +1,480 @@
+package deck_palette
+
+import (
+	"fmt"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"sort"
+	"strings"
+	"time"
+	"tui-deck/deck_board"
+	"tui-deck/deck_card"
+	"tui-deck/deck_keys"
+	"tui-deck/deck_stack"
+	"tui-deck/deck_structs"
+	"tui-deck/deck_template"
+	"tui-deck/deck_theme"
+	"tui-deck/deck_ui"
+	"tui-deck/utils"
+)
+
+type Entry struct {
+	Title   string
+	Keys    string
+	Run     func()
+	Choices func() []Entry
+}
+
+var PaletteFlex *tview.Flex
+var QueryInput *tview.InputField
+var ResultList *tview.List
+
+var entries []Entry
+var matches []Entry
+var providers = make(map[string]func() []Entry)
+
+var returnPrimitive tview.Primitive
+var returnFocus tview.Primitive
+var selectedCard deck_structs.Card
+var cardSelected bool
+
+var app *tview.Application
+var configuration utils.Configuration
+
+func Init(application *tview.Application, conf utils.Configuration) {
+	app = application
+	configuration = conf
+
+	PaletteFlex = tview.NewFlex()
+	QueryInput = tview.NewInputField()
+	ResultList = tview.NewList()
+
+	PaletteFlex.SetDirection(tview.FlexRow)
+	PaletteFlex.SetBorder(true)
+	PaletteFlex.AddItem(QueryInput, 1, 0, true)
+	PaletteFlex.AddItem(ResultList, 0, 1, false)
+
+	QueryInput.SetLabel("> ")
+	QueryInput.SetChangedFunc(filter)
+	QueryInput.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyEsc:
+			closePalette()
+			return nil
+		case tcell.KeyEnter:
+			selectEntry()
+			return nil
+		case tcell.KeyDown, tcell.KeyTab, tcell.KeyCtrlN:
+			moveSelection(1)
+			return nil
+		case tcell.KeyUp, tcell.KeyBacktab, tcell.KeyCtrlP:
+			moveSelection(-1)
+			return nil
+		}
+		return event
+	})
+	ResultList.ShowSecondaryText(false)
+
+	applyTheme()
+	deck_theme.OnChange(applyTheme)
+
+	for _, c := range deck_keys.Contexts {
+		context := c.Name
+		deck_keys.SetHandler(context+".palette", func() {
+			Open(context)
+		})
+	}
+
+	deck_keys.SetHandler("main.bulk", func() {
+		cards := deck_card.GetMarkedCards()
+		if len(cards) == 0 {
+			deck_ui.FooterBar.SetText(fmt.Sprintf("No cards marked, press %s to mark a card",
+				deck_theme.Key(deck_keys.FirstKey("main.mark"))))
+			return
+		}
+		OpenEntries(fmt.Sprintf(" BULK - %d CARDS ", len(cards)), getBulkEntries(cards))
+	})
+
+	deck_keys.SetHandler("main.add-card-template", func() {
+		choices := getTemplateChoices()
+		if len(choices) == 0 {
+			return
+		}
+		OpenEntries(" ADD CARD FROM TEMPLATE ", choices)
+	})
+
+	providers[deck_keys.Main] = getMainEntries
+	providers[deck_keys.Card] = getCardEntries
+}
+
+func applyTheme() {
+	deck_theme.StyleBox(PaletteFlex.Box)
+	deck_theme.StyleBox(QueryInput.Box)
+	QueryInput.SetLabelColor(deck_theme.Accent())
+	QueryInput.SetFieldBackgroundColor(deck_theme.GetColor(deck_theme.Current.FieldBackground))
+	QueryInput.SetFieldTextColor(deck_theme.GetColor(deck_theme.Current.FieldText))
+	deck_theme.StyleList(ResultList)
+}
+
+func Open(context string) {
+	entries := make([]Entry, 0)
+	for _, action := range deck_keys.GetActions(context) {
+		if !action.Available() || strings.HasSuffix(action.Name, ".palette") {
+			continue
+		}
+		a := action
+		entries = append(entries, Entry{Title: a.Description, Keys: a.FormatKeys(), Run: func() {
+			a.Run()
+		}})
+	}
+	if provider, ok := providers[context]; ok {
+		entries = append(entries, provider()...)
+	}
+
+	title := context
+	for _, c := range deck_keys.Contexts {
+		if c.Name == context {
+			title = c.Title
+		}
+	}
+	OpenEntries(fmt.Sprintf(" %s - COMMANDS ", title), entries)
+}
+
+func OpenEntries(title string, paletteEntries []Entry) {
+	returnPrimitive = deck_ui.GetCurrentPrimitive()
+	returnFocus = app.GetFocus()
+	selectedCard, cardSelected = deck_card.GetListCard(returnFocus)
+	entries = paletteEntries
+	show(title)
+}
+
+func show(title string) {
+	PaletteFlex.SetTitle(title)
+	deck_ui.BuildFullFlex(PaletteFlex, nil)
+	QueryInput.SetText("")
+	filter("")
+	app.SetFocus(QueryInput)
+}
+
+func filter(query string) {
+	type scored struct {
+		entry Entry
+		score int
+	}
+	results := make([]scored, 0)
+	for _, entry := range entries {
+		score, ok := fuzzyScore(query, entry.Title)
+		if ok {
+			results = append(results, scored{entry, score})
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].score > results[j].score
+	})
+
+	matches = make([]Entry, 0)
+	ResultList.Clear()
+	for _, r := range results {
+		matches = append(matches, r.entry)
+		text := tview.Escape(r.entry.Title)
+		if len(r.entry.Keys) > 0 {
+			text = fmt.Sprintf("%s [%s](%s)[-]", text, deck_theme.Current.Muted, tview.Escape(r.entry.Keys))
+		}
+		ResultList.AddItem(text, "", rune(0), nil)
+	}
+}
+
+func fuzzyScore(query string, text string) (int, bool) {
+	q := []rune(strings.ToLower(strings.TrimSpace(query)))
+	t := []rune(strings.ToLower(text))
+	score := 0
+	last := -2
+	qi := 0
+	for ti := 0; ti < len(t) && qi < len(q); ti++ {
+		if q[qi] == ' ' {
+			qi++
+			continue
+		}
+		if t[ti] != q[qi] {
+			continue
+		}
+		score++
+		if ti == last+1 {
+			score += 2
+		}
+		if ti == 0 || t[ti-1] == ' ' || t[ti-1] == '-' {
+			score += 3
+		}
+		last = ti
+		qi++
+	}
+	if qi < len(q) {
+		return 0, false
+	}
+	return score, true
+}
+
+func moveSelection(delta int) {
+	if ResultList.GetItemCount() == 0 {
+		return
+	}
+	index := ResultList.GetCurrentItem() + delta
+	if index < 0 {
+		index = ResultList.GetItemCount() - 1
+	} else if index >= ResultList.GetItemCount() {
+		index = 0
+	}
+	ResultList.SetCurrentItem(index)
+}
+
+func selectEntry() {
+	if len(matches) == 0 {
+		return
+	}
+	entry := matches[ResultList.GetCurrentItem()]
+	if entry.Choices != nil {
+		choices := entry.Choices()
+		if len(choices) == 0 {
+			deck_ui.FooterBar.SetText(fmt.Sprintf("Nothing to choose for %s", entry.Title))
+			return
+		}
+		entries = choices
+		show(fmt.Sprintf(" %s ", entry.Title))
+		return
+	}
+	closePalette()
+	entry.Run()
+}
+
+func closePalette() {
+	deck_ui.BuildFullFlex(returnPrimitive, nil)
+	app.SetFocus(returnFocus)
+}
+
+func getMainEntries() []Entry {
+	return []Entry{
+		{Title: "Move card to stack…", Choices: func() []Entry {
+			if !cardSelected {
+				return nil
+			}
+			return getStackChoices(deck_card.MoveSelectedCard)
+		}},
+		{Title: "Go to stack…", Choices: func() []Entry {
+			return getStackChoices(func(index int) {
+				app.SetFocus(deck_ui.GetNextFocus(index))
+			})
+		}},
+		{Title: "Switch to board…", Choices: getBoardChoices},
+		{Title: "Add card from template…", Choices: getTemplateChoices},
+		{Title: "Assign user to card…", Choices: func() []Entry {
+			if !cardSelected {
+				return nil
+			}
+			card := selectedCard
+			return getUserChoices(func(user deck_structs.Owner) {
+				deck_card.AssignUserToCard(card, user)
+			})
+		}},
+		{Title: "Add label to card…", Choices: func() []Entry {
+			if !cardSelected {
+				return nil
+			}
+			card := selectedCard
+			return getLabelChoices(func(label deck_structs.Label) {
+				deck_card.AddLabelToCard(card, label)
+			})
+		}},
+	}
+}
+
+func getBulkEntries(cards []deck_structs.Card) []Entry {
+	return []Entry{
+		{Title: "Move marked cards to stack…", Choices: func() []Entry {
+			return getStackChoices(deck_card.BulkMove)
+		}},
+		{Title: "Add label to marked cards…", Choices: func() []Entry {
+			return getLabelChoices(func(label deck_structs.Label) {
+				deck_card.BulkLabel(label, true)
+			})
+		}},
+		{Title: "Remove label from marked cards…", Choices: func() []Entry {
+			choices := make([]Entry, 0)
+			found := make(map[int]bool)
+			for _, card := range cards {
+				for _, label := range card.Labels {
+					if found[label.Id] {
+						continue
+					}
+					found[label.Id] = true
+					l := label
+					choices = append(choices, Entry{Title: l.Title, Run: func() {
+						deck_card.BulkLabel(l, false)
+					}})
+				}
+			}
+			return choices
+		}},
+		{Title: "Assign user to marked cards…", Choices: func() []Entry {
+			return getUserChoices(func(user deck_structs.Owner) {
+				deck_card.BulkUser(user, true)
+			})
+		}},
+		{Title: "Unassign user from marked cards…", Choices: func() []Entry {
+			choices := make([]Entry, 0)
+			found := make(map[string]bool)
+			for _, card := range cards {
+				for _, user := range card.AssignedUsers {
+					if found[user.Participant.Uid] {
+						continue
+					}
+					found[user.Participant.Uid] = true
+					u := user.Participant
+					choices = append(choices, Entry{Title: fmt.Sprintf("%s (@%s)", u.DisplayName, u.Uid), Run: func() {
+						deck_card.BulkUser(u, false)
+					}})
+				}
+			}
+			return choices
+		}},
+		{Title: "Set due date of marked cards…", Run: func() {
+			deck_ui.BuildFullFlex(deck_card.BuildBulkDueDateForm(), nil)
+		}},
+		{Title: "Archive marked cards", Run: deck_card.BulkArchive},
+		{Title: "Delete marked cards", Run: deck_card.BulkDelete},
+		{Title: "Unmark all cards", Run: deck_card.ClearMarks},
+	}
+}
+
+func getCardEntries() []Entry {
+	return []Entry{
+		{Title: "Assign user…", Choices: func() []Entry {
+			return getUserChoices(func(user deck_structs.Owner) {
+				deck_card.AssignCardUser(user)
+			})
+		}},
+		{Title: "Unassign user…", Choices: func() []Entry {
+			choices := make([]Entry, 0)
+			for i, user := range deck_card.EditableCard.AssignedUsers {
+				index := i
+				choices = append(choices, Entry{Title: user.Participant.DisplayName, Run: func() {
+					deck_card.UnassignCardUser(index)
+				}})
+			}
+			return choices
+		}},
+		{Title: "Add label…", Choices: func() []Entry {
+			return getLabelChoices(func(label deck_structs.Label) {
+				deck_card.AddCardLabel(label)
+			})
+		}},
+		{Title: "Remove label…", Choices: func() []Entry {
+			choices := make([]Entry, 0)
+			for i, label := range deck_card.EditableCard.Labels {
+				index := i
+				choices = append(choices, Entry{Title: label.Title, Run: func() {
+					deck_card.RemoveCardLabel(index)
+				}})
+			}
+			return choices
+		}},
+	}
+}
+
+func getStackChoices(run func(index int)) []Entry {
+	choices := make([]Entry, 0)
+	for i, stack := range deck_stack.Stacks {
+		index := i
+		choices = append(choices, Entry{Title: stack.Title, Run: func() {
+			run(index)
+		}})
+	}
+	return choices
+}
+
+func getBoardChoices() []Entry {
+	choices := make([]Entry, 0)
+	for _, board := range deck_board.Boards {
+		boardId := board.Id
+		choices = append(choices, Entry{Title: board.Title, Run: func() {
+			err := deck_board.SwitchBoard(boardId)
+			deck_ui.BuildFullFlex(deck_ui.MainFlex, err)
+		}})
+	}
+	return choices
+}
+
+func getUserChoices(run func(user deck_structs.Owner)) []Entry {
+	choices := make([]Entry, 0)
+	for _, user := range deck_board.CurrentBoard.Users {
+		u := user
+		choices = append(choices, Entry{Title: fmt.Sprintf("%s (@%s)", u.DisplayName, u.Uid), Run: func() {
+			run(u)
+		}})
+	}
+	return choices
+}
+
+func getLabelChoices(run func(label deck_structs.Label)) []Entry {
+	choices := make([]Entry, 0)
+	for _, label := range deck_board.CurrentBoard.Labels {
+		l := label
+		choices = append(choices, Entry{Title: l.Title, Run: func() {
+			run(l)
+		}})
+	}
+	return choices
+}
+
+func getTemplateChoices() []Entry {
+	err := deck_template.Load()
+	if err != nil {
+		deck_ui.FooterBar.SetText(err.Error())
+	}
+	if len(deck_template.Templates) == 0 {
+		if err == nil {
+			deck_ui.FooterBar.SetText(fmt.Sprintf("No templates found in %s", deck_template.GetTemplatesDir()))
+		}
+		return nil
+	}
+	choices := make([]Entry, 0)
+	for _, template := range deck_template.Templates {
+		t := template
+		choices = append(choices, Entry{Title: t.Name, Run: func() {
+			addCardFromTemplate(t)
+		}})
+	}
+	return choices
+}
+
+func addCardFromTemplate(template deck_template.Template) {
+	if len(deck_stack.Stacks) == 0 {
+		return
+	}
+	stackIndex, ok := deck_ui.Primitives[app.GetFocus()]
+	if !ok {
+		stackIndex = 0
+	}
+	var stackErr error
+	if len(template.Stack) > 0 {
+		found := false
+		for i, stack := range deck_stack.Stacks {
+			if strings.EqualFold(stack.Title, template.Stack) {
+				stackIndex = i
+				found = true
+				break
+			}
+		}
+		if !found {
+			stackErr = fmt.Errorf("template %s: unknown stack %s", template.Name, template.Stack)
+		}
+	}
+
+	card, err := template.Render(deck_board.CurrentBoard, deck_stack.Stacks[stackIndex], time.Now())
+	list := deck_ui.GetNextFocus(stackIndex).(*tview.List)
+	deck_card.ShowAddForm(list, card, fmt.Sprintf(" Add Card - %s ", template.Name))
+	if stackErr != nil {
+		deck_ui.FooterBar.SetText(stackErr.Error())
+	} else if err != nil {
+		deck_ui.FooterBar.SetText(err.Error())
+	}
+}
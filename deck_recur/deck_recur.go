@@ -0,0 +1,313 @@
+package deck_recur
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/teambition/rrule-go"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"tui-deck/deck_http"
+	"tui-deck/deck_structs"
+	"tui-deck/deck_template"
+	"tui-deck/utils"
+)
+
+const maxOccurrences = 10
+
+type Rule struct {
+	Name     string `json:"name"`
+	RRule    string `json:"rrule"`
+	Start    string `json:"start"`
+	Template string `json:"template"`
+	Board    string `json:"board"`
+	Stack    string `json:"stack"`
+}
+
+type Recurring struct {
+	CronOnly bool   `json:"cronOnly"`
+	Rules    []Rule `json:"rules"`
+}
+
+var configuration utils.Configuration
+
+func Init(conf utils.Configuration) {
+	configuration = conf
+}
+
+func GetRecurringFile() string {
+	return configuration.ConfigDir + "/recurring.json"
+}
+
+func getStateFile() string {
+	return configuration.ConfigDir + "/db/recurring-state.json"
+}
+
+func Load() (Recurring, error) {
+	recurring := Recurring{}
+	if !utils.Exists(GetRecurringFile()) {
+		return recurring, nil
+	}
+	file, err := os.Open(GetRecurringFile())
+	if err != nil {
+		return recurring, err
+	}
+	defer file.Close()
+	err = json.NewDecoder(file).Decode(&recurring)
+	if err != nil {
+		return Recurring{}, fmt.Errorf("error reading %s: %s", GetRecurringFile(), err.Error())
+	}
+	return recurring, nil
+}
+
+func RunAtStartup() ([]string, error) {
+	recurring, err := Load()
+	if err != nil {
+		return nil, err
+	}
+	if recurring.CronOnly {
+		return nil, nil
+	}
+	return run(recurring, time.Now())
+}
+
+func Run() ([]string, error) {
+	recurring, err := Load()
+	if err != nil {
+		return nil, err
+	}
+	return run(recurring, time.Now())
+}
+
+func run(recurring Recurring, now time.Time) ([]string, error) {
+	if len(recurring.Rules) == 0 {
+		return nil, nil
+	}
+	state, err := loadState()
+	if err != nil {
+		return nil, err
+	}
+	boards, err := deck_http.GetBoards(configuration)
+	if err != nil {
+		return nil, err
+	}
+
+	report := make([]string, 0)
+	errs := make([]string, 0)
+	for _, rule := range recurring.Rules {
+		template, err := deck_template.Find(rule.Template)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %s", rule.Name, err.Error()))
+			continue
+		}
+		occurrences, err := getOccurrences(rule, state[rule.Name], now)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %s", rule.Name, err.Error()))
+			continue
+		}
+		occurrences, skipped := limitOccurrences(occurrences)
+		if skipped > 0 {
+			report = append(report, fmt.Sprintf("%s: skipped %d older occurrences", rule.Name, skipped))
+		}
+		for _, occurrence := range occurrences {
+			card, created, err := createCard(rule, template, boards, occurrence)
+			if card.Id != 0 {
+				state[rule.Name] = occurrence.Format(time.RFC3339)
+				if created {
+					report = append(report, fmt.Sprintf("%s: created card #%d %s", rule.Name, card.Id, card.Title))
+				} else {
+					report = append(report, fmt.Sprintf("%s: card #%d %s already exists", rule.Name, card.Id, card.Title))
+				}
+			}
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("%s (%s): %s", rule.Name, occurrence.Format("2006-01-02 15:04"), err.Error()))
+				break
+			}
+		}
+	}
+
+	err = saveState(state)
+	if err != nil {
+		errs = append(errs, err.Error())
+	}
+	if len(errs) > 0 {
+		return report, fmt.Errorf("error creating recurring cards: %s", strings.Join(errs, ", "))
+	}
+	return report, nil
+}
+
+func getOccurrences(rule Rule, last string, now time.Time) ([]time.Time, error) {
+	if len(rule.Name) == 0 {
+		return nil, fmt.Errorf("rule without name")
+	}
+	lines := make([]string, 0)
+	for _, line := range strings.Split(strings.ReplaceAll(rule.RRule, `\n`, "\n"), "\n") {
+		lines = append(lines, strings.TrimPrefix(strings.TrimSpace(line), "RRULE:"))
+	}
+	option, err := rrule.StrToROptionInLocation(strings.Join(lines, "\n"), time.Local)
+	if err != nil {
+		return nil, err
+	}
+	if len(rule.Start) > 0 {
+		option.Dtstart, err = time.ParseInLocation("2006-01-02 15:04", rule.Start, time.Local)
+		if err != nil {
+			return nil, fmt.Errorf("not a valid start %s, format must be YYYY-MM-dd HH:mm", rule.Start)
+		}
+	}
+	if option.Dtstart.IsZero() {
+		return nil, fmt.Errorf("missing start, set start or DTSTART in rrule")
+	}
+	r, err := rrule.NewRRule(*option)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(last) == 0 {
+		occurrence := r.Before(now, true)
+		if occurrence.IsZero() {
+			return nil, nil
+		}
+		return []time.Time{occurrence}, nil
+	}
+	lastOccurrence, err := time.Parse(time.RFC3339, last)
+	if err != nil {
+		return nil, err
+	}
+	occurrences := make([]time.Time, 0)
+	for _, occurrence := range r.Between(lastOccurrence, now, true) {
+		if occurrence.After(lastOccurrence) {
+			occurrences = append(occurrences, occurrence)
+		}
+	}
+	return occurrences, nil
+}
+
+func limitOccurrences(occurrences []time.Time) ([]time.Time, int) {
+	if len(occurrences) <= maxOccurrences {
+		return occurrences, 0
+	}
+	return occurrences[len(occurrences)-maxOccurrences:], len(occurrences) - maxOccurrences
+}
+
+func createCard(rule Rule, template deck_template.Template, boards []deck_structs.Board, occurrence time.Time) (deck_structs.Card, bool, error) {
+	boardId := 0
+	for _, b := range boards {
+		if strings.EqualFold(b.Title, rule.Board) || strconv.Itoa(b.Id) == rule.Board {
+			boardId = b.Id
+			break
+		}
+	}
+	if boardId == 0 {
+		return deck_structs.Card{}, false, fmt.Errorf("unknown board %s", rule.Board)
+	}
+	board, err := deck_http.GetBoardDetail(boardId, configuration)
+	if err != nil {
+		return deck_structs.Card{}, false, err
+	}
+	stacks, err := deck_http.GetStacks(boardId, configuration)
+	if err != nil {
+		return deck_structs.Card{}, false, err
+	}
+
+	stackName := rule.Stack
+	if len(stackName) == 0 {
+		stackName = template.Stack
+	}
+	var stack deck_structs.Stack
+	found := false
+	for _, s := range stacks {
+		if strings.EqualFold(s.Title, stackName) || strconv.Itoa(s.Id) == stackName {
+			stack = s
+			found = true
+			break
+		}
+	}
+	if !found {
+		return deck_structs.Card{}, false, fmt.Errorf("unknown stack %s", stackName)
+	}
+
+	card, err := template.Render(board, stack, occurrence)
+	if err != nil {
+		return deck_structs.Card{}, false, err
+	}
+	dueDate := ""
+	if len(card.DueDate) > 0 {
+		parse, _ := time.Parse("02/01/2006 15:04", card.DueDate)
+		dueDate = parse.Format("2006-01-02T15:04:05+00:00")
+	}
+	for _, c := range stack.Cards {
+		if c.Title == card.Title && sameDueDate(c.DueDate, dueDate) {
+			return c, false, nil
+		}
+	}
+	dueDateFormat := ""
+	if len(dueDate) > 0 {
+		dueDateFormat = fmt.Sprintf(`, "duedate": "%s"`, dueDate)
+	}
+	jsonBody := fmt.Sprintf(`{"title":"%s", "description": "%s", "type": "plain", "order": %d%s}`,
+		utils.CleanText(card.Title), utils.CleanText(card.Description), card.Order, dueDateFormat)
+	newCard, err := deck_http.AddCard(boardId, stack.Id, jsonBody, configuration)
+	if err != nil {
+		return deck_structs.Card{}, false, err
+	}
+	for _, label := range card.Labels {
+		_, err = deck_http.AssignLabel(boardId, stack.Id, newCard.Id, fmt.Sprintf(`{"labelId": %d}`, label.Id), configuration)
+		if err != nil {
+			return newCard, true, fmt.Errorf("error assigning label %s to card #%d: %s", label.Title, newCard.Id, err.Error())
+		}
+	}
+	for _, user := range card.AssignedUsers {
+		_, err = deck_http.AssignUser(boardId, stack.Id, newCard.Id, fmt.Sprintf(`{"userId": "%s"}`, user.Participant.Uid), configuration)
+		if err != nil {
+			return newCard, true, fmt.Errorf("error assigning user %s to card #%d: %s", user.Participant.DisplayName, newCard.Id, err.Error())
+		}
+	}
+	return newCard, true, nil
+}
+
+func sameDueDate(a string, b string) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return len(a) == len(b)
+	}
+	aTime, err := time.Parse(time.RFC3339, a)
+	if err != nil {
+		return false
+	}
+	bTime, err := time.Parse(time.RFC3339, b)
+	if err != nil {
+		return false
+	}
+	return aTime.Equal(bTime)
+}
+
+func loadState() (map[string]string, error) {
+	state := make(map[string]string)
+	if !utils.Exists(getStateFile()) {
+		return state, nil
+	}
+	file, err := os.Open(getStateFile())
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	err = json.NewDecoder(file).Decode(&state)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %s", getStateFile(), err.Error())
+	}
+	return state, nil
+}
+
+func saveState(state map[string]string) error {
+	file, err := utils.CreateFile(getStateFile())
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	marshal, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(marshal)
+	return err
+}
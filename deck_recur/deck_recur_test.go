@@ -0,0 +1,60 @@
+package deck_recur
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGetOccurrences(t *testing.T) {
+	rule := Rule{Name: "daily", RRule: "FREQ=DAILY", Start: "2024-01-01 09:00"}
+	now := time.Date(2024, 1, 20, 12, 0, 0, 0, time.Local)
+
+	t.Run("first run", func(t *testing.T) {
+		occurrences, err := getOccurrences(rule, "", now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := time.Date(2024, 1, 20, 9, 0, 0, 0, time.Local)
+		if len(occurrences) != 1 || !occurrences[0].Equal(want) {
+			t.Errorf("got %v, want [%s]", occurrences, want)
+		}
+	})
+
+	t.Run("catch up", func(t *testing.T) {
+		last := time.Date(2024, 1, 1, 9, 0, 0, 0, time.Local).Format(time.RFC3339)
+		occurrences, err := getOccurrences(rule, last, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(occurrences) != 19 {
+			t.Fatalf("got %d occurrences, want 19", len(occurrences))
+		}
+		limited, skipped := limitOccurrences(occurrences)
+		if len(limited) != maxOccurrences || skipped != 19-maxOccurrences {
+			t.Errorf("got %d occurrences and %d skipped, want %d and %d", len(limited), skipped, maxOccurrences, 19-maxOccurrences)
+		}
+		want := time.Date(2024, 1, 20, 9, 0, 0, 0, time.Local)
+		if !limited[len(limited)-1].Equal(want) {
+			t.Errorf("last occurrence %s, want %s", limited[len(limited)-1], want)
+		}
+	})
+
+	t.Run("last boundary", func(t *testing.T) {
+		last := time.Date(2024, 1, 20, 9, 0, 0, 0, time.Local).Format(time.RFC3339)
+		occurrences, err := getOccurrences(rule, last, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(occurrences) != 0 {
+			t.Errorf("got %v, want no occurrences", occurrences)
+		}
+		occurrences, err = getOccurrences(rule, last, now.Add(24*time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := time.Date(2024, 1, 21, 9, 0, 0, 0, time.Local)
+		if len(occurrences) != 1 || !occurrences[0].Equal(want) {
+			t.Errorf("got %v, want [%s]", occurrences, want)
+		}
+	})
+}
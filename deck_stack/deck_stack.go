@@ -9,12 +9,15 @@ import (
 	"strings"
 	"tui-deck/deck_http"
 	"tui-deck/deck_structs"
+	"tui-deck/deck_theme"
 	"tui-deck/deck_ui"
+	"tui-deck/deck_undo"
 	"tui-deck/utils"
 )
 
 var Stacks []deck_structs.Stack
 var Modal *tview.Modal
+var restoredStacks = make(map[int]int)
 var app *tview.Application
 var configuration utils.Configuration
 
@@ -25,6 +28,9 @@ func Init(application *tview.Application, conf utils.Configuration) {
 	Modal = tview.NewModal()
 }
 func GetActualStack(actualList *tview.List) (int, deck_structs.Stack, error) {
+	if index, ok := deck_ui.Primitives[actualList]; ok && index < len(Stacks) {
+		return index, Stacks[index], nil
+	}
 	for i, s := range Stacks {
 		if s.Title == strings.TrimSpace(actualList.GetTitle()) {
 			return i, s, nil
@@ -51,7 +57,7 @@ func AddStack(boardId int, stack deck_structs.Stack) error {
 func DeleteStack(stackId int, actualList *tview.List) {
 	Modal.ClearButtons()
 	Modal.SetText(fmt.Sprintf("Are you sure to delete stack #%d?", stackId))
-	Modal.SetBackgroundColor(utils.GetColor(configuration.Color))
+	deck_theme.StyleModal(Modal)
 
 	Modal.AddButtons([]string{"Yes", "No"})
 
@@ -76,12 +82,94 @@ func EditStack(boardId int, stack deck_structs.Stack) error {
 	jsonBody := strings.ReplaceAll(
 		fmt.Sprintf(`{"title": "%s", "order": %d }`,
 			description, stack.Order), "\n", `\n`)
-	var err error
-	_, err = deck_http.EditStack(boardId, stack.Id, jsonBody, configuration)
+	_, err := deck_http.EditStack(boardId, stack.Id, jsonBody, configuration)
+	return err
+}
+
+func ResolveStackId(stackId int) int {
+	for {
+		restoredId, ok := restoredStacks[stackId]
+		if !ok {
+			return stackId
+		}
+		stackId = restoredId
+	}
+}
+
+func RestoreStack(boardId int, stack deck_structs.Stack) (deck_structs.Stack, error) {
+	jsonBody := fmt.Sprintf(`{"title":"%s", "order": %d}`, utils.CleanText(stack.Title), stack.Order)
+	newStack, err := deck_http.AddStack(boardId, jsonBody, configuration)
+	if err != nil {
+		return newStack, err
+	}
+	app.QueueUpdate(func() {
+		restoredStacks[stack.Id] = newStack.Id
+		Stacks = append(Stacks, newStack)
+	})
+	return newStack, nil
+}
+
+func RemoveStack(boardId int, stackId int) error {
+	app.QueueUpdate(func() {
+		stackId = ResolveStackId(stackId)
+	})
+	_, err := deck_http.DeleteStack(boardId, stackId, configuration)
 	if err != nil {
-		deck_ui.FooterBar.SetText(fmt.Sprintf("Error updating stack: %s", err.Error()))
 		return err
 	}
+	app.QueueUpdate(func() {
+		for i, s := range Stacks {
+			if s.Id == stackId {
+				Stacks = append(Stacks[:i], Stacks[i+1:]...)
+				break
+			}
+		}
+	})
+	return nil
+}
+
+func RecordAddStack(boardId int, stack deck_structs.Stack) {
+	deck_undo.Record(deck_undo.Operation{
+		Title: fmt.Sprintf("Add stack %s", stack.Title),
+		Undo: func() error {
+			return RemoveStack(boardId, stack.Id)
+		},
+		Redo: func() error {
+			_, err := RestoreStack(boardId, stack)
+			return err
+		},
+	})
+}
+
+func RecordEditStack(boardId int, previous deck_structs.Stack, stack deck_structs.Stack) {
+	deck_undo.Record(deck_undo.Operation{
+		Title: fmt.Sprintf("Edit stack %s", stack.Title),
+		Undo: func() error {
+			return updateStack(boardId, previous)
+		},
+		Redo: func() error {
+			return updateStack(boardId, stack)
+		},
+	})
+}
+
+func updateStack(boardId int, stack deck_structs.Stack) error {
+	app.QueueUpdate(func() {
+		stack.Id = ResolveStackId(stack.Id)
+	})
+	err := EditStack(boardId, stack)
+	if err != nil {
+		return err
+	}
+	app.QueueUpdate(func() {
+		for i, s := range Stacks {
+			if s.Id == stack.Id {
+				stack.Cards = s.Cards
+				Stacks[i] = stack
+				break
+			}
+		}
+	})
 	return nil
 }
 
@@ -95,11 +183,7 @@ func BuildAddForm(s deck_structs.Stack) (*tview.Form, *deck_structs.Stack) {
 	}
 	addForm.SetTitle(title)
 	addForm.SetBorder(true)
-	addForm.SetBorderColor(utils.GetColor(configuration.Color))
-	addForm.SetButtonBackgroundColor(utils.GetColor(configuration.Color))
-	addForm.SetFieldBackgroundColor(tcell.ColorWhite)
-	addForm.SetFieldTextColor(tcell.ColorBlack)
-	addForm.SetLabelColor(utils.GetColor(configuration.Color))
+	deck_theme.StyleForm(addForm)
 	addForm.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
 		if event.Key() == tcell.KeyEsc {
 			deck_ui.BuildFullFlex(deck_ui.MainFlex, nil)
@@ -0,0 +1,377 @@
+package deck_stats
+
+import (
+	"fmt"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"tui-deck/deck_db"
+	"tui-deck/deck_keys"
+	"tui-deck/deck_markdown"
+	"tui-deck/deck_structs"
+	"tui-deck/deck_theme"
+	"tui-deck/deck_ui"
+	"tui-deck/utils"
+)
+
+const (
+	Burndown = "Burndown"
+	Flow     = "Cumulative flow"
+)
+
+type count struct {
+	name  string
+	value int
+}
+
+var StatsFlex *tview.Flex
+var StatsView *tview.TextView
+var ChartBox *tview.Box
+
+var chart = Burndown
+var snapshots = make([]deck_structs.Snapshot, 0)
+var fills = []rune{'█', '▓', '▒', '░'}
+
+var app *tview.Application
+var configuration utils.Configuration
+
+func Init(application *tview.Application, conf utils.Configuration) {
+	app = application
+	configuration = conf
+
+	StatsFlex = tview.NewFlex()
+	StatsView = tview.NewTextView()
+	ChartBox = tview.NewBox()
+
+	StatsFlex.SetDirection(tview.FlexColumn)
+	StatsFlex.SetBorder(true)
+	StatsView.SetDynamicColors(true)
+	StatsView.SetWrap(false)
+	StatsView.SetBorder(true)
+	StatsView.SetTitle(" Numbers ")
+	ChartBox.SetBorder(true)
+	ChartBox.SetDrawFunc(drawChart)
+
+	StatsFlex.AddItem(StatsView, 0, 1, true)
+	StatsFlex.AddItem(ChartBox, 0, 2, false)
+
+	applyTheme()
+	deck_theme.OnChange(applyTheme)
+}
+
+func applyTheme() {
+	deck_theme.StyleBox(StatsFlex.Box)
+	deck_theme.StyleBox(StatsView.Box)
+	deck_theme.StyleBox(ChartBox)
+	StatsView.SetTextColor(deck_theme.GetColor(deck_theme.Current.Text))
+}
+
+func BuildStats(board deck_structs.Board, stacks []deck_structs.Stack) {
+	recorded, err := deck_db.RecordSnapshot(board.Id, stacks, configuration)
+	snapshots = fillDays(recorded)
+
+	StatsFlex.SetTitle(fmt.Sprintf(" Stats - %s ", board.Title))
+	StatsView.SetText(getStatsText(stacks, time.Now()))
+	StatsView.ScrollToBeginning()
+	setChartTitle()
+
+	StatsView.SetInputCapture(deck_keys.Capture(deck_keys.Stats))
+	deck_keys.SetHandler("stats.chart", func() {
+		if chart == Burndown {
+			chart = Flow
+		} else {
+			chart = Burndown
+		}
+		setChartTitle()
+	})
+	deck_keys.SetHandler("stats.back", func() {
+		deck_ui.BuildFullFlex(deck_ui.MainFlex, nil)
+	})
+	deck_keys.SetHandler("stats.help", func() {
+		deck_ui.BuildHelp(StatsFlex, deck_keys.Stats)
+	})
+
+	deck_ui.BuildFullFlex(StatsFlex, err)
+	app.SetFocus(StatsView)
+	if err != nil {
+		deck_ui.FooterBar.SetText(fmt.Sprintf("Error recording snapshot: %s", err.Error()))
+	}
+}
+
+func fillDays(recorded []deck_structs.Snapshot) []deck_structs.Snapshot {
+	days := make([]deck_structs.Snapshot, 0)
+	for _, snapshot := range recorded {
+		date, err := time.Parse("2006-01-02", snapshot.Date)
+		if err != nil {
+			continue
+		}
+		if len(days) > 0 {
+			previous := days[len(days)-1]
+			last, _ := time.Parse("2006-01-02", previous.Date)
+			for day := last.AddDate(0, 0, 1); day.Before(date); day = day.AddDate(0, 0, 1) {
+				previous.Date = day.Format("2006-01-02")
+				days = append(days, previous)
+			}
+		}
+		days = append(days, snapshot)
+	}
+	return days
+}
+
+func setChartTitle() {
+	ChartBox.SetTitle(fmt.Sprintf(" %s (%d days) ", chart, len(snapshots)))
+}
+
+func getStatsText(stacks []deck_structs.Stack, now time.Time) string {
+	perStack := make([]count, 0)
+	labels := make(map[string]int)
+	users := make(map[string]int)
+	total, overdue, dueSoon, withoutDue := 0, 0, 0, 0
+	withCheckList, completeCheckList, checked, checkItems := 0, 0, 0, 0
+
+	for _, s := range stacks {
+		perStack = append(perStack, count{s.Title, len(s.Cards)})
+		for _, card := range s.Cards {
+			total++
+			if len(card.Labels) == 0 {
+				labels["(no label)"]++
+			}
+			for _, label := range card.Labels {
+				labels[label.Title]++
+			}
+			if len(card.AssignedUsers) == 0 {
+				users["(unassigned)"]++
+			}
+			for _, user := range card.AssignedUsers {
+				users[user.Participant.DisplayName]++
+			}
+
+			if len(card.DueDate) == 0 {
+				withoutDue++
+			} else if due, err := time.Parse("2006-01-02T15:04:05+00:00", card.DueDate); err == nil {
+				if due.Before(now) {
+					overdue++
+				} else if due.Before(now.AddDate(0, 0, 7)) {
+					dueSoon++
+				}
+			}
+
+			c, t, err := deck_markdown.CountCheckList(card.Description)
+			if err == nil {
+				withCheckList++
+				checked += c
+				checkItems += t
+				if c == t {
+					completeCheckList++
+				}
+			}
+		}
+	}
+
+	var sb strings.Builder
+	writeCounts(&sb, fmt.Sprintf("Cards per stack (%d)", total), perStack, total)
+	writeCounts(&sb, "Cards per label", sortCounts(labels), total)
+	writeCounts(&sb, "Cards per assignee", sortCounts(users), total)
+	writeCounts(&sb, "Due dates", []count{
+		{"overdue", overdue},
+		{"due in 7 days", dueSoon},
+		{"no due date", withoutDue},
+	}, total)
+
+	sb.WriteString(fmt.Sprintf("[%s::b]Checklists[-::-]\n", deck_theme.Current.Warning))
+	sb.WriteString(fmt.Sprintf("  cards with checklist  %d\n", withCheckList))
+	sb.WriteString(fmt.Sprintf("  complete              %d\n", completeCheckList))
+	if checkItems > 0 {
+		sb.WriteString(fmt.Sprintf("  items                 %d/%d (%d%%)\n", checked, checkItems, checked*100/checkItems))
+	}
+	return sb.String()
+}
+
+func sortCounts(counts map[string]int) []count {
+	sorted := make([]count, 0)
+	for name, value := range counts {
+		sorted = append(sorted, count{name, value})
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].value == sorted[j].value {
+			return sorted[i].name < sorted[j].name
+		}
+		return sorted[i].value > sorted[j].value
+	})
+	return sorted
+}
+
+func writeCounts(sb *strings.Builder, title string, counts []count, total int) {
+	sb.WriteString(fmt.Sprintf("[%s::b]%s[-::-]\n", deck_theme.Current.Warning, tview.Escape(title)))
+	width := 0
+	for _, c := range counts {
+		if l := len([]rune(c.name)); l > width {
+			width = l
+		}
+	}
+	for _, c := range counts {
+		bar := ""
+		if total > 0 {
+			bar = strings.Repeat("▇", c.value*10/total)
+		}
+		padding := strings.Repeat(" ", width-len([]rune(c.name)))
+		sb.WriteString(fmt.Sprintf("  %s%s %4d [%s]%s[-]\n", tview.Escape(c.name), padding, c.value, deck_theme.Current.Muted, bar))
+	}
+	sb.WriteString("\n")
+}
+
+func drawChart(screen tcell.Screen, x, y, width, height int) (int, int, int, int) {
+	x, y, width, height = ChartBox.GetInnerRect()
+	textColor := deck_theme.GetColor(deck_theme.Current.Text)
+	if len(snapshots) == 0 || width < 10 || height < 4 {
+		tview.Print(screen, "no snapshots yet", x, y, width, tview.AlignCenter, textColor)
+		return x, y, width, height
+	}
+
+	titles := getSeriesTitles()
+	plotHeight := height - 2
+	maxValue := 1
+	for _, snapshot := range snapshots {
+		if v := getChartTotal(snapshot); v > maxValue {
+			maxValue = v
+		}
+	}
+	labelWidth := len(strconv.Itoa(maxValue)) + 1
+	plotWidth := width - labelWidth
+	days := snapshots
+	if len(days) > plotWidth {
+		days = days[len(days)-plotWidth:]
+		maxValue = 1
+		for _, snapshot := range days {
+			if v := getChartTotal(snapshot); v > maxValue {
+				maxValue = v
+			}
+		}
+	}
+	columnWidth := plotWidth / len(days)
+	if columnWidth > 3 {
+		columnWidth = 3
+	}
+
+	tview.Print(screen, strconv.Itoa(maxValue), x, y, labelWidth-1, tview.AlignRight, textColor)
+	tview.Print(screen, "0", x, y+plotHeight-1, labelWidth-1, tview.AlignRight, textColor)
+
+	background := deck_theme.GetColor(deck_theme.Current.Background)
+	for i, snapshot := range days {
+		column := x + labelWidth + i*columnWidth
+		barWidth := columnWidth
+		if barWidth > 1 {
+			barWidth--
+		}
+		bottom := 0
+		for _, segment := range getSegments(snapshot, titles) {
+			top := bottom + segment.value
+			from := bottom * plotHeight / maxValue
+			to := top * plotHeight / maxValue
+			style := tcell.StyleDefault.Foreground(getSeriesColor(segment.index)).Background(background)
+			for row := from; row < to; row++ {
+				for col := 0; col < barWidth; col++ {
+					screen.SetContent(column+col, y+plotHeight-1-row, fills[segment.index%len(fills)], nil, style)
+				}
+			}
+			bottom = top
+		}
+	}
+
+	first, _ := time.Parse("2006-01-02", days[0].Date)
+	last, _ := time.Parse("2006-01-02", days[len(days)-1].Date)
+	tview.Print(screen, first.Format("02/01"), x+labelWidth, y+plotHeight, plotWidth, tview.AlignLeft, textColor)
+	if len(days) > 1 {
+		tview.Print(screen, last.Format("02/01"), x+labelWidth, y+plotHeight, plotWidth, tview.AlignRight, textColor)
+	}
+
+	legend := x
+	for i, title := range getLegend(titles) {
+		if legend >= x+width {
+			break
+		}
+		style := tcell.StyleDefault.Foreground(getSeriesColor(i)).Background(background)
+		screen.SetContent(legend, y+height-1, fills[i%len(fills)], nil, style)
+		_, printed := tview.Print(screen, " "+title+"  ", legend+1, y+height-1, x+width-legend-1, tview.AlignLeft, textColor)
+		legend += printed + 1
+	}
+	return x, y, width, height
+}
+
+type segment struct {
+	index int
+	value int
+}
+
+func getSeriesTitles() []string {
+	titles := make([]string, 0)
+	seen := make(map[string]bool)
+	for i := len(snapshots) - 1; i >= 0; i-- {
+		for _, s := range snapshots[i].Stacks {
+			if !seen[s.Title] {
+				seen[s.Title] = true
+				titles = append(titles, s.Title)
+			}
+		}
+	}
+	return titles
+}
+
+// getChartTotal counts the open cards for the burndown, assuming the last
+// stack of a snapshot holds the done cards.
+func getChartTotal(snapshot deck_structs.Snapshot) int {
+	total := 0
+	for _, s := range snapshot.Stacks {
+		total += s.Count
+	}
+	if chart == Burndown && len(snapshot.Stacks) > 0 {
+		total -= snapshot.Stacks[len(snapshot.Stacks)-1].Count
+	}
+	return total
+}
+
+func getSegments(snapshot deck_structs.Snapshot, titles []string) []segment {
+	if chart == Burndown {
+		open := getChartTotal(snapshot)
+		overdue := snapshot.Overdue
+		if overdue > open {
+			overdue = open
+		}
+		return []segment{{1, overdue}, {0, open - overdue}}
+	}
+
+	counts := make(map[string]int)
+	for _, s := range snapshot.Stacks {
+		counts[s.Title] = s.Count
+	}
+	segments := make([]segment, 0)
+	for i := len(titles) - 1; i >= 0; i-- {
+		segments = append(segments, segment{i, counts[titles[i]]})
+	}
+	return segments
+}
+
+func getLegend(titles []string) []string {
+	if chart == Burndown {
+		return []string{"open cards", "overdue"}
+	}
+	return titles
+}
+
+func getSeriesColor(index int) tcell.Color {
+	colors := []string{
+		deck_theme.Current.Accent,
+		deck_theme.Current.Danger,
+		deck_theme.Current.Warning,
+		deck_theme.Current.LinkText,
+		deck_theme.Current.LinkUrl,
+		deck_theme.Current.Muted,
+	}
+	if index == 0 {
+		return deck_theme.Accent()
+	}
+	return deck_theme.GetColor(colors[index%len(colors)])
+}
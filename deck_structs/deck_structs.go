@@ -1,6 +1,7 @@
 package deck_structs
 
 import (
+	"encoding/json"
 	"fmt"
 	"strings"
 )
@@ -11,6 +12,16 @@ type Owner struct {
 	DisplayName string `json:"displayName"`
 }
 
+func (owner *Owner) UnmarshalJSON(data []byte) error {
+	var uid string
+	if err := json.Unmarshal(data, &uid); err == nil {
+		*owner = Owner{PrimaryKey: uid, Uid: uid, DisplayName: uid}
+		return nil
+	}
+	type plain Owner
+	return json.Unmarshal(data, (*plain)(owner))
+}
+
 func (owner *Owner) GetAbbrv() string {
 	split := strings.Split(owner.DisplayName, " ")
 
@@ -50,6 +61,11 @@ type Card struct {
 	Type          string         `json:"type"`
 	DueDate       string         `json:"duedate"`
 	AssignedUsers []AssignedUser `json:"assignedUsers"`
+	Archived      bool           `json:"archived"`
+	Owner         Owner          `json:"owner"`
+	CreatedAt     int64          `json:"createdAt"`
+	LastModified  int64          `json:"lastModified"`
+	CommentsCount int            `json:"commentsCount"`
 }
 
 type AssignedUser struct {
@@ -91,6 +107,33 @@ type OcsUsers struct {
 	Data Users `json:"data"`
 }
 
+type OcsResponseNotifications struct {
+	Ocs OcsNotifications `json:"ocs"`
+}
+
+type OcsNotifications struct {
+	Meta Meta           `json:"meta"`
+	Data []Notification `json:"data"`
+}
+
+type OcsResponseActivities struct {
+	Ocs OcsActivities `json:"ocs"`
+}
+
+type OcsActivities struct {
+	Meta Meta       `json:"meta"`
+	Data []Activity `json:"data"`
+}
+
+type OcsResponseAutocomplete struct {
+	Ocs OcsAutocomplete `json:"ocs"`
+}
+
+type OcsAutocomplete struct {
+	Meta Meta           `json:"meta"`
+	Data []Autocomplete `json:"data"`
+}
+
 type Users struct {
 	Users []string
 }
@@ -114,7 +157,50 @@ type Comment struct {
 }
 
 type Mention struct {
-	MentionId          int    `json:"mentionId"`
+	MentionId          string `json:"mentionId"`
 	MentionType        string `json:"mentionType"`
 	MentionDisplayName string `json:"mentionDisplayName"`
 }
+
+type Notification struct {
+	NotificationId int    `json:"notification_id"`
+	App            string `json:"app"`
+	User           string `json:"user"`
+	Datetime       string `json:"datetime"`
+	ObjectType     string `json:"object_type"`
+	ObjectId       string `json:"object_id"`
+	Subject        string `json:"subject"`
+	Message        string `json:"message"`
+	Link           string `json:"link"`
+}
+
+type Activity struct {
+	ActivityId int    `json:"activity_id"`
+	App        string `json:"app"`
+	Type       string `json:"type"`
+	User       string `json:"user"`
+	Subject    string `json:"subject"`
+	Message    string `json:"message"`
+	Link       string `json:"link"`
+	ObjectType string `json:"object_type"`
+	ObjectId   int    `json:"object_id"`
+	ObjectName string `json:"object_name"`
+	Datetime   string `json:"datetime"`
+}
+
+type Autocomplete struct {
+	Id     string `json:"id"`
+	Label  string `json:"label"`
+	Source string `json:"source"`
+}
+
+type Snapshot struct {
+	Date    string       `json:"date"`
+	Stacks  []StackCount `json:"stacks"`
+	Overdue int          `json:"overdue"`
+}
+
+type StackCount struct {
+	Title string `json:"title"`
+	Count int    `json:"count"`
+}
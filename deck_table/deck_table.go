@@ -0,0 +1,383 @@
+package deck_table
+
+import (
+	"fmt"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"tui-deck/deck_card"
+	"tui-deck/deck_keys"
+	"tui-deck/deck_markdown"
+	"tui-deck/deck_stack"
+	"tui-deck/deck_structs"
+	"tui-deck/deck_theme"
+	"tui-deck/deck_ui"
+	"tui-deck/utils"
+)
+
+const (
+	IdColumn = iota
+	TitleColumn
+	StackColumn
+	LabelsColumn
+	AssigneesColumn
+	DueColumn
+	CheckListColumn
+	ModifiedColumn
+)
+
+var columns = []string{"ID", "Title", "Stack", "Labels", "Assignees", "Due", "Checklist", "Modified"}
+
+type row struct {
+	card    deck_structs.Card
+	stack   string
+	checked int
+	total   int
+}
+
+type term struct {
+	key    string
+	value  string
+	negate bool
+}
+
+var TableFlex *tview.Flex
+var FilterInput *tview.InputField
+var CardsTable *tview.Table
+
+var rows = make([]row, 0)
+var sortColumn = IdColumn
+var sortReverse = false
+
+var app *tview.Application
+var configuration utils.Configuration
+
+func Init(application *tview.Application, conf utils.Configuration) {
+	app = application
+	configuration = conf
+
+	TableFlex = tview.NewFlex()
+	FilterInput = tview.NewInputField()
+	CardsTable = tview.NewTable()
+
+	TableFlex.SetDirection(tview.FlexRow)
+	TableFlex.SetBorder(true)
+	TableFlex.AddItem(FilterInput, 1, 0, false)
+	TableFlex.AddItem(CardsTable, 0, 1, true)
+
+	FilterInput.SetLabel("Filter: ")
+	FilterInput.SetPlaceholder("label:bug @alice stack:todo due:overdue -stack:done")
+	FilterInput.SetChangedFunc(func(text string) {
+		fill()
+	})
+	FilterInput.SetDoneFunc(func(key tcell.Key) {
+		app.SetFocus(CardsTable)
+	})
+
+	CardsTable.SetFixed(1, 0)
+	CardsTable.SetSelectable(true, false)
+	CardsTable.SetSelectedFunc(func(r int, column int) {
+		openCard(r)
+	})
+
+	applyTheme()
+	deck_theme.OnChange(applyTheme)
+}
+
+func applyTheme() {
+	deck_theme.StyleBox(TableFlex.Box)
+	deck_theme.StyleBox(FilterInput.Box)
+	deck_theme.StyleBox(CardsTable.Box)
+	FilterInput.SetLabelColor(deck_theme.Accent())
+	FilterInput.SetFieldBackgroundColor(deck_theme.GetColor(deck_theme.Current.FieldBackground))
+	FilterInput.SetFieldTextColor(deck_theme.GetColor(deck_theme.Current.FieldText))
+	FilterInput.SetPlaceholderTextColor(deck_theme.GetColor(deck_theme.Current.Muted))
+	if deck_theme.IsNoColor() {
+		CardsTable.SetSelectedStyle(tcell.StyleDefault.Reverse(true))
+	} else {
+		CardsTable.SetSelectedStyle(tcell.StyleDefault.
+			Foreground(deck_theme.GetColor(deck_theme.Current.SelectionText)).
+			Background(deck_theme.GetColor(deck_theme.Current.SelectionBackground)))
+	}
+	if CardsTable.GetRowCount() > 0 {
+		fill()
+	}
+}
+
+func BuildTable(board deck_structs.Board) {
+	TableFlex.SetTitle(fmt.Sprintf(" Cards - %s ", board.Title))
+
+	CardsTable.SetInputCapture(deck_keys.Capture(deck_keys.Table))
+	deck_keys.SetHandler("table.open", func() {
+		r, _ := CardsTable.GetSelection()
+		openCard(r)
+	})
+	deck_keys.SetHandler("table.filter", func() {
+		app.SetFocus(FilterInput)
+	})
+	deck_keys.SetHandler("table.clear-filter", func() {
+		FilterInput.SetText("")
+	})
+	deck_keys.SetHandler("table.sort", func() {
+		sortColumn = (sortColumn + 1) % len(columns)
+		fill()
+	})
+	deck_keys.SetHandler("table.reverse", func() {
+		sortReverse = !sortReverse
+		fill()
+	})
+	deck_keys.SetHandler("table.back", func() {
+		deck_ui.BuildFullFlex(deck_ui.MainFlex, nil)
+	})
+	deck_keys.SetHandler("table.help", func() {
+		deck_ui.BuildHelp(TableFlex, deck_keys.Table)
+	})
+
+	refresh()
+}
+
+func refresh() {
+	deck_ui.BuildFullFlex(TableFlex, nil)
+	fill()
+	app.SetFocus(CardsTable)
+}
+
+func fill() {
+	selected := 0
+	if r, _ := CardsTable.GetSelection(); r > 0 && r <= len(rows) {
+		selected = rows[r-1].card.Id
+	}
+
+	terms := parseFilter(FilterInput.GetText())
+	rows = make([]row, 0)
+	for _, s := range deck_stack.Stacks {
+		for _, card := range s.Cards {
+			if !matches(card, s.Title, terms) {
+				continue
+			}
+			checked, total, err := deck_markdown.CountCheckList(card.Description)
+			if err != nil {
+				checked, total = 0, 0
+			}
+			rows = append(rows, row{card: card, stack: s.Title, checked: checked, total: total})
+		}
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		if sortReverse {
+			return less(rows[j], rows[i])
+		}
+		return less(rows[i], rows[j])
+	})
+
+	CardsTable.Clear()
+	for i, name := range columns {
+		if i == sortColumn {
+			arrow := "▲"
+			if sortReverse {
+				arrow = "▼"
+			}
+			name = fmt.Sprintf("%s %s", name, arrow)
+		}
+		cell := tview.NewTableCell(name)
+		cell.SetSelectable(false)
+		cell.SetTextColor(deck_theme.Accent())
+		cell.SetAttributes(tcell.AttrBold)
+		CardsTable.SetCell(0, i, cell)
+	}
+	for i, r := range rows {
+		for column, text := range getCells(r) {
+			cell := tview.NewTableCell(text)
+			cell.SetTextColor(deck_theme.GetColor(deck_theme.Current.Text))
+			if column == TitleColumn {
+				cell.SetExpansion(1)
+			}
+			CardsTable.SetCell(i+1, column, cell)
+		}
+	}
+
+	CardsTable.Select(1, 0)
+	for i, r := range rows {
+		if r.card.Id == selected {
+			CardsTable.Select(i+1, 0)
+		}
+	}
+	deck_ui.FooterBar.SetText(fmt.Sprintf("%d of %d cards, sorted by %s", len(rows), countCards(), strings.ToLower(columns[sortColumn])))
+}
+
+func getCells(r row) []string {
+	card := r.card
+	assignees := make([]string, 0)
+	for _, u := range card.AssignedUsers {
+		assignees = append(assignees, u.Participant.DisplayName)
+	}
+
+	dueDate := ""
+	if len(card.DueDate) > 0 {
+		parse, err := time.Parse("2006-01-02T15:04:05+00:00", card.DueDate)
+		if err == nil {
+			dueDate = parse.Format("02/01/2006 15:04")
+			if parse.Before(time.Now()) {
+				dueDate = fmt.Sprintf("[%s]%s[-]", deck_theme.Current.Danger, dueDate)
+			}
+		}
+	}
+
+	checkList := ""
+	if r.total > 0 {
+		checkList = fmt.Sprintf("%d/%d", r.checked, r.total)
+	}
+
+	modified := ""
+	if card.LastModified > 0 {
+		modified = time.Unix(card.LastModified, 0).Format("02/01/2006 15:04")
+	}
+
+	return []string{
+		fmt.Sprintf("[%s]#%d[-]", deck_theme.Current.Accent, card.Id),
+		tview.Escape(card.Title),
+		tview.Escape(r.stack),
+		utils.BuildLabels(card, !deck_theme.IsNoColor()),
+		tview.Escape(strings.Join(assignees, ", ")),
+		dueDate,
+		checkList,
+		modified,
+	}
+}
+
+func less(a row, b row) bool {
+	switch sortColumn {
+	case TitleColumn:
+		return strings.ToLower(a.card.Title) < strings.ToLower(b.card.Title)
+	case StackColumn:
+		return strings.ToLower(a.stack) < strings.ToLower(b.stack)
+	case LabelsColumn:
+		return strings.ToLower(utils.BuildLabels(a.card, false)) < strings.ToLower(utils.BuildLabels(b.card, false))
+	case AssigneesColumn:
+		return getAssignees(a.card) < getAssignees(b.card)
+	case DueColumn:
+		if len(a.card.DueDate) == 0 || len(b.card.DueDate) == 0 {
+			return len(a.card.DueDate) > len(b.card.DueDate)
+		}
+		return a.card.DueDate < b.card.DueDate
+	case CheckListColumn:
+		if a.total == 0 || b.total == 0 {
+			return a.total > b.total
+		}
+		return a.checked*b.total < b.checked*a.total
+	case ModifiedColumn:
+		return a.card.LastModified < b.card.LastModified
+	}
+	return a.card.Id < b.card.Id
+}
+
+func getAssignees(card deck_structs.Card) string {
+	assignees := make([]string, 0)
+	for _, u := range card.AssignedUsers {
+		assignees = append(assignees, strings.ToLower(u.Participant.DisplayName))
+	}
+	return strings.Join(assignees, ", ")
+}
+
+func countCards() int {
+	count := 0
+	for _, s := range deck_stack.Stacks {
+		count += len(s.Cards)
+	}
+	return count
+}
+
+func openCard(r int) {
+	if r < 1 || r > len(rows) {
+		return
+	}
+	err := deck_card.OpenCardFrom(rows[r-1].card.Id, refresh)
+	if err != nil {
+		deck_ui.FooterBar.SetText(err.Error())
+	}
+}
+
+func parseFilter(expression string) []term {
+	terms := make([]term, 0)
+	for _, field := range strings.Fields(strings.ToLower(expression)) {
+		t := term{}
+		if strings.HasPrefix(field, "-") && len(field) > 1 {
+			t.negate = true
+			field = field[1:]
+		}
+		switch {
+		case strings.HasPrefix(field, "@"):
+			t.key, t.value = "user", field[1:]
+		case strings.HasPrefix(field, "#"):
+			t.key, t.value = "id", field[1:]
+		case strings.Contains(field, ":"):
+			t.key, t.value, _ = strings.Cut(field, ":")
+		default:
+			t.value = field
+		}
+		terms = append(terms, t)
+	}
+	return terms
+}
+
+func matches(card deck_structs.Card, stack string, terms []term) bool {
+	for _, t := range terms {
+		if matchTerm(card, stack, t) == t.negate {
+			return false
+		}
+	}
+	return true
+}
+
+func matchTerm(card deck_structs.Card, stack string, t term) bool {
+	switch t.key {
+	case "label":
+		for _, label := range card.Labels {
+			if strings.Contains(strings.ToLower(label.Title), t.value) {
+				return true
+			}
+		}
+		return false
+	case "user":
+		for _, u := range card.AssignedUsers {
+			if strings.Contains(strings.ToLower(u.Participant.Uid), t.value) ||
+				strings.Contains(strings.ToLower(u.Participant.DisplayName), t.value) {
+				return true
+			}
+		}
+		return false
+	case "stack":
+		return strings.Contains(strings.ToLower(stack), t.value)
+	case "id":
+		return strconv.Itoa(card.Id) == t.value
+	case "due":
+		return matchDue(card, t.value)
+	}
+	return strings.Contains(strings.ToLower(card.Title), t.value) ||
+		strings.Contains(strings.ToLower(card.Description), t.value)
+}
+
+func matchDue(card deck_structs.Card, value string) bool {
+	if len(card.DueDate) == 0 {
+		return value == "none"
+	}
+	dueDate, err := time.Parse("2006-01-02T15:04:05+00:00", card.DueDate)
+	if err != nil {
+		return false
+	}
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	switch value {
+	case "any":
+		return true
+	case "overdue":
+		return dueDate.Before(now)
+	case "today":
+		return !dueDate.Before(today) && dueDate.Before(today.AddDate(0, 0, 1))
+	case "week":
+		return !dueDate.Before(today) && dueDate.Before(today.AddDate(0, 0, 7))
+	}
+	return false
+}
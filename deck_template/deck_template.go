@@ -0,0 +1,199 @@
+package deck_template
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"tui-deck/deck_structs"
+	"tui-deck/utils"
+)
+
+type Template struct {
+	Name        string   `json:"name"`
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Labels      []string `json:"labels"`
+	Assignees   []string `json:"assignees"`
+	DueIn       string   `json:"dueIn"`
+	DueTime     string   `json:"dueTime"`
+	Stack       string   `json:"stack"`
+	Order       int      `json:"order"`
+}
+
+var Templates []Template
+
+var configuration utils.Configuration
+
+func Init(conf utils.Configuration) error {
+	configuration = conf
+	return Load()
+}
+
+func GetTemplatesDir() string {
+	return configuration.ConfigDir + "/templates"
+}
+
+func Load() error {
+	Templates = make([]Template, 0)
+	files, err := filepath.Glob(GetTemplatesDir() + "/*.json")
+	if err != nil {
+		return err
+	}
+	sort.Strings(files)
+
+	errs := make([]string, 0)
+	for _, file := range files {
+		template, err := readTemplate(file)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		Templates = append(Templates, template)
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("error reading templates: %s", strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+func Find(name string) (Template, error) {
+	files, err := filepath.Glob(GetTemplatesDir() + "/*.json")
+	if err != nil {
+		return Template{}, err
+	}
+	sort.Strings(files)
+
+	errs := make([]string, 0)
+	for _, file := range files {
+		template, err := readTemplate(file)
+		if err != nil {
+			if strings.EqualFold(strings.TrimSuffix(filepath.Base(file), ".json"), name) {
+				return Template{}, err
+			}
+			errs = append(errs, err.Error())
+			continue
+		}
+		if strings.EqualFold(template.Name, name) {
+			return template, nil
+		}
+	}
+	if len(errs) > 0 {
+		return Template{}, fmt.Errorf("unknown template %s, unreadable templates: %s", name, strings.Join(errs, ", "))
+	}
+	return Template{}, fmt.Errorf("unknown template %s", name)
+}
+
+func readTemplate(file string) (Template, error) {
+	template := Template{}
+	f, err := os.Open(file)
+	if err != nil {
+		return template, err
+	}
+	defer f.Close()
+	err = json.NewDecoder(f).Decode(&template)
+	if err != nil {
+		return template, fmt.Errorf("%s: %s", filepath.Base(file), err.Error())
+	}
+	if len(template.Name) == 0 {
+		template.Name = strings.TrimSuffix(filepath.Base(file), ".json")
+	}
+	return template, nil
+}
+
+func (template Template) Render(board deck_structs.Board, stack deck_structs.Stack, now time.Time) (deck_structs.Card, error) {
+	_, week := now.ISOWeek()
+	replacer := strings.NewReplacer(
+		"{date}", now.Format("2006-01-02"),
+		"{time}", now.Format("15:04"),
+		"{year}", now.Format("2006"),
+		"{month}", now.Format("01"),
+		"{day}", now.Format("02"),
+		"{week}", fmt.Sprintf("%02d", week),
+		"{user}", configuration.User,
+		"{board}", board.Title,
+		"{stack}", stack.Title,
+	)
+
+	card := deck_structs.Card{
+		Title:       replacer.Replace(template.Title),
+		Description: replacer.Replace(template.Description),
+		StackId:     stack.Id,
+		Order:       template.Order,
+	}
+
+	errs := make([]string, 0)
+	for _, name := range template.Labels {
+		found := false
+		for _, label := range board.Labels {
+			if strings.EqualFold(label.Title, name) {
+				card.Labels = append(card.Labels, label)
+				found = true
+				break
+			}
+		}
+		if !found {
+			errs = append(errs, fmt.Sprintf("unknown label %s", name))
+		}
+	}
+	for _, name := range template.Assignees {
+		found := false
+		for _, user := range board.Users {
+			if user.Uid == name || strings.EqualFold(user.DisplayName, name) {
+				card.AssignedUsers = append(card.AssignedUsers, deck_structs.AssignedUser{Participant: user})
+				found = true
+				break
+			}
+		}
+		if !found {
+			errs = append(errs, fmt.Sprintf("unknown user %s", name))
+		}
+	}
+
+	dueDate, err := template.GetDueDate(now)
+	if err != nil {
+		errs = append(errs, err.Error())
+	}
+	card.DueDate = dueDate
+
+	if len(errs) > 0 {
+		return card, fmt.Errorf("template %s: %s", template.Name, strings.Join(errs, ", "))
+	}
+	return card, nil
+}
+
+func (template Template) GetDueDate(now time.Time) (string, error) {
+	if len(template.DueIn) == 0 {
+		return "", nil
+	}
+	re := regexp.MustCompile(`^(\d+)\s*([hdw])$`)
+	match := re.FindStringSubmatch(strings.TrimSpace(template.DueIn))
+	if len(match) < 3 {
+		return "", fmt.Errorf("not a valid dueIn %s, use a number followed by h, d or w", template.DueIn)
+	}
+	amount, _ := strconv.Atoi(match[1])
+
+	var due time.Time
+	switch match[2] {
+	case "h":
+		due = now.Add(time.Duration(amount) * time.Hour)
+	case "d":
+		due = now.AddDate(0, 0, amount)
+	case "w":
+		due = now.AddDate(0, 0, amount*7)
+	}
+
+	if len(template.DueTime) > 0 {
+		dueTime, err := time.Parse("15:04", template.DueTime)
+		if err != nil {
+			return "", fmt.Errorf("not a valid dueTime %s, format must be HH:mm", template.DueTime)
+		}
+		due = time.Date(due.Year(), due.Month(), due.Day(), dueTime.Hour(), dueTime.Minute(), 0, 0, due.Location())
+	}
+	return due.Format("02/01/2006 15:04"), nil
+}
@@ -0,0 +1,340 @@
+package deck_theme
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"os"
+	"time"
+	"tui-deck/utils"
+)
+
+const NoColor = "no-color"
+
+type Theme struct {
+	Name                string `json:"name"`
+	Background          string `json:"background"`
+	Text                string `json:"text"`
+	Accent              string `json:"accent"`
+	Muted               string `json:"muted"`
+	Warning             string `json:"warning"`
+	Danger              string `json:"danger"`
+	CodeText            string `json:"codeText"`
+	CodeBackground      string `json:"codeBackground"`
+	CodeStyle           string `json:"codeStyle"`
+	LinkText            string `json:"linkText"`
+	LinkUrl             string `json:"linkUrl"`
+	FieldText           string `json:"fieldText"`
+	FieldBackground     string `json:"fieldBackground"`
+	SelectionText       string `json:"selectionText"`
+	SelectionBackground string `json:"selectionBackground"`
+}
+
+var Themes = map[string]Theme{
+	"dark": {
+		Name:                "dark",
+		Background:          "black",
+		Text:                "white",
+		Muted:               "gray",
+		Warning:             "yellow",
+		Danger:              "red",
+		CodeText:            "#af0000",
+		CodeBackground:      "#4e4e4e",
+		CodeStyle:           "monokai",
+		LinkText:            "#00ffaf",
+		LinkUrl:             "#5f5fff",
+		FieldText:           "black",
+		FieldBackground:     "white",
+		SelectionText:       "black",
+		SelectionBackground: "white",
+	},
+	"light": {
+		Name:                "light",
+		Background:          "white",
+		Text:                "black",
+		Muted:               "#6c6c6c",
+		Warning:             "#af5f00",
+		Danger:              "#d70000",
+		CodeText:            "#af0000",
+		CodeBackground:      "#e4e4e4",
+		CodeStyle:           "github",
+		LinkText:            "#005f87",
+		LinkUrl:             "#0000d7",
+		FieldText:           "black",
+		FieldBackground:     "#d0d0d0",
+		SelectionText:       "white",
+		SelectionBackground: "#005fd7",
+	},
+	"high-contrast": {
+		Name:                "high-contrast",
+		Background:          "black",
+		Text:                "white",
+		Accent:              "yellow",
+		Muted:               "white",
+		Warning:             "yellow",
+		Danger:              "#ff5f5f",
+		CodeText:            "white",
+		CodeBackground:      "black",
+		CodeStyle:           "hr_high_contrast",
+		LinkText:            "aqua",
+		LinkUrl:             "aqua",
+		FieldText:           "black",
+		FieldBackground:     "yellow",
+		SelectionText:       "black",
+		SelectionBackground: "aqua",
+	},
+	NoColor: {
+		Name:                NoColor,
+		Background:          "-",
+		Text:                "-",
+		Accent:              "-",
+		Muted:               "-",
+		Warning:             "-",
+		Danger:              "-",
+		CodeText:            "-",
+		CodeBackground:      "-",
+		LinkText:            "-",
+		LinkUrl:             "-",
+		FieldText:           "-",
+		FieldBackground:     "-",
+		SelectionText:       "-",
+		SelectionBackground: "-",
+	},
+}
+
+var Current = Themes["dark"]
+
+var app *tview.Application
+var configuration utils.Configuration
+var listeners = make([]func(), 0)
+var modTimes = make(map[string]time.Time)
+
+func Init(application *tview.Application, conf utils.Configuration) error {
+	app = application
+	configuration = conf
+	err := Load()
+	for _, file := range watchedFiles() {
+		modTimes[file] = getModTime(file)
+	}
+	return err
+}
+
+func Load() error {
+	var err error
+	name := configuration.Theme
+	if len(name) == 0 {
+		name = "dark"
+	}
+	theme, ok := Themes[name]
+	if !ok {
+		theme = Themes["dark"]
+		err = fmt.Errorf("unknown theme %s, using dark", name)
+	}
+	if len(theme.CodeStyle) > 0 && len(configuration.CodeStyle) > 0 {
+		theme.CodeStyle = configuration.CodeStyle
+	}
+
+	themeFile := getThemeFile()
+	if utils.Exists(themeFile) {
+		custom, fileErr := readTheme(themeFile)
+		if fileErr != nil {
+			err = fileErr
+		} else {
+			theme = merge(theme, custom)
+		}
+	}
+
+	if len(os.Getenv("NO_COLOR")) > 0 {
+		theme = Themes[NoColor]
+	}
+	if len(theme.Accent) == 0 {
+		theme.Accent = configuration.Color
+	}
+
+	Current = theme
+	apply()
+	return err
+}
+
+func IsNoColor() bool {
+	return Current.Name == NoColor
+}
+
+func GetColor(color string) tcell.Color {
+	if len(color) == 0 || color == "-" {
+		return tcell.ColorDefault
+	}
+	return utils.GetColor(color)
+}
+
+func Accent() tcell.Color {
+	return GetColor(Current.Accent)
+}
+
+func Key(key string) string {
+	return fmt.Sprintf("[%s::b]%s[-::-]", Current.Warning, key)
+}
+
+func StyleBox(box *tview.Box) {
+	box.SetBorderColor(Accent())
+	box.SetBackgroundColor(GetColor(Current.Background))
+	box.SetTitleColor(GetColor(Current.Text))
+}
+
+func StyleForm(form *tview.Form) {
+	form.SetBorderColor(Accent())
+	form.SetBackgroundColor(GetColor(Current.Background))
+	form.SetButtonBackgroundColor(Accent())
+	form.SetButtonTextColor(GetColor(Current.Background))
+	form.SetFieldBackgroundColor(GetColor(Current.FieldBackground))
+	form.SetFieldTextColor(GetColor(Current.FieldText))
+	form.SetLabelColor(Accent())
+	if IsNoColor() {
+		form.SetButtonStyle(tcell.StyleDefault)
+		form.SetButtonActivatedStyle(tcell.StyleDefault.Reverse(true))
+	}
+}
+
+func StyleModal(modal *tview.Modal) {
+	modal.SetBackgroundColor(Accent())
+	modal.SetTextColor(GetColor(Current.Text))
+	modal.SetButtonBackgroundColor(GetColor(Current.Background))
+	modal.SetButtonTextColor(GetColor(Current.Text))
+}
+
+func StyleList(list *tview.List) {
+	list.SetBackgroundColor(GetColor(Current.Background))
+	list.SetMainTextColor(GetColor(Current.Text))
+	list.SetSecondaryTextColor(GetColor(Current.Muted))
+	if IsNoColor() {
+		list.SetSelectedStyle(tcell.StyleDefault.Reverse(true))
+	} else {
+		list.SetSelectedTextColor(GetColor(Current.SelectionText))
+		list.SetSelectedBackgroundColor(GetColor(Current.SelectionBackground))
+	}
+}
+
+func StyleTextArea(textArea *tview.TextArea) {
+	textArea.SetTextStyle(tcell.StyleDefault.
+		Foreground(GetColor(Current.Text)).
+		Background(GetColor(Current.Background)))
+	if IsNoColor() {
+		textArea.SetSelectedStyle(tcell.StyleDefault.Reverse(true))
+	} else {
+		textArea.SetSelectedStyle(tcell.StyleDefault.
+			Foreground(GetColor(Current.SelectionText)).
+			Background(GetColor(Current.SelectionBackground)))
+	}
+}
+
+func OnChange(listener func()) {
+	listeners = append(listeners, listener)
+}
+
+func Watch() {
+	ticker := time.NewTicker(2 * time.Second)
+	for range ticker.C {
+		changed := false
+		for _, file := range watchedFiles() {
+			modTime := getModTime(file)
+			if !modTime.Equal(modTimes[file]) {
+				modTimes[file] = modTime
+				changed = true
+			}
+		}
+		if !changed {
+			continue
+		}
+		app.QueueUpdateDraw(func() {
+			conf, err := utils.GetConfiguration(configuration.ConfigDir + "/config.json")
+			if err == nil {
+				configuration.Theme = conf.Theme
+				configuration.Color = conf.Color
+				configuration.CodeStyle = conf.CodeStyle
+			}
+			_ = Load()
+			for _, listener := range listeners {
+				listener()
+			}
+		})
+	}
+}
+
+func apply() {
+	tview.Styles.PrimitiveBackgroundColor = GetColor(Current.Background)
+	tview.Styles.ContrastBackgroundColor = GetColor(Current.FieldBackground)
+	tview.Styles.MoreContrastBackgroundColor = GetColor(Current.SelectionBackground)
+	tview.Styles.BorderColor = Accent()
+	tview.Styles.TitleColor = GetColor(Current.Text)
+	tview.Styles.GraphicsColor = Accent()
+	tview.Styles.PrimaryTextColor = GetColor(Current.Text)
+	tview.Styles.SecondaryTextColor = Accent()
+	tview.Styles.TertiaryTextColor = GetColor(Current.Muted)
+	tview.Styles.InverseTextColor = GetColor(Current.Background)
+	tview.Styles.ContrastSecondaryTextColor = GetColor(Current.FieldText)
+}
+
+func merge(theme Theme, custom Theme) Theme {
+	base := &theme
+	overrides := map[*string]string{
+		&base.Background:          custom.Background,
+		&base.Text:                custom.Text,
+		&base.Accent:              custom.Accent,
+		&base.Muted:               custom.Muted,
+		&base.Warning:             custom.Warning,
+		&base.Danger:              custom.Danger,
+		&base.CodeText:            custom.CodeText,
+		&base.CodeBackground:      custom.CodeBackground,
+		&base.CodeStyle:           custom.CodeStyle,
+		&base.LinkText:            custom.LinkText,
+		&base.LinkUrl:             custom.LinkUrl,
+		&base.FieldText:           custom.FieldText,
+		&base.FieldBackground:     custom.FieldBackground,
+		&base.SelectionText:       custom.SelectionText,
+		&base.SelectionBackground: custom.SelectionBackground,
+	}
+	for field, value := range overrides {
+		if len(value) > 0 {
+			*field = value
+		}
+	}
+	return theme
+}
+
+func readTheme(themeFile string) (Theme, error) {
+	file, err := os.Open(themeFile)
+	if err != nil {
+		return Theme{}, err
+	}
+	defer file.Close()
+	theme := Theme{}
+	err = json.NewDecoder(file).Decode(&theme)
+	if err != nil {
+		return Theme{}, fmt.Errorf("error reading theme %s: %s", themeFile, err.Error())
+	}
+	return theme, nil
+}
+
+func getThemeFile() string {
+	return configuration.ConfigDir + "/theme.json"
+}
+
+func watchedFiles() []string {
+	return []string{configuration.ConfigDir + "/config.json", getThemeFile()}
+}
+
+func getModTime(file string) time.Time {
+	info, err := os.Stat(file)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+func LabelColor(color string) string {
+	if IsNoColor() {
+		return "-"
+	}
+	return "#" + color
+}
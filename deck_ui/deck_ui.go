@@ -1,9 +1,12 @@
 package deck_ui
 
 import (
+	"fmt"
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
 	"tui-deck/deck_help"
+	"tui-deck/deck_keys"
+	"tui-deck/deck_theme"
 	"tui-deck/utils"
 )
 
@@ -15,6 +18,8 @@ var FooterBar = *tview.NewTextView()
 
 var Primitives = make(map[tview.Primitive]int)
 var PrimitivesIndexMap = make(map[int]tview.Primitive)
+var Lanes = make(map[tview.Primitive]int)
+var LanesIndexMap = make(map[int]map[int]tview.Primitive)
 var app *tview.Application
 var configuration utils.Configuration
 
@@ -24,13 +29,14 @@ func Init(application *tview.Application, conf utils.Configuration) {
 
 	MainFlex.SetDirection(tview.FlexColumn)
 	MainFlex.SetBorder(true)
-	MainFlex.SetBorderColor(utils.GetColor(configuration.Color))
 
 	FooterBar.SetBorder(true)
 	FooterBar.SetTitle(" Info ")
-	FooterBar.SetBorderColor(utils.GetColor(configuration.Color))
 	FooterBar.SetDynamicColors(true)
-	FooterBar.SetText("Press [yellow]?[white] for help, [yellow]q[white] to exit")
+	SetFooterHelp(true)
+
+	applyTheme()
+	deck_theme.OnChange(applyTheme)
 
 	FullFlex.SetDirection(tview.FlexRow)
 	FullFlex.AddItem(MainFlex, 0, 10, true)
@@ -42,20 +48,37 @@ func BuildFullFlex(primitive tview.Primitive, err error) {
 	FullFlex.AddItem(primitive, 0, 10, true)
 	FullFlex.AddItem(&FooterBar, 0, 1, false)
 	if err == nil {
-		if primitive != MainFlex {
-			FooterBar.SetText("Press [yellow]?[white] for help, [yellow]ESC[white] to go back")
-
-		} else {
-			FooterBar.SetText("Press [yellow]?[white] for help, [yellow]q[white] to exit")
-		}
+		SetFooterHelp(primitive == MainFlex)
 	}
 	app.SetFocus(primitive)
 }
 
-func BuildHelp(primitive tview.Primitive, helpView *tview.TextView) {
+func GetCurrentPrimitive() tview.Primitive {
+	return FullFlex.GetItem(0)
+}
+
+func SetFooterHelp(main bool) {
+	if main {
+		FooterBar.SetText(fmt.Sprintf("Press %s for help, %s to exit",
+			deck_theme.Key(deck_keys.FirstKey("main.help")), deck_theme.Key(deck_keys.FirstKey("main.quit"))))
+	} else {
+		FooterBar.SetText(fmt.Sprintf("Press %s for help, %s to go back",
+			deck_theme.Key(deck_keys.FirstKey("card.help")), deck_theme.Key(deck_keys.FirstKey("card.back"))))
+	}
+}
+
+func applyTheme() {
+	deck_theme.StyleBox(FullFlex.Box)
+	deck_theme.StyleBox(MainFlex.Box)
+	deck_theme.StyleBox(FooterBar.Box)
+	FooterBar.SetTextColor(deck_theme.GetColor(deck_theme.Current.Text))
+}
+
+func BuildHelp(primitive tview.Primitive, context string) {
+	helpView := deck_help.HelpViews[context]
 	help := tview.NewFrame(helpView)
 	help.SetBorder(true)
-	help.SetBorderColor(utils.GetColor(configuration.Color))
+	deck_theme.StyleBox(help.Box)
 	help.SetTitle(helpView.GetTitle())
 	FooterBar.SetTitle(VERSION)
 	BuildFullFlex(help, nil)
@@ -66,36 +89,11 @@ func BuildHelp(primitive tview.Primitive, helpView *tview.TextView) {
 			FooterBar.SetTitle(" Info ")
 			return nil
 		} else if event.Key() == tcell.KeyEnter {
-			switch {
-			case help.GetPrimitive() == deck_help.HelpMain:
-				help.SetTitle(deck_help.HelpView.GetTitle())
-				help.SetPrimitive(deck_help.HelpView)
-				return nil
-			case help.GetPrimitive() == deck_help.HelpView:
-				help.SetTitle(deck_help.HelpEdit.GetTitle())
-				help.SetPrimitive(deck_help.HelpEdit)
-				return nil
-			case help.GetPrimitive() == deck_help.HelpEdit:
-				help.SetTitle(deck_help.HelpLabels.GetTitle())
-				help.SetPrimitive(deck_help.HelpLabels)
-				return nil
-			case help.GetPrimitive() == deck_help.HelpLabels:
-				help.SetTitle(deck_help.HelpUsers.GetTitle())
-				help.SetPrimitive(deck_help.HelpUsers)
-				return nil
-			case help.GetPrimitive() == deck_help.HelpUsers:
-				help.SetTitle(deck_help.HelpComments.GetTitle())
-				help.SetPrimitive(deck_help.HelpComments)
-				return nil
-			case help.GetPrimitive() == deck_help.HelpComments:
-				help.SetTitle(deck_help.HelpBoards.GetTitle())
-				help.SetPrimitive(deck_help.HelpBoards)
-				return nil
-			case help.GetPrimitive() == deck_help.HelpBoards:
-				help.SetTitle(deck_help.HelpMain.GetTitle())
-				help.SetPrimitive(deck_help.HelpMain)
-				return nil
-			}
+			context = deck_help.NextHelp(context)
+			helpView = deck_help.HelpViews[context]
+			help.SetTitle(helpView.GetTitle())
+			help.SetPrimitive(helpView)
+			return nil
 		}
 		return event
 	})
@@ -105,5 +103,17 @@ func GetNextFocus(index int) tview.Primitive {
 	if index == len(PrimitivesIndexMap) {
 		index = 0
 	}
+	if lane, ok := Lanes[app.GetFocus()]; ok {
+		return GetLaneFocus(lane, index)
+	}
+	return PrimitivesIndexMap[index]
+}
+
+func GetLaneFocus(lane int, index int) tview.Primitive {
+	if cells, ok := LanesIndexMap[lane]; ok {
+		if primitive, ok := cells[index]; ok {
+			return primitive
+		}
+	}
 	return PrimitivesIndexMap[index]
 }
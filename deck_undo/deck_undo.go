@@ -0,0 +1,195 @@
+package deck_undo
+
+import (
+	"errors"
+	"fmt"
+	"github.com/rivo/tview"
+	"time"
+	"tui-deck/deck_keys"
+	"tui-deck/deck_theme"
+	"tui-deck/deck_ui"
+	"tui-deck/utils"
+)
+
+const historySize = 100
+
+type Operation struct {
+	Title string
+	Undo  func() error
+	Redo  func() error
+	time  time.Time
+}
+
+type appliedError struct {
+	error
+}
+
+var HistoryFlex *tview.Flex
+var HistoryList *tview.List
+
+var done = make([]Operation, 0)
+var undone = make([]Operation, 0)
+var listeners = make([]func(), 0)
+var running = false
+
+var app *tview.Application
+var configuration utils.Configuration
+
+func Init(application *tview.Application, conf utils.Configuration) {
+	app = application
+	configuration = conf
+
+	HistoryFlex = tview.NewFlex()
+	HistoryList = tview.NewList()
+
+	HistoryList.SetBorder(true)
+	HistoryFlex.AddItem(HistoryList, 0, 1, true)
+
+	applyTheme()
+	deck_theme.OnChange(func() {
+		applyTheme()
+		buildHistoryList()
+	})
+}
+
+func applyTheme() {
+	deck_theme.StyleBox(HistoryFlex.Box)
+	deck_theme.StyleBox(HistoryList.Box)
+	deck_theme.StyleList(HistoryList)
+}
+
+func Applied(err error) error {
+	if err == nil {
+		return nil
+	}
+	return appliedError{err}
+}
+
+func IsApplied(err error) bool {
+	var applied appliedError
+	return errors.As(err, &applied)
+}
+
+func OnChange(listener func()) {
+	listeners = append(listeners, listener)
+}
+
+func Record(operation Operation) {
+	operation.time = time.Now()
+	done = append(done, operation)
+	if len(done) > historySize {
+		done = done[len(done)-historySize:]
+	}
+	undone = make([]Operation, 0)
+	buildHistoryList()
+}
+
+func Clear() {
+	done = make([]Operation, 0)
+	undone = make([]Operation, 0)
+	buildHistoryList()
+}
+
+func Undo() {
+	if running {
+		deck_ui.FooterBar.SetText("Wait for the current undo or redo to finish")
+		return
+	}
+	if len(done) == 0 {
+		deck_ui.FooterBar.SetText("Nothing to undo")
+		return
+	}
+	operation := done[len(done)-1]
+	run(operation, operation.Undo, "Undoing", func(err error) {
+		if err != nil && !IsApplied(err) {
+			deck_ui.FooterBar.SetText(fmt.Sprintf("Error undoing %s: %s", operation.Title, err.Error()))
+			return
+		}
+		done = done[:len(done)-1]
+		undone = append(undone, operation)
+		changed()
+		if err != nil {
+			deck_ui.FooterBar.SetText(fmt.Sprintf("Undone %s with errors: %s", operation.Title, err.Error()))
+			return
+		}
+		deck_ui.FooterBar.SetText(fmt.Sprintf("Undone: %s", operation.Title))
+	})
+}
+
+func Redo() {
+	if running {
+		deck_ui.FooterBar.SetText("Wait for the current undo or redo to finish")
+		return
+	}
+	if len(undone) == 0 {
+		deck_ui.FooterBar.SetText("Nothing to redo")
+		return
+	}
+	operation := undone[len(undone)-1]
+	run(operation, operation.Redo, "Redoing", func(err error) {
+		if err != nil && !IsApplied(err) {
+			deck_ui.FooterBar.SetText(fmt.Sprintf("Error redoing %s: %s", operation.Title, err.Error()))
+			return
+		}
+		undone = undone[:len(undone)-1]
+		done = append(done, operation)
+		changed()
+		if err != nil {
+			deck_ui.FooterBar.SetText(fmt.Sprintf("Redone %s with errors: %s", operation.Title, err.Error()))
+			return
+		}
+		deck_ui.FooterBar.SetText(fmt.Sprintf("Redone: %s", operation.Title))
+	})
+}
+
+func run(operation Operation, apply func() error, action string, finish func(err error)) {
+	running = true
+	deck_ui.FooterBar.SetText(fmt.Sprintf("%s %s...", action, operation.Title))
+	go func() {
+		err := apply()
+		app.QueueUpdateDraw(func() {
+			running = false
+			finish(err)
+		})
+	}()
+}
+
+func changed() {
+	for _, listener := range listeners {
+		listener()
+	}
+	buildHistoryList()
+}
+
+func BuildHistory() {
+	buildHistoryList()
+
+	HistoryList.SetInputCapture(deck_keys.Capture(deck_keys.History))
+	deck_keys.SetHandler("history.back", func() {
+		deck_ui.BuildFullFlex(deck_ui.MainFlex, nil)
+	})
+	deck_keys.SetHandler("history.undo", Undo)
+	deck_keys.SetHandler("history.redo", Redo)
+	deck_keys.SetHandler("history.help", func() {
+		deck_ui.BuildHelp(HistoryFlex, deck_keys.History)
+	})
+
+	deck_ui.BuildFullFlex(HistoryFlex, nil)
+}
+
+func buildHistoryList() {
+	HistoryList.Clear()
+	HistoryList.SetTitle(fmt.Sprintf(" History (%d/%d) ", len(done), len(done)+len(undone)))
+	for _, operation := range undone {
+		HistoryList.AddItem(fmt.Sprintf("[%s]undone[-] - [%s]%s[-]", deck_theme.Current.Muted, deck_theme.Current.Muted, tview.Escape(operation.Title)),
+			fmt.Sprintf("[-:-:i]%s[-:-:-]", operation.time.Format("15:04:05 - 2006-01-02")), rune(0), nil)
+	}
+	for i := len(done) - 1; i >= 0; i-- {
+		operation := done[i]
+		HistoryList.AddItem(fmt.Sprintf("[%s]done[-] - %s", deck_theme.Current.Accent, tview.Escape(operation.Title)),
+			fmt.Sprintf("[-:-:i]%s[-:-:-]", operation.time.Format("15:04:05 - 2006-01-02")), rune(0), nil)
+	}
+	if len(undone) > 0 && len(done) > 0 {
+		HistoryList.SetCurrentItem(len(undone))
+	}
+}
@@ -3,18 +3,21 @@ module tui-deck
 go 1.20
 
 require (
+	github.com/alecthomas/chroma/v2 v2.8.0
+	github.com/emersion/go-ical v0.0.0-20220601085725-0864dccc089f
+	github.com/emersion/go-webdav v0.4.0
 	github.com/gdamore/tcell/v2 v2.6.0
 	github.com/rivo/tview v0.0.0-20230525073430-4a1f85bb2219
+	github.com/teambition/rrule-go v1.7.2
+	github.com/yuin/goldmark v1.5.4
 )
 
 require (
-	github.com/emersion/go-ical v0.0.0-20220601085725-0864dccc089f // indirect
-	github.com/emersion/go-webdav v0.4.0 // indirect
+	github.com/dlclark/regexp2 v1.4.0 // indirect
 	github.com/gdamore/encoding v1.0.0 // indirect
 	github.com/lucasb-eyer/go-colorful v1.2.0 // indirect
 	github.com/mattn/go-runewidth v0.0.14 // indirect
 	github.com/rivo/uniseg v0.4.4 // indirect
-	github.com/teambition/rrule-go v1.7.2 // indirect
 	golang.org/x/sys v0.8.0 // indirect
 	golang.org/x/term v0.8.0 // indirect
 	golang.org/x/text v0.9.0 // indirect
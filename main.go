@@ -2,20 +2,33 @@ package main
 
 import (
 	"encoding/json"
+	"flag"
 	"fmt"
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
 	"os"
-	"time"
+	"strings"
+	"tui-deck/deck_activity"
 	"tui-deck/deck_board"
 	"tui-deck/deck_card"
 	"tui-deck/deck_comment"
 	"tui-deck/deck_db"
+	"tui-deck/deck_export"
 	"tui-deck/deck_help"
 	"tui-deck/deck_http"
+	"tui-deck/deck_ical"
+	"tui-deck/deck_keys"
+	"tui-deck/deck_notification"
+	"tui-deck/deck_palette"
+	"tui-deck/deck_recur"
 	"tui-deck/deck_stack"
+	"tui-deck/deck_stats"
 	"tui-deck/deck_structs"
+	"tui-deck/deck_table"
+	"tui-deck/deck_template"
+	"tui-deck/deck_theme"
 	"tui-deck/deck_ui"
+	"tui-deck/deck_undo"
 	"tui-deck/utils"
 )
 
@@ -25,7 +38,6 @@ var pages = tview.NewPages()
 var configuration utils.Configuration
 
 func main() {
-	deck_help.InitHelp()
 	var err error
 	configFile, err := utils.InitConfingDirectory()
 	if err != nil {
@@ -37,8 +49,50 @@ func main() {
 		deck_ui.FooterBar.SetText(err.Error())
 	}
 
+	err = deck_http.Init(configuration)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error configuring http client: %s\n", err.Error())
+		os.Exit(1)
+	}
+
+	templateErr := deck_template.Init(configuration)
+	deck_recur.Init(configuration)
+	deck_ical.Init(configuration)
+	deck_export.Init(configuration)
+	if len(os.Args) > 1 && os.Args[1] == "recur" {
+		recur()
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "ical" {
+		exportIcal(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "export" {
+		exportBoard(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "import" {
+		importBoard(os.Args[2:])
+		return
+	}
+
+	themeErr := deck_theme.Init(app, configuration)
+	keysErr := deck_keys.Init(app, configuration)
+	deck_help.InitHelp()
+	deck_theme.OnChange(deck_help.InitHelp)
+	go deck_theme.Watch()
+
 	fmt.Print("Getting boards...\n")
 	deck_ui.Init(app, configuration)
+	if themeErr != nil {
+		deck_ui.FooterBar.SetText(themeErr.Error())
+	}
+	if keysErr != nil {
+		deck_ui.FooterBar.SetText(keysErr.Error())
+	}
+	if templateErr != nil {
+		deck_ui.FooterBar.SetText(templateErr.Error())
+	}
 	deck_board.Init(app, configuration)
 	var fatalError = false
 	deck_board.Boards, err = deck_http.GetBoards(configuration)
@@ -77,12 +131,18 @@ func main() {
 		} else {
 			deck_ui.FooterBar.SetText("No boards found")
 		}
-		deck_ui.MainFlex.SetTitle(fmt.Sprintf(" TUI DECK: [#%s]%s ", deck_board.CurrentBoard.Color, deck_board.CurrentBoard.Title))
+		deck_ui.MainFlex.SetTitle(fmt.Sprintf(" TUI DECK: [%s]%s ", deck_theme.LabelColor(deck_board.CurrentBoard.Color), deck_board.CurrentBoard.Title))
 
 		fmt.Print("Getting stacks...\n")
 		deck_stack.Init(app, configuration)
+		deck_undo.Init(app, configuration)
+		deck_stats.Init(app, configuration)
+		deck_table.Init(app, configuration)
 		deck_card.Init(app, configuration, deck_board.CurrentBoard)
 		deck_comment.Init(app, configuration)
+		deck_notification.Init(app, configuration)
+		deck_activity.Init(app, configuration)
+		deck_palette.Init(app, configuration)
 		deck_stack.Stacks, err = deck_db.GetStacks(deck_board.CurrentBoard.Id, deck_board.CurrentBoard.Updated, configuration)
 		if err != nil {
 			deck_ui.FooterBar.SetText(fmt.Sprintf("Error getting stacks: %s", err.Error()))
@@ -90,135 +150,298 @@ func main() {
 		deck_card.BuildStacks()
 
 		deck_ui.MainFlex.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
-			if deck_card.Modal.HasFocus() {
-				return deck_card.Modal.GetInputCapture()(event)
+			if _, ok := app.GetFocus().(*tview.List); !ok {
+				return event
+			}
+			return deck_keys.Handle(deck_keys.Main, event)
+		})
+
+		deck_keys.SetHandler("main.quit", app.Stop)
+		deck_keys.SetHandler("main.next-stack", func() {
+			switchStack(1)
+		})
+		deck_keys.SetHandler("main.previous-stack", func() {
+			switchStack(-1)
+		})
+		deck_keys.SetHandler("main.reload", func() {
+			deck_comment.ClearCache()
+			deck_stack.Stacks, err = deck_http.GetStacks(deck_board.CurrentBoard.Id, configuration)
+			if err != nil {
+				deck_ui.FooterBar.SetText(fmt.Sprintf("Error reloading stacks: %s", err.Error()))
+			}
+			deck_card.BuildStacks()
+		})
+		deck_keys.SetHandler("main.switch-board", func() {
+			deck_ui.BuildFullFlex(deck_board.BoardFlex, nil)
+		})
+		deck_keys.SetHandler("main.notifications", deck_notification.BuildNotifications)
+		deck_keys.SetHandler("main.undo", deck_undo.Undo)
+		deck_keys.SetHandler("main.redo", deck_undo.Redo)
+		deck_keys.SetHandler("main.history", deck_undo.BuildHistory)
+		deck_keys.SetHandler("main.stats", func() {
+			deck_stats.BuildStats(deck_board.CurrentBoard, deck_stack.Stacks)
+		})
+		deck_keys.SetHandler("main.table", func() {
+			deck_table.BuildTable(deck_board.CurrentBoard)
+		})
+		deck_keys.SetHandler("main.add-card", func() {
+			if len(deck_stack.Stacks) == 0 {
+				return
+			}
+			actualList := app.GetFocus().(*tview.List)
+			deck_card.ShowAddForm(actualList, deck_card.GetLaneCard(actualList), " Add Card ")
+		})
+		deck_keys.SetHandler("main.delete-card", func() {
+			if len(deck_stack.Stacks) == 0 {
+				return
+			}
+			actualList := app.GetFocus().(*tview.List)
+			if actualList.GetItemCount() == 0 {
+				return
 			}
-			if event.Rune() == 113 {
-				// q -> quit app
-				app.Stop()
-			} else if event.Key() == tcell.KeyTab {
-				// tab -> switch focus between stacks
-				primitive := app.GetFocus()
-				list := primitive.(*tview.List)
-				list.SetTitleColor(tcell.ColorWhite)
-				actualPrimitiveIndex := deck_ui.Primitives[primitive]
-				app.SetFocus(deck_ui.GetNextFocus(actualPrimitiveIndex + 1))
-			} else if event.Rune() == 114 {
-				// r -> reload stacks
-				deck_stack.Stacks, err = deck_http.GetStacks(deck_board.CurrentBoard.Id, configuration)
-				if err != nil {
-					deck_ui.FooterBar.SetText(fmt.Sprintf("Error reloading stacks: %s", err.Error()))
+			var _, stack, _ = deck_stack.GetActualStack(actualList)
+			var currentItemIndex = actualList.GetCurrentItem()
+			mainText, _ := actualList.GetItemText(currentItemIndex)
+			cardId := utils.GetId(mainText)
+			deck_card.DeleteCard(cardId, stack, actualList, currentItemIndex)
+		})
+		deck_keys.SetHandler("main.add-stack", func() {
+			addForm, stack := deck_stack.BuildAddForm(deck_structs.Stack{})
+			addForm.AddButton("Save", func() {
+				err := deck_stack.AddStack(deck_board.CurrentBoard.Id, *stack)
+				if err == nil {
+					deck_stack.RecordAddStack(deck_board.CurrentBoard.Id, deck_stack.Stacks[len(deck_stack.Stacks)-1])
 				}
 				deck_card.BuildStacks()
-			} else if event.Rune() == 115 {
-				// s -> switch board
-				deck_ui.BuildFullFlex(deck_board.BoardFlex, nil)
-			} else if event.Rune() == 97 {
-				// a -> add card
-				if len(deck_stack.Stacks) == 0 {
-					return nil
-				}
-				actualList := app.GetFocus().(*tview.List)
-				addForm, card := deck_card.BuildAddForm()
-				addForm.AddButton("Save", func() {
-					if len(card.DueDate) > 0 {
-
-						dueDate := card.DueDate
-						pattern := "02/01/2006 15:04"
-						parse, err2 := time.Parse(pattern, dueDate)
-						if err2 != nil {
-							deck_ui.FooterBar.SetText(fmt.Sprintf("Not a valid date, format must be dd/MM/YYYY HH:mm: %s", err2.Error()))
-							return
-						}
-						card.DueDate = parse.Format("2006-01-02T15:04:05+00:00")
-					}
-					deck_card.AddCard(actualList, *card)
-				})
-				deck_ui.BuildFullFlex(addForm, nil)
-			} else if event.Rune() == 100 {
-				if len(deck_stack.Stacks) == 0 {
-					return nil
-				}
-				// d -> delete card
-				actualList := app.GetFocus().(*tview.List)
-				var _, stack, _ = deck_stack.GetActualStack(actualList)
-				var currentItemIndex = actualList.GetCurrentItem()
-				mainText, _ := actualList.GetItemText(currentItemIndex)
-				cardId := utils.GetId(mainText)
-				deck_card.DeleteCard(cardId, stack, actualList, currentItemIndex)
-
-			} else if event.Key() == tcell.KeyCtrlA {
-				// ctrl + a -> add stack
-				addForm, stack := deck_stack.BuildAddForm(deck_structs.Stack{})
-				addForm.AddButton("Save", func() {
-					err := deck_stack.AddStack(deck_board.CurrentBoard.Id, *stack)
-					deck_card.BuildStacks()
-					deck_ui.BuildFullFlex(deck_ui.MainFlex, err)
-				})
-				deck_ui.BuildFullFlex(addForm, nil)
-
-			} else if event.Key() == tcell.KeyCtrlD {
-				// ctrl + d -> delete stack
-				if len(deck_stack.Stacks) == 0 {
-					return nil
-				}
+				deck_ui.BuildFullFlex(deck_ui.MainFlex, err)
+			})
+			deck_ui.BuildFullFlex(addForm, nil)
+		})
+		deck_keys.SetHandler("main.delete-stack", func() {
+			if len(deck_stack.Stacks) == 0 {
+				return
+			}
 
-				actualList := app.GetFocus().(*tview.List)
-				index := deck_ui.Primitives[app.GetFocus()]
-				currentStack := deck_stack.Stacks[index]
-
-				deck_stack.DeleteStack(currentStack.Id, actualList)
-				deck_stack.Modal.SetDoneFunc(func(buttonIndex int, buttonLabel string) {
-					if buttonLabel == "Yes" {
-						go func() {
-							_, err = deck_http.DeleteStack(deck_board.CurrentBoard.Id, currentStack.Id, configuration)
-							if err != nil {
-								deck_ui.FooterBar.SetText(fmt.Sprintf("Error deleting stack: %s", err.Error()))
-							}
-						}()
-						deck_ui.MainFlex.RemoveItem(deck_stack.Modal)
-						deck_ui.MainFlex.RemoveItem(actualList)
-						deck_stack.Stacks = append(deck_stack.Stacks[:index], deck_stack.Stacks[index+1:]...)
-						deck_card.BuildStacks()
-						deck_ui.BuildFullFlex(deck_ui.MainFlex, nil)
-					} else if buttonLabel == "No" {
-						deck_ui.MainFlex.RemoveItem(deck_stack.Modal)
-						app.SetFocus(actualList)
-					}
-				})
-
-			} else if event.Key() == tcell.KeyCtrlE {
-				// ctrl + e -> edit stack
-				actualList := app.GetFocus().(*tview.List)
-
-				index := deck_ui.Primitives[app.GetFocus()]
-				currentStack := deck_stack.Stacks[index]
-				editForm, editedStack := deck_stack.BuildAddForm(currentStack)
-				editForm.AddButton("Save", func() {
-					actualList.SetTitle(fmt.Sprintf("# %s ", editedStack.Title))
-					var err error
-					go func() {
-						err = deck_stack.EditStack(deck_board.CurrentBoard.Id, *editedStack)
-					}()
-
-					deck_stack.Stacks[index] = *editedStack
-					deck_card.BuildStacks()
-					deck_ui.BuildFullFlex(deck_ui.MainFlex, err)
-				})
-				deck_ui.BuildFullFlex(editForm, nil)
+			actualList := app.GetFocus().(*tview.List)
+			index := deck_ui.Primitives[app.GetFocus()]
+			currentStack := deck_stack.Stacks[index]
 
-			} else if event.Rune() == 63 {
-				// ? deck_help menu
-				deck_ui.BuildHelp(deck_ui.MainFlex, deck_help.HelpMain)
+			deck_stack.DeleteStack(currentStack.Id, actualList)
+			deck_stack.Modal.SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+				if buttonLabel == "Yes" {
+					deck_card.DeleteStack(index)
+					deck_ui.MainFlex.RemoveItem(deck_stack.Modal)
+					deck_ui.MainFlex.RemoveItem(actualList)
+					deck_card.BuildStacks()
+					deck_ui.BuildFullFlex(deck_ui.MainFlex, nil)
+				} else if buttonLabel == "No" {
+					deck_ui.MainFlex.RemoveItem(deck_stack.Modal)
+					app.SetFocus(actualList)
+				}
+			})
+		})
+		deck_keys.SetHandler("main.edit-stack", func() {
+			if len(deck_stack.Stacks) == 0 {
+				return
 			}
+			actualList := app.GetFocus().(*tview.List)
+
+			index := deck_ui.Primitives[app.GetFocus()]
+			currentStack := deck_stack.Stacks[index]
+			editForm, editedStack := deck_stack.BuildAddForm(currentStack)
+			editForm.AddButton("Save", func() {
+				actualList.SetTitle(fmt.Sprintf("# %s ", editedStack.Title))
+				boardId := deck_board.CurrentBoard.Id
+				stack := *editedStack
+				go func() {
+					err := deck_stack.EditStack(boardId, stack)
+					app.QueueUpdateDraw(func() {
+						if err != nil {
+							deck_ui.FooterBar.SetText(fmt.Sprintf("Error updating stack: %s", err.Error()))
+							return
+						}
+						deck_stack.RecordEditStack(boardId, currentStack, stack)
+					})
+				}()
 
-			return event
+				deck_stack.Stacks[index] = stack
+				deck_card.BuildStacks()
+				deck_ui.BuildFullFlex(deck_ui.MainFlex, nil)
+			})
+			deck_ui.BuildFullFlex(editForm, nil)
+		})
+		deck_keys.SetHandler("main.help", func() {
+			deck_ui.BuildHelp(deck_ui.MainFlex, deck_keys.Main)
 		})
 		deck_card.BuildCardViewer()
 	}
 	pages.AddPage("Main", deck_ui.FullFlex, true, true)
+	go runRecurring(!fatalError)
 	if err := app.SetRoot(pages, true).EnableMouse(false).Run(); err != nil {
 		panic(err)
 	}
 
 }
+
+func runRecurring(reload bool) {
+	report, err := deck_recur.RunAtStartup()
+	if err == nil && len(report) == 0 {
+		return
+	}
+	var stacks []deck_structs.Stack
+	var stacksErr error
+	var boardId int
+	if reload && len(report) > 0 {
+		app.QueueUpdate(func() {
+			boardId = deck_board.CurrentBoard.Id
+		})
+		stacks, stacksErr = deck_http.GetStacks(boardId, configuration)
+	}
+	app.QueueUpdateDraw(func() {
+		if err != nil {
+			deck_ui.FooterBar.SetText(err.Error())
+		} else {
+			deck_ui.FooterBar.SetText(fmt.Sprintf("Recurring cards: %s", strings.Join(report, ", ")))
+		}
+		if !reload || len(report) == 0 {
+			return
+		}
+		if stacksErr != nil {
+			deck_ui.FooterBar.SetText(fmt.Sprintf("Error reloading stacks: %s", stacksErr.Error()))
+			return
+		}
+		if boardId == deck_board.CurrentBoard.Id {
+			deck_stack.Stacks = stacks
+			deck_card.BuildStacks()
+		}
+	})
+}
+
+func switchStack(delta int) {
+	primitive := app.GetFocus()
+	list := primitive.(*tview.List)
+	list.SetTitleColor(deck_theme.GetColor(deck_theme.Current.Text))
+	index := deck_ui.Primitives[primitive] + delta
+	if index < 0 {
+		index = len(deck_ui.PrimitivesIndexMap) - 1
+	}
+	app.SetFocus(deck_ui.GetNextFocus(index))
+}
+
+func recur() {
+	report, err := deck_recur.Run()
+	for _, line := range report {
+		fmt.Println(line)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+}
+
+func exportIcal(args []string) {
+	flags := flag.NewFlagSet("ical", flag.ExitOnError)
+	output := flags.String("o", "tui-deck.ics", "output file")
+	todo := flags.Bool("todo", false, "export cards as VTODO instead of VEVENT")
+	sync := flags.Bool("sync", false, "sync due dates and completion with the boards CalDAV calendars instead of exporting")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: tui-deck ical [-o file] [-todo] [-sync] [board...]")
+		flags.PrintDefaults()
+	}
+	_ = flags.Parse(args)
+
+	if *sync {
+		report, err := deck_ical.Sync(flags.Args())
+		for _, line := range report {
+			fmt.Println(line)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+
+	count, err := deck_ical.Export(flags.Args(), *output, *todo)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+	fmt.Printf("exported %d cards to %s\n", count, *output)
+}
+
+func exportBoard(args []string) {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	board := flags.String("board", "", "board title or id")
+	format := flags.String("format", "json", "json, md or csv")
+	output := flags.String("o", "", "output file, defaults to stdout")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: tui-deck export --board N [--format json|md|csv] [-o file]")
+		flags.PrintDefaults()
+	}
+	_ = flags.Parse(args)
+	if len(*board) == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	err := deck_export.CheckFormat(*format)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(2)
+	}
+
+	export, err := deck_export.GetBoard(*board)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+	out := os.Stdout
+	if len(*output) > 0 {
+		out, err = utils.CreateFile(*output)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+	}
+	err = deck_export.Write(export, *format, out)
+	if out != os.Stdout {
+		closeErr := out.Close()
+		if err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+}
+
+func importBoard(args []string) {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	format := flags.String("format", "auto", "auto, json, trello or wekan")
+	title := flags.String("title", "", "title of the new board, defaults to the imported one")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: tui-deck import [--format auto|json|trello|wekan] [--title name] file")
+		flags.PrintDefaults()
+	}
+	_ = flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	export, err := deck_export.Read(flags.Arg(0), *format)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+	board, warnings, err := deck_export.Import(export, *title)
+	for _, warning := range warnings {
+		fmt.Fprintln(os.Stderr, warning)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+	fmt.Printf("imported board #%d %s\n", board.Id, board.Title)
+}
@@ -1,22 +1,35 @@
 package utils
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"github.com/gdamore/tcell/v2"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"runtime"
 	"strconv"
 	"strings"
 	"tui-deck/deck_structs"
 )
 
 type Configuration struct {
-	User      string `json:"username"`
-	Password  string `json:"password"`
-	Url       string `json:"url"`
-	Color     string `json:"color"`
-	ConfigDir string
+	User           string `json:"username"`
+	Password       string `json:"password"`
+	Url            string `json:"url"`
+	Color          string `json:"color"`
+	Theme          string `json:"theme"`
+	CodeStyle      string `json:"codeStyle"`
+	Opener         string `json:"opener"`
+	Insecure       bool   `json:"insecure"`
+	CaFile         string `json:"caFile"`
+	ClientCert     string `json:"clientCert"`
+	ClientKey      string `json:"clientKey"`
+	Proxy          string `json:"proxy"`
+	Timeout        int    `json:"timeout"`
+	ConnectTimeout int    `json:"connectTimeout"`
+	ConfigDir      string
 }
 
 func InitConfingDirectory() (string, error) {
@@ -36,6 +49,7 @@ func InitConfingDirectory() (string, error) {
 			Password:  "",
 			Url:       "https://nextcloud.example.com",
 			Color:     "#BF40BF",
+			Theme:     "dark",
 			ConfigDir: configDir,
 		}
 		jsonConfig, err := json.Marshal(configuration)
@@ -119,10 +133,14 @@ func FormatDescription(description string) string {
 	return strings.ReplaceAll(description, `\n`, "\n")
 }
 
-func BuildLabels(card deck_structs.Card) string {
+func BuildLabels(card deck_structs.Card, colored bool) string {
 	var labels = ""
 	for i, label := range card.Labels {
-		labels = fmt.Sprintf("%s[#%s]%s[white]", labels, label.Color, label.Title)
+		if colored {
+			labels = fmt.Sprintf("%s[#%s]%s[-]", labels, label.Color, label.Title)
+		} else {
+			labels = fmt.Sprintf("%s%s", labels, label.Title)
+		}
 		if i != len(card.Labels)-1 {
 			labels = fmt.Sprintf("%s, ", labels)
 		}
@@ -147,3 +165,75 @@ func CommaString(a []string) string {
 	}
 	return res
 }
+
+func OpenUrl(url string, opener string) error {
+	if len(opener) == 0 {
+		opener = "xdg-open"
+		if runtime.GOOS == "darwin" {
+			opener = "open"
+		}
+	}
+	command := exec.Command(opener, url)
+	err := command.Start()
+	if err != nil {
+		return err
+	}
+	go command.Wait()
+	return nil
+}
+
+func CopyToClipboard(text string) error {
+	tty, err := os.OpenFile("/dev/tty", os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer tty.Close()
+	// OSC 52 asks the terminal emulator to set the system clipboard
+	_, err = fmt.Fprintf(tty, "\x1b]52;c;%s\a", base64.StdEncoding.EncodeToString([]byte(text)))
+	return err
+}
+
+func GetCardUrl(url string, boardId int, cardId int) string {
+	return fmt.Sprintf("%s/index.php/apps/deck/#/board/%d/card/%d", strings.TrimRight(url, "/"), boardId, cardId)
+}
+
+func EditInExternalEditor(text string) (string, error) {
+	editor := os.Getenv("VISUAL")
+	if len(editor) == 0 {
+		editor = os.Getenv("EDITOR")
+	}
+	if len(editor) == 0 {
+		editor = "vi"
+	}
+
+	file, err := os.CreateTemp("", "tui-deck-*.md")
+	if err != nil {
+		return text, err
+	}
+	defer os.Remove(file.Name())
+
+	_, err = file.WriteString(text)
+	if err != nil {
+		return text, err
+	}
+	err = file.Close()
+	if err != nil {
+		return text, err
+	}
+
+	args := strings.Fields(editor)
+	command := exec.Command(args[0], append(args[1:], file.Name())...)
+	command.Stdin = os.Stdin
+	command.Stdout = os.Stdout
+	command.Stderr = os.Stderr
+	err = command.Run()
+	if err != nil {
+		return text, err
+	}
+
+	edited, err := os.ReadFile(file.Name())
+	if err != nil {
+		return text, err
+	}
+	return strings.TrimRight(string(edited), "\n"), nil
+}